@@ -3,6 +3,8 @@ package config
 import (
 	"log"
 	"os"
+	"sync"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -14,10 +16,43 @@ type Config struct {
 	DBPassword string
 	DBName     string
 	JWTSecret  string
+
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+
+	// Mailer: "log" (default), "file" atau "smtp"
+	AppURL       string
+	MailDriver   string
+	MailFrom     string
+	MailDir      string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUser     string
+	SMTPPassword string
+
+	// Lama data terhapus disimpan di trash sebelum dihapus permanen
+	TrashRetention time.Duration
+
+	// Masa tenggang sebelum akun yang dihapus user di-purge, 0 = langsung
+	AccountDeletionGrace time.Duration
+}
+
+var (
+	current  *Config
+	loadOnce sync.Once
+)
+
+// Get mengembalikan konfigurasi yang dimuat sekali saat pertama dipanggil.
+// Pakai ini di kode per-request, jangan LoadConfig.
+func Get() *Config {
+	loadOnce.Do(func() {
+		current = LoadConfig()
+	})
+	return current
 }
 
+// LoadConfig membaca .env dan environment variable dari awal.
 func LoadConfig() *Config {
-	// Load .env sekali saja
 	err := godotenv.Load()
 	if err != nil {
 		log.Println("⚠️  .env file not found, using system environment variables")
@@ -30,6 +65,21 @@ func LoadConfig() *Config {
 		DBPassword: getEnv("DB_PASSWORD", ""),
 		DBName:     getEnv("DB_NAME", "finance_app"),
 		JWTSecret:  getEnv("JWT_SECRET", "secret"), // default fallback
+
+		AccessTokenTTL:  getDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL: getDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+
+		AppURL:       getEnv("APP_URL", "http://localhost:5173"),
+		MailDriver:   getEnv("MAIL_DRIVER", "log"),
+		MailFrom:     getEnv("MAIL_FROM", "no-reply@finance-app.local"),
+		MailDir:      getEnv("MAIL_DIR", "tmp/mail"),
+		SMTPHost:     getEnv("SMTP_HOST", ""),
+		SMTPPort:     getEnv("SMTP_PORT", "587"),
+		SMTPUser:     getEnv("SMTP_USER", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+
+		TrashRetention:       getDuration("TRASH_RETENTION", 30*24*time.Hour),
+		AccountDeletionGrace: getDuration("ACCOUNT_DELETION_GRACE", 0),
 	}
 }
 
@@ -40,3 +90,16 @@ func getEnv(key, defaultValue string) string {
 	}
 	return value
 }
+
+func getDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("⚠️  invalid %s=%q, using %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return d
+}
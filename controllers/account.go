@@ -1,17 +1,18 @@
 package controllers
 
 import (
-	"finance-app/database"
 	"finance-app/models"
+	"finance-app/services"
 	"finance-app/utils"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 func GetAccounts(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -19,8 +20,9 @@ func GetAccounts(c *gin.Context) {
 
 	memberID := c.Query("member_id")
 	accType := c.Query("type")
+	includeArchived := c.Query("include_archived") == "true"
 
-	db := database.GetDB()
+	db := utils.RequestDB(c)
 	query := db.Preload("Member").
 		Joins("JOIN members ON members.id = accounts.member_id").
 		Where("members.user_id = ?", userID)
@@ -31,6 +33,12 @@ func GetAccounts(c *gin.Context) {
 	if accType != "" {
 		query = query.Where("accounts.type = ?", accType)
 	}
+	if !includeArchived {
+		query = query.Where("accounts.archived_at IS NULL")
+	}
+	if mid, restricted := restrictedMember(c); restricted {
+		query = query.Where("accounts.member_id = ?", mid)
+	}
 
 	var accounts []models.Account
 	if err := query.Find(&accounts).Error; err != nil {
@@ -42,7 +50,7 @@ func GetAccounts(c *gin.Context) {
 }
 
 func CreateAccount(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -62,11 +70,11 @@ func CreateAccount(c *gin.Context) {
 
 	// validate account type
 	if !models.ValidAccountTypes[input.Type] {
-		utils.RespondWithError(c, http.StatusBadRequest, "Invalid account type, allowed: Bank, e-Wallet, Cash")
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid account type, allowed: Bank, e-Wallet, Cash, Investment")
 		return
 	}
 
-	db := database.GetDB()
+	db := utils.RequestDB(c)
 	var member models.Member
 	if err := db.Where("user_id = ? AND id = ?", userID, input.MemberID).First(&member).Error; err != nil {
 		utils.RespondWithError(c, http.StatusNotFound, "Member not found")
@@ -90,7 +98,7 @@ func CreateAccount(c *gin.Context) {
 }
 
 func GetAccountByID(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -102,12 +110,16 @@ func GetAccountByID(c *gin.Context) {
 		return
 	}
 
-	db := database.GetDB()
-	var account models.Account
-	if err := db.Preload("Member").
+	db := utils.RequestDB(c)
+	query := db.Preload("Member").
 		Joins("JOIN members ON members.id = accounts.member_id").
-		Where("members.user_id = ? AND accounts.id = ?", userID, id).
-		First(&account).Error; err != nil {
+		Where("members.user_id = ? AND accounts.id = ?", userID, id)
+	if mid, restricted := restrictedMember(c); restricted {
+		query = query.Where("accounts.member_id = ?", mid)
+	}
+
+	var account models.Account
+	if err := query.First(&account).Error; err != nil {
 		utils.RespondWithError(c, http.StatusNotFound, "Account not found")
 		return
 	}
@@ -116,7 +128,7 @@ func GetAccountByID(c *gin.Context) {
 }
 
 func UpdateAccount(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -138,7 +150,7 @@ func UpdateAccount(c *gin.Context) {
 		return
 	}
 
-	db := database.GetDB()
+	db := utils.RequestDB(c)
 	var account models.Account
 	if err := db.Joins("JOIN members ON members.id = accounts.member_id").
 		Where("members.user_id = ? AND accounts.id = ?", userID, id).
@@ -152,9 +164,17 @@ func UpdateAccount(c *gin.Context) {
 	}
 	if input.Type != "" {
 		if !models.ValidAccountTypes[input.Type] {
-			utils.RespondWithError(c, http.StatusBadRequest, "Invalid account type, allowed: Bank, e-Wallet, Cash")
+			utils.RespondWithError(c, http.StatusBadRequest, "Invalid account type, allowed: Bank, e-Wallet, Cash, Investment")
 			return
 		}
+		if account.Type == models.AccountTypeInvestment && input.Type != models.AccountTypeInvestment {
+			var lots int64
+			db.Model(&models.InvestmentLot{}).Where("account_id = ?", account.ID).Count(&lots)
+			if lots > 0 {
+				utils.RespondWithError(c, http.StatusConflict, "Account still holds investment lots")
+				return
+			}
+		}
 		account.Type = input.Type
 	}
 	if input.Currency != "" {
@@ -170,7 +190,43 @@ func UpdateAccount(c *gin.Context) {
 }
 
 func DeleteAccount(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid account ID")
+		return
+	}
+
+	var opts services.DeleteOptions
+	if err := c.ShouldBindQuery(&opts); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+
+	service := services.NewIntegrityService(utils.RequestDB(c))
+	if err := service.DeleteAccount(userID, uint(id), opts); err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	utils.RespondWithSuccess(c, gin.H{"message": "Account deleted successfully"})
+}
+
+func ArchiveAccount(c *gin.Context) {
+	setAccountArchived(c, true)
+}
+
+func UnarchiveAccount(c *gin.Context) {
+	setAccountArchived(c, false)
+}
+
+func setAccountArchived(c *gin.Context, archived bool) {
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -182,7 +238,7 @@ func DeleteAccount(c *gin.Context) {
 		return
 	}
 
-	db := database.GetDB()
+	db := utils.RequestDB(c)
 	var account models.Account
 	if err := db.Joins("JOIN members ON members.id = accounts.member_id").
 		Where("members.user_id = ? AND accounts.id = ?", userID, id).
@@ -191,12 +247,17 @@ func DeleteAccount(c *gin.Context) {
 		return
 	}
 
-	if err := db.Delete(&account).Error; err != nil {
-		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to delete account")
+	account.ArchivedAt = nil
+	if archived {
+		now := time.Now()
+		account.ArchivedAt = &now
+	}
+	if err := db.Model(&account).Update("archived_at", account.ArchivedAt).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update account")
 		return
 	}
 
-	utils.RespondWithSuccess(c, gin.H{"message": "Account deleted successfully"})
+	utils.RespondWithSuccess(c, account)
 }
 
 // ✅ New endpoint: list available account types
@@ -0,0 +1,173 @@
+package controllers
+
+import (
+	"finance-app/models"
+	"finance-app/services"
+	"finance-app/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetAllowances(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	db := utils.RequestDB(c)
+	query := db.Preload("Member").Preload("FromAccount").Preload("ToAccount").
+		Where("user_id = ?", userID)
+	if memberID := c.Query("member_id"); memberID != "" {
+		query = query.Where("member_id = ?", memberID)
+	}
+	if mid, restricted := restrictedMember(c); restricted {
+		query = query.Where("member_id = ?", mid)
+	}
+
+	var allowances []models.Allowance
+	if err := query.Find(&allowances).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch allowances")
+		return
+	}
+
+	utils.RespondWithSuccess(c, allowances)
+}
+
+func CreateAllowance(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var input struct {
+		MemberID      uint    `json:"member_id" binding:"required"`
+		FromAccountID uint    `json:"from_account_id" binding:"required"`
+		ToAccountID   uint    `json:"to_account_id" binding:"required"`
+		Amount        float64 `json:"amount" binding:"required"`
+		Frequency     string  `json:"frequency" binding:"required,oneof=weekly monthly"`
+		StartDate     string  `json:"start_date" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	allowance := models.Allowance{
+		UserID:        userID,
+		MemberID:      input.MemberID,
+		FromAccountID: input.FromAccountID,
+		ToAccountID:   input.ToAccountID,
+		Amount:        input.Amount,
+		Frequency:     input.Frequency,
+		StartDate:     input.StartDate,
+		NextRunDate:   input.StartDate,
+		IsActive:      true,
+	}
+
+	db := utils.RequestDB(c)
+	if err := services.NewAllowanceService(db).Validate(&allowance); err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	if err := db.Create(&allowance).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to create allowance")
+		return
+	}
+
+	utils.RespondWithCreated(c, allowance)
+}
+
+func UpdateAllowance(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid allowance ID")
+		return
+	}
+
+	var input struct {
+		FromAccountID uint    `json:"from_account_id"`
+		ToAccountID   uint    `json:"to_account_id"`
+		Amount        float64 `json:"amount"`
+		Frequency     string  `json:"frequency" binding:"omitempty,oneof=weekly monthly"`
+		NextRunDate   string  `json:"next_run_date"`
+		IsActive      *bool   `json:"is_active"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	db := utils.RequestDB(c)
+	var allowance models.Allowance
+	if err := db.Where("user_id = ? AND id = ?", userID, id).First(&allowance).Error; err != nil {
+		utils.RespondWithError(c, http.StatusNotFound, "Allowance not found")
+		return
+	}
+
+	if input.FromAccountID != 0 {
+		allowance.FromAccountID = input.FromAccountID
+	}
+	if input.ToAccountID != 0 {
+		allowance.ToAccountID = input.ToAccountID
+	}
+	if input.Amount != 0 {
+		allowance.Amount = input.Amount
+	}
+	if input.Frequency != "" && input.Frequency != allowance.Frequency {
+		allowance.Frequency = input.Frequency
+		allowance.StartDate = allowance.NextRunDate
+	}
+	if input.NextRunDate != "" {
+		// jadwal baru dihitung dari tanggal ini
+		allowance.NextRunDate = input.NextRunDate
+		allowance.StartDate = input.NextRunDate
+	}
+	if input.IsActive != nil {
+		allowance.IsActive = *input.IsActive
+	}
+
+	if err := services.NewAllowanceService(db).Validate(&allowance); err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	if err := db.Save(&allowance).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update allowance")
+		return
+	}
+
+	utils.RespondWithSuccess(c, allowance)
+}
+
+func DeleteAllowance(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid allowance ID")
+		return
+	}
+
+	db := utils.RequestDB(c)
+	if err := db.Where("user_id = ? AND id = ?", userID, id).Delete(&models.Allowance{}).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to delete allowance")
+		return
+	}
+
+	utils.RespondWithSuccess(c, gin.H{"message": "Allowance deleted successfully"})
+}
@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"finance-app/services"
+	"finance-app/utils"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAPIKeys godoc
+// @Summary List API keys
+// @Description Daftar API key milik user (kunci asli tidak pernah ditampilkan lagi)
+// @Tags User
+// @Produce json
+// @Success 200 {array} models.APIKey
+// @Router /user/api-keys [get]
+// @Security BearerAuth
+func GetAPIKeys(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	keys, err := services.NewAPIKeyService(utils.RequestDB(c)).List(userID)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch API keys")
+		return
+	}
+
+	utils.RespondWithSuccess(c, keys)
+}
+
+// CreateAPIKey godoc
+// @Summary Create API key
+// @Description Buat API key dengan scope, mis. transactions:read, transactions:write, reports:read. Kunci hanya ditampilkan sekali.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param body body models.APIKeyCreateRequest true "API key input"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Router /user/api-keys [post]
+// @Security BearerAuth
+func CreateAPIKey(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var input struct {
+		Name      string   `json:"name" binding:"required,max=100"`
+		Scopes    []string `json:"scopes" binding:"required"`
+		ExpiresAt string   `json:"expires_at"` // opsional, YYYY-MM-DD
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var expiresAt *time.Time
+	if input.ExpiresAt != "" {
+		t, err := time.Parse("2006-01-02", input.ExpiresAt)
+		if err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, "expires_at must be YYYY-MM-DD")
+			return
+		}
+		expiresAt = &t
+	}
+
+	key, raw, err := services.NewAPIKeyService(utils.RequestDB(c)).Create(userID, input.Name, input.Scopes, expiresAt)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	// kunci mentah hanya dikirim di response ini
+	utils.RespondWithCreated(c, gin.H{
+		"key":     raw,
+		"api_key": key,
+		"note":    "Store this key now, it will not be shown again",
+	})
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke API key
+// @Description Cabut API key; request berikutnya dengan key ini ditolak
+// @Tags User
+// @Produce json
+// @Param id path int true "API key ID"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /user/api-keys/{id} [delete]
+// @Security BearerAuth
+func RevokeAPIKey(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid API key ID")
+		return
+	}
+
+	if err := services.NewAPIKeyService(utils.RequestDB(c)).Revoke(userID, uint(id)); err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	utils.RespondWithSuccess(c, gin.H{"message": "API key revoked"})
+}
@@ -0,0 +1,403 @@
+package controllers
+
+import (
+	"finance-app/models"
+	"finance-app/services"
+	"finance-app/utils"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// findAsset mengambil aset milik ledger; role member hanya boleh aset miliknya.
+func findAsset(c *gin.Context, db *gorm.DB, userID uint) (*models.Asset, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid asset ID")
+		return nil, false
+	}
+
+	query := db.Where("user_id = ? AND id = ?", userID, id)
+	if mid, restricted := restrictedMember(c); restricted {
+		query = query.Where("member_id = ?", mid)
+	}
+
+	var asset models.Asset
+	if err := query.First(&asset).Error; err != nil {
+		utils.RespondWithError(c, http.StatusNotFound, "Asset not found")
+		return nil, false
+	}
+	return &asset, true
+}
+
+// GetAssets godoc
+// @Summary List assets and liabilities
+// @Description Daftar aset non-kas (tanah, kendaraan, emas, ...) dan kewajiban (KPR, pinjaman, ...) beserta valuasi terakhirnya
+// @Tags Assets
+// @Produce json
+// @Param kind query string false "asset or liability"
+// @Param member_id query int false "Member ID"
+// @Param include_closed query bool false "Ikut tampilkan aset yang sudah dijual / lunas"
+// @Success 200 {array} models.Asset
+// @Router /assets [get]
+// @Security BearerAuth
+func GetAssets(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	db := utils.RequestDB(c)
+	query := db.Preload("Member").Where("user_id = ?", userID)
+	if kind := c.Query("kind"); kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	if memberID := c.Query("member_id"); memberID != "" {
+		query = query.Where("member_id = ?", memberID)
+	}
+	if c.Query("include_closed") != "true" {
+		query = query.Where("closed_at IS NULL")
+	}
+	if mid, restricted := restrictedMember(c); restricted {
+		query = query.Where("member_id = ?", mid)
+	}
+
+	var assets []models.Asset
+	if err := query.Order("kind, name").Find(&assets).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch assets")
+		return
+	}
+
+	utils.RespondWithSuccess(c, assets)
+}
+
+// GetAssetCategories godoc
+// @Summary List asset categories
+// @Description Kategori yang valid untuk aset dan kewajiban
+// @Tags Assets
+// @Produce json
+// @Success 200 {object} map[string][]string
+// @Router /assets/categories [get]
+// @Security BearerAuth
+func GetAssetCategories(c *gin.Context) {
+	utils.RespondWithSuccess(c, models.AssetCategories)
+}
+
+// CreateAsset godoc
+// @Summary Create asset or liability
+// @Description Tambah aset/kewajiban beserta valuasi awal. Untuk kewajiban, value adalah sisa utang.
+// @Tags Assets
+// @Accept json
+// @Produce json
+// @Param request body models.AssetRequest true "Asset"
+// @Success 201 {object} models.Asset
+// @Router /assets [post]
+// @Security BearerAuth
+func CreateAsset(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var input struct {
+		MemberID  *uint   `json:"member_id"`
+		Kind      string  `json:"kind" binding:"required,oneof=asset liability"`
+		Category  string  `json:"category" binding:"required"`
+		Name      string  `json:"name" binding:"required"`
+		Currency  string  `json:"currency"`
+		Quantity  float64 `json:"quantity"`
+		Unit      string  `json:"unit"`
+		Notes     string  `json:"notes"`
+		Value     float64 `json:"value"`
+		ValueDate string  `json:"value_date"` // default hari ini
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if mid, restricted := restrictedMember(c); restricted {
+		input.MemberID = &mid
+	}
+	if input.ValueDate == "" {
+		input.ValueDate = time.Now().Format("2006-01-02")
+	}
+
+	asset := models.Asset{
+		UserID:   userID,
+		MemberID: input.MemberID,
+		Kind:     input.Kind,
+		Category: input.Category,
+		Name:     input.Name,
+		Currency: input.Currency,
+		Quantity: input.Quantity,
+		Unit:     input.Unit,
+		Notes:    input.Notes,
+	}
+
+	if err := services.NewAssetService(utils.RequestDB(c)).Create(&asset, input.Value, input.ValueDate); err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	utils.RespondWithCreated(c, asset)
+}
+
+// GetAssetByID godoc
+// @Summary Get asset
+// @Tags Assets
+// @Produce json
+// @Param id path int true "Asset ID"
+// @Success 200 {object} models.Asset
+// @Router /assets/{id} [get]
+// @Security BearerAuth
+func GetAssetByID(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	asset, ok := findAsset(c, utils.RequestDB(c).Preload("Member"), userID)
+	if !ok {
+		return
+	}
+
+	utils.RespondWithSuccess(c, asset)
+}
+
+// UpdateAsset godoc
+// @Summary Update asset
+// @Description Ubah detail aset. Isi closed_at (YYYY-MM-DD) saat aset dijual atau utang lunas; kirim string kosong untuk membuka lagi. Nilai diubah lewat endpoint valuations.
+// @Tags Assets
+// @Accept json
+// @Produce json
+// @Param id path int true "Asset ID"
+// @Success 200 {object} models.Asset
+// @Router /assets/{id} [put]
+// @Security BearerAuth
+func UpdateAsset(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var input struct {
+		MemberID *uint    `json:"member_id"`
+		Category string   `json:"category"`
+		Name     string   `json:"name"`
+		Currency string   `json:"currency"`
+		Quantity *float64 `json:"quantity"`
+		Unit     *string  `json:"unit"`
+		Notes    *string  `json:"notes"`
+		ClosedAt *string  `json:"closed_at"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	db := utils.RequestDB(c)
+	asset, ok := findAsset(c, db, userID)
+	if !ok {
+		return
+	}
+	if err := services.NewAssetService(db).EnsureUnmanaged(asset); err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	if input.MemberID != nil {
+		if _, restricted := restrictedMember(c); !restricted {
+			asset.MemberID = input.MemberID
+		}
+	}
+	if input.Category != "" {
+		asset.Category = input.Category
+	}
+	if input.Name != "" {
+		asset.Name = input.Name
+	}
+	if input.Currency != "" {
+		asset.Currency = input.Currency
+	}
+	if input.Quantity != nil {
+		asset.Quantity = *input.Quantity
+	}
+	if input.Unit != nil {
+		asset.Unit = *input.Unit
+	}
+	if input.Notes != nil {
+		asset.Notes = *input.Notes
+	}
+	if input.ClosedAt != nil {
+		if *input.ClosedAt == "" {
+			asset.ClosedAt = nil
+		} else {
+			asset.ClosedAt = input.ClosedAt
+		}
+	}
+
+	if err := services.NewAssetService(db).Validate(asset); err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	if err := db.Omit("Member").Save(asset).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update asset")
+		return
+	}
+
+	utils.RespondWithSuccess(c, asset)
+}
+
+// DeleteAsset godoc
+// @Summary Delete asset
+// @Description Hapus aset yang salah input. Aset yang dijual / lunas sebaiknya ditutup lewat closed_at supaya riwayat net worth tetap utuh. Aset milik pinjaman / akun investasi ditolak dengan 409.
+// @Tags Assets
+// @Produce json
+// @Param id path int true "Asset ID"
+// @Success 200 {object} models.DeleteResponse
+// @Router /assets/{id} [delete]
+// @Security BearerAuth
+func DeleteAsset(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	db := utils.RequestDB(c)
+	asset, ok := findAsset(c, db, userID)
+	if !ok {
+		return
+	}
+	if err := services.NewAssetService(db).EnsureUnmanaged(asset); err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	if err := db.Delete(asset).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to delete asset")
+		return
+	}
+
+	utils.RespondWithSuccess(c, gin.H{"message": "Asset deleted successfully"})
+}
+
+// GetAssetValuations godoc
+// @Summary List asset valuations
+// @Tags Assets
+// @Produce json
+// @Param id path int true "Asset ID"
+// @Success 200 {array} models.AssetValuation
+// @Router /assets/{id}/valuations [get]
+// @Security BearerAuth
+func GetAssetValuations(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	db := utils.RequestDB(c)
+	asset, ok := findAsset(c, db, userID)
+	if !ok {
+		return
+	}
+
+	var valuations []models.AssetValuation
+	if err := db.Where("asset_id = ?", asset.ID).Order("date DESC").Find(&valuations).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch valuations")
+		return
+	}
+
+	utils.RespondWithSuccess(c, valuations)
+}
+
+// CreateAssetValuation godoc
+// @Summary Record asset valuation
+// @Description Catat nilai aset (atau sisa utang) pada suatu tanggal. Valuasi di tanggal yang sama ditimpa.
+// @Tags Assets
+// @Accept json
+// @Produce json
+// @Param id path int true "Asset ID"
+// @Param request body models.AssetValuationRequest true "Valuation"
+// @Success 201 {object} models.AssetValuation
+// @Router /assets/{id}/valuations [post]
+// @Security BearerAuth
+func CreateAssetValuation(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var input struct {
+		Date  string   `json:"date"` // default hari ini
+		Value *float64 `json:"value" binding:"required"`
+		Note  string   `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if input.Date == "" {
+		input.Date = time.Now().Format("2006-01-02")
+	}
+
+	db := utils.RequestDB(c)
+	asset, ok := findAsset(c, db, userID)
+	if !ok {
+		return
+	}
+
+	valuation, err := services.NewAssetService(db).AddValuation(asset, input.Date, *input.Value, input.Note)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	utils.RespondWithCreated(c, valuation)
+}
+
+// DeleteAssetValuation godoc
+// @Summary Delete asset valuation
+// @Tags Assets
+// @Produce json
+// @Param id path int true "Asset ID"
+// @Param valuation_id path int true "Valuation ID"
+// @Success 200 {object} models.DeleteResponse
+// @Router /assets/{id}/valuations/{valuation_id} [delete]
+// @Security BearerAuth
+func DeleteAssetValuation(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	valuationID, err := strconv.Atoi(c.Param("valuation_id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid valuation ID")
+		return
+	}
+
+	db := utils.RequestDB(c)
+	asset, ok := findAsset(c, db, userID)
+	if !ok {
+		return
+	}
+
+	if err := services.NewAssetService(db).DeleteValuation(asset, uint(valuationID)); err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	utils.RespondWithSuccess(c, gin.H{"message": "Valuation deleted successfully"})
+}
@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"finance-app/services"
+	"finance-app/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAuditLogs godoc
+// @Summary List audit log
+// @Description Riwayat perubahan data keuangan (siapa, kapan, dari IP mana, sebelum/sesudah). Filter: entity, entity_id, action, actor_user_id, start_date, end_date.
+// @Tags Audit
+// @Produce json
+// @Param entity query string false "transaction, transfer, account, budget, saving_target, recurring_transaction, asset, asset_valuation, loan, loan_installment, security, investment_lot, investment_dividend"
+// @Param entity_id query int false "Entity ID"
+// @Param action query string false "create, update, delete"
+// @Param page query int false "Page"
+// @Param limit query int false "Limit"
+// @Success 200 {array} models.AuditLog
+// @Router /audit [get]
+// @Security BearerAuth
+func GetAuditLogs(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, err)
+		return
+	}
+
+	var q services.AuditQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+
+	logs, total, err := services.NewAuditService(utils.RequestDB(c)).List(userID, &q)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	utils.RespondWithPaginatedData(c, logs, total, q.Page, q.Limit)
+}
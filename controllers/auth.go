@@ -1,15 +1,20 @@
 package controllers
 
 import (
-	"finance-app/database"
+	"errors"
 	"finance-app/models"
+	"finance-app/services"
 	"finance-app/utils"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
+var errUserExists = errors.New("username or email already exists")
+
 // Register godoc
 // @Summary Register new user
 // @Description Membuat user baru
@@ -26,6 +31,7 @@ func Register(c *gin.Context) {
 		Username string `json:"username" binding:"required"`
 		Email    string `json:"email" binding:"required,email"`
 		Password string `json:"password" binding:"required,min=6"`
+		Locale   string `json:"locale"` // bahasa kategori bawaan
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -45,13 +51,37 @@ func Register(c *gin.Context) {
 		Password: string(hashedPassword),
 	}
 
-	db := database.GetDB()
-	if err := db.Create(&user).Error; err != nil {
+	if input.Locale == "" {
+		input.Locale = services.DefaultCategoryLocale
+	}
+	if !services.IsSupportedCategoryLocale(input.Locale) {
+		utils.RespondWithError(c, http.StatusBadRequest, "Unsupported locale")
+		return
+	}
+
+	db := utils.RequestDB(c)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return errUserExists
+		}
+		if _, err := utils.EnsurePersonalWorkspace(tx, user); err != nil {
+			return err
+		}
+		// kategori bawaan supaya akun baru langsung bisa dipakai
+		return services.NewCategoryService(tx).SeedDefaults(user.ID, input.Locale)
+	})
+	if err == errUserExists {
 		utils.RespondWithError(c, http.StatusConflict, "Username or email already exists")
 		return
 	}
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to register user")
+		return
+	}
+
+	services.NewUserTokenService(db, services.MailerFromConfig()).SendEmailVerificationAsync(user)
 
-	utils.RespondWithSuccess(c, gin.H{"message": "User registered successfully"})
+	utils.RespondWithSuccess(c, gin.H{"message": "User registered successfully. Please check your email to verify your address."})
 }
 
 // Login godoc
@@ -68,6 +98,7 @@ func Login(c *gin.Context) {
 	var input struct {
 		Email    string `json:"email" binding:"required"`
 		Password string `json:"password" binding:"required"`
+		Device   string `json:"device"` // nama perangkat opsional, mis. "Pixel 7"
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -76,7 +107,7 @@ func Login(c *gin.Context) {
 	}
 
 	var user models.User
-	db := database.GetDB()
+	db := utils.RequestDB(c)
 	if err := db.Where("email = ?", input.Email).First(&user).Error; err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Invalid email or password")
 		return
@@ -87,26 +118,275 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	token, err := utils.GenerateToken(user.ID)
+	if user.TwoFactorEnabled {
+		// password benar, tapi token baru diberikan setelah kode 2FA diverifikasi
+		challenge, err := utils.GenerateChallengeToken(user.ID)
+		if err != nil {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to generate token")
+			return
+		}
+		utils.RespondWithSuccess(c, gin.H{
+			"two_factor_required": true,
+			"challenge_token":     challenge,
+			"expires_in":          int64(utils.ChallengeTokenTTL.Seconds()),
+		})
+		return
+	}
+
+	pair, err := services.NewTokenService(db).IssueTokenPair(user.ID, sessionMeta(c, input.Device))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to generate token")
+		return
+	}
+
+	utils.RespondWithSuccess(c, pair)
+}
+
+// LoginTwoFactor godoc
+// @Summary Login step 2 (2FA)
+// @Description Verifikasi kode TOTP atau recovery code dengan challenge token dari /login
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body models.AuthTwoFactorRequest true "Challenge token dan kode"
+// @Success 200 {object} models.AuthResponse
+// @Failure 401 {object} map[string]string
+// @Router /login/2fa [post]
+func LoginTwoFactor(c *gin.Context) {
+	var input struct {
+		ChallengeToken string `json:"challenge_token" binding:"required"`
+		Code           string `json:"code" binding:"required"`
+		Device         string `json:"device"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID, err := utils.ParseChallengeToken(input.ChallengeToken)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Invalid or expired challenge token")
+		return
+	}
+
+	db := utils.RequestDB(c)
+	if err := services.NewTwoFactorService(db).Verify(userID, input.Code); err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	pair, err := services.NewTokenService(db).IssueTokenPair(userID, sessionMeta(c, input.Device))
 	if err != nil {
 		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to generate token")
 		return
 	}
 
-	utils.RespondWithSuccess(c, gin.H{"token": token})
+	utils.RespondWithSuccess(c, pair)
+}
+
+// sessionMeta mengambil info perangkat dari request untuk dicatat di sesi.
+func sessionMeta(c *gin.Context, device string) services.SessionMeta {
+	ua := c.GetHeader("User-Agent")
+	if device == "" {
+		device = deviceFromUserAgent(ua)
+	}
+	return services.SessionMeta{
+		Device:    device,
+		IPAddress: c.ClientIP(),
+		UserAgent: ua,
+	}
+}
+
+// deviceFromUserAgent menebak jenis perangkat secara kasar dari User-Agent.
+func deviceFromUserAgent(ua string) string {
+	lower := strings.ToLower(ua)
+	switch {
+	case lower == "":
+		return "Unknown"
+	case strings.Contains(lower, "iphone"):
+		return "iPhone"
+	case strings.Contains(lower, "ipad"):
+		return "iPad"
+	case strings.Contains(lower, "android"):
+		return "Android"
+	case strings.Contains(lower, "windows"):
+		return "Windows"
+	case strings.Contains(lower, "mac os"):
+		return "Mac"
+	case strings.Contains(lower, "linux"):
+		return "Linux"
+	}
+	return "Other"
+}
+
+// RefreshToken godoc
+// @Summary Refresh access token
+// @Description Tukar refresh token dengan pasangan token baru. Refresh token lama langsung tidak berlaku.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body models.AuthRefreshRequest true "Refresh token"
+// @Success 200 {object} models.AuthResponse
+// @Failure 401 {object} map[string]string
+// @Router /token/refresh [post]
+func RefreshToken(c *gin.Context) {
+	var input struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	pair, err := services.NewTokenService(utils.RequestDB(c)).Refresh(input.RefreshToken)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	utils.RespondWithSuccess(c, pair)
 }
 
 // Logout godoc
 // @Summary Logout user
-// @Description Logout dengan menghapus token di sisi client (stateless JWT).
+// @Description Mencabut access token saat ini beserta refresh token sesinya.
 // @Tags Auth
 // @Produce json
 // @Success 200 {object} map[string]string
 // @Router /logout [post]
 // @Security BearerAuth
 func Logout(c *gin.Context) {
-	// Karena JWT stateless, server hanya balikin response sukses.
-	utils.RespondWithSuccess(c, gin.H{
-		"message": "Logout successful. Please remove token on client side.",
-	})
+	principal, err := utils.GetPrincipal(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	if err := services.NewTokenService(utils.RequestDB(c)).Logout(principal); err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to logout")
+		return
+	}
+
+	utils.RespondWithSuccess(c, gin.H{"message": "Logout successful"})
+}
+
+// ForgotPassword godoc
+// @Summary Request password reset
+// @Description Kirim link reset password ke email. Response selalu sukses supaya email terdaftar tidak bisa ditebak.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body models.ForgotPasswordRequest true "Email"
+// @Success 200 {object} map[string]string
+// @Router /password/forgot [post]
+func ForgotPassword(c *gin.Context) {
+	var input struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := services.NewUserTokenService(utils.RequestDB(c), services.MailerFromConfig()).RequestPasswordReset(input.Email); err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to send reset email")
+		return
+	}
+
+	utils.RespondWithSuccess(c, gin.H{"message": "If the email is registered, a reset link has been sent"})
+}
+
+// ResetPassword godoc
+// @Summary Reset password
+// @Description Ganti password memakai token dari email. Semua sesi yang ada akan dicabut.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body models.ResetPasswordRequest true "Token dan password baru"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Router /password/reset [post]
+func ResetPassword(c *gin.Context) {
+	var input struct {
+		Token    string `json:"token" binding:"required"`
+		Password string `json:"password" binding:"required,min=6"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := services.NewUserTokenService(utils.RequestDB(c), services.MailerFromConfig()).ResetPassword(input.Token, input.Password); err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	utils.RespondWithSuccess(c, gin.H{"message": "Password has been reset. Please login again."})
+}
+
+// VerifyEmail godoc
+// @Summary Verify email
+// @Description Konfirmasi alamat email memakai token dari email verifikasi
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body models.VerifyEmailRequest true "Token verifikasi"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Router /email/verify [post]
+func VerifyEmail(c *gin.Context) {
+	var input struct {
+		Token string `json:"token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := services.NewUserTokenService(utils.RequestDB(c), services.MailerFromConfig()).VerifyEmail(input.Token); err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	utils.RespondWithSuccess(c, gin.H{"message": "Email verified"})
+}
+
+// ResendEmailVerification godoc
+// @Summary Resend verification email
+// @Description Kirim ulang email verifikasi ke alamat email user saat ini
+// @Tags User
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /user/email/verification [post]
+// @Security BearerAuth
+func ResendEmailVerification(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	db := utils.RequestDB(c)
+	var user models.User
+	if err := db.First(&user, userID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusNotFound, "User not found")
+		return
+	}
+	if user.EmailVerifiedAt != nil {
+		utils.RespondWithError(c, http.StatusConflict, "Email is already verified")
+		return
+	}
+
+	if err := services.NewUserTokenService(db, services.MailerFromConfig()).SendEmailVerification(user); err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to send verification email")
+		return
+	}
+
+	utils.RespondWithSuccess(c, gin.H{"message": "Verification email sent"})
 }
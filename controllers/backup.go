@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"encoding/json"
+	"finance-app/services"
+	"finance-app/utils"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxBackupSize membatasi ukuran file yang bisa di-import.
+const maxBackupSize = 50 << 20
+
+// ExportUserData godoc
+// @Summary Export all data
+// @Description Mengunduh seluruh data (member, akun, kategori, transaksi, transfer, budget, recurring, saving target) sebagai arsip JSON berversi.
+// @Tags User
+// @Produce json
+// @Success 200 {object} services.BackupArchive
+// @Router /user/export [get]
+// @Security BearerAuth
+func ExportUserData(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	archive, err := services.NewBackupService(utils.RequestDB(c)).Export(userID)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	body, err := json.MarshalIndent(archive, "", "  ")
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to build export")
+		return
+	}
+
+	filename := fmt.Sprintf("finance-backup-%s.json", archive.ExportedAt.Format("20060102-150405"))
+	c.Header("Content-Description", "File Transfer")
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Data(http.StatusOK, "application/json", body)
+}
+
+// ImportUserData godoc
+// @Summary Import data from backup
+// @Description Memulihkan arsip hasil export ke akun yang masih kosong. Kirim file lewat multipart (field "file") atau langsung sebagai body JSON. Semua ID dibuat ulang.
+// @Tags User
+// @Accept json
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file false "Backup file"
+// @Success 201 {object} services.ImportResult
+// @Failure 409 {object} map[string]interface{}
+// @Router /user/import [post]
+// @Security BearerAuth
+func ImportUserData(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBackupSize)
+
+	var reader io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		file, err := c.FormFile("file")
+		if err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, "Backup file is required")
+			return
+		}
+		f, err := file.Open()
+		if err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, "Failed to read backup file")
+			return
+		}
+		defer f.Close()
+		reader = f
+	}
+
+	var archive services.BackupArchive
+	if err := json.NewDecoder(reader).Decode(&archive); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid backup file")
+		return
+	}
+
+	result, err := services.NewBackupService(utils.RequestDB(c)).Import(userID, &archive)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	utils.RespondWithCreated(c, gin.H{
+		"message": "Backup imported successfully",
+		"result":  result,
+	})
+}
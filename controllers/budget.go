@@ -1,7 +1,6 @@
 package controllers
 
 import (
-	"finance-app/database"
 	"finance-app/models"
 	"finance-app/utils"
 	"net/http"
@@ -12,7 +11,7 @@ import (
 )
 
 func GetBudgets(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -21,7 +20,7 @@ func GetBudgets(c *gin.Context) {
 	period := c.Query("period")
 	categoryID := c.Query("category_id")
 
-	db := database.GetDB()
+	db := utils.RequestDB(c)
 	query := db.Where("user_id = ?", userID)
 
 	if period != "" {
@@ -41,7 +40,7 @@ func GetBudgets(c *gin.Context) {
 }
 
 func CreateBudget(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -58,13 +57,18 @@ func CreateBudget(c *gin.Context) {
 		return
 	}
 
-	db := database.GetDB()
+	db := utils.RequestDB(c)
 	var category models.Category
 	if err := db.Where("user_id = ? AND id = ?", userID, input.CategoryID).First(&category).Error; err != nil {
 		utils.RespondWithError(c, http.StatusNotFound, "Category not found")
 		return
 	}
 
+	if category.ArchivedAt != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Category is archived")
+		return
+	}
+
 	// ✅ Hanya untuk kategori expense
 	if category.Type != "expense" {
 		utils.RespondWithError(c, http.StatusBadRequest, "Budget hanya untuk kategori expense")
@@ -107,7 +111,7 @@ func CreateBudget(c *gin.Context) {
 }
 
 func UpdateBudget(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -129,7 +133,7 @@ func UpdateBudget(c *gin.Context) {
 		return
 	}
 
-	db := database.GetDB()
+	db := utils.RequestDB(c)
 	var budget models.BudgetCategory
 	if err := db.Where("user_id = ? AND id = ?", userID, id).First(&budget).Error; err != nil {
 		utils.RespondWithError(c, http.StatusNotFound, "Budget not found")
@@ -184,7 +188,7 @@ func UpdateBudget(c *gin.Context) {
 }
 
 func GetBudgetByID(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -196,7 +200,7 @@ func GetBudgetByID(c *gin.Context) {
 		return
 	}
 
-	db := database.GetDB()
+	db := utils.RequestDB(c)
 	var budget models.BudgetCategory
 	if err := db.Where("user_id = ? AND id = ?", userID, id).First(&budget).Error; err != nil {
 		utils.RespondWithError(c, http.StatusNotFound, "Budget not found")
@@ -207,7 +211,7 @@ func GetBudgetByID(c *gin.Context) {
 }
 
 func DeleteBudget(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -219,7 +223,7 @@ func DeleteBudget(c *gin.Context) {
 		return
 	}
 
-	db := database.GetDB()
+	db := utils.RequestDB(c)
 	if err := db.Where("user_id = ? AND id = ?", userID, id).Delete(&models.BudgetCategory{}).Error; err != nil {
 		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to delete budget")
 		return
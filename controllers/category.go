@@ -1,26 +1,29 @@
 package controllers
 
 import (
-	"finance-app/database"
 	"finance-app/models"
+	"finance-app/services"
 	"finance-app/utils"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 func GetCategories(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
 	categoryType := c.Query("type")
-	search := c.Query("search") // optional filter nama kategori
+	search := c.Query("search")      // optional filter nama kategori
+	parentID := c.Query("parent_id") // "0" = hanya kategori utama
+	includeArchived := c.Query("include_archived") == "true"
 
-	db := database.GetDB()
+	db := utils.RequestDB(c)
 	query := db.Where("user_id = ?", userID)
 
 	if categoryType != "" {
@@ -29,6 +32,14 @@ func GetCategories(c *gin.Context) {
 	if search != "" {
 		query = query.Where("name LIKE ?", "%"+search+"%")
 	}
+	if !includeArchived {
+		query = query.Where("archived_at IS NULL")
+	}
+	if parentID == "0" {
+		query = query.Where("parent_id IS NULL")
+	} else if parentID != "" {
+		query = query.Where("parent_id = ?", parentID)
+	}
 
 	var categories []models.Category
 	if err := query.Order("type DESC, name ASC").Find(&categories).Error; err != nil {
@@ -39,16 +50,48 @@ func GetCategories(c *gin.Context) {
 	utils.RespondWithSuccess(c, categories)
 }
 
+func GetCategoryTree(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	query := utils.RequestDB(c).Where("user_id = ?", userID)
+	if c.Query("include_archived") != "true" {
+		query = query.Where("archived_at IS NULL")
+	}
+	var categories []models.Category
+	if err := query.Order("name ASC").Find(&categories).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch categories")
+		return
+	}
+
+	nodes := services.NewCategoryTree(categories).Nodes()
+	if categoryType := c.Query("type"); categoryType != "" {
+		filtered := nodes[:0]
+		for _, n := range nodes {
+			if n.Type == categoryType {
+				filtered = append(filtered, n)
+			}
+		}
+		nodes = filtered
+	}
+
+	utils.RespondWithSuccess(c, nodes)
+}
+
 func CreateCategory(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
 	var input struct {
-		Name string `json:"name" binding:"required"`
-		Type string `json:"type" binding:"required,oneof=income expense"`
+		Name     string `json:"name" binding:"required"`
+		Type     string `json:"type" binding:"required,oneof=income expense"`
+		ParentID *uint  `json:"parent_id"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -56,13 +99,29 @@ func CreateCategory(c *gin.Context) {
 		return
 	}
 
+	db := utils.RequestDB(c)
+	if input.ParentID != nil && *input.ParentID == 0 {
+		input.ParentID = nil
+	}
+	if input.ParentID != nil {
+		tree, err := services.LoadCategoryTree(db, userID)
+		if err != nil {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch categories")
+			return
+		}
+		if err := tree.ValidateParent(0, *input.ParentID, input.Type); err != nil {
+			respondWithServiceError(c, err)
+			return
+		}
+	}
+
 	category := models.Category{
-		UserID: userID,
-		Name:   input.Name,
-		Type:   input.Type,
+		UserID:   userID,
+		ParentID: input.ParentID,
+		Name:     input.Name,
+		Type:     input.Type,
 	}
 
-	db := database.GetDB()
 	if err := db.Create(&category).Error; err != nil {
 		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to create category")
 		return
@@ -72,7 +131,7 @@ func CreateCategory(c *gin.Context) {
 }
 
 func GetCategoryByID(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -84,7 +143,7 @@ func GetCategoryByID(c *gin.Context) {
 		return
 	}
 
-	db := database.GetDB()
+	db := utils.RequestDB(c)
 	var category models.Category
 	if err := db.Where("user_id = ? AND id = ?", userID, id).First(&category).Error; err != nil {
 		utils.RespondWithError(c, http.StatusNotFound, "Category not found")
@@ -95,7 +154,7 @@ func GetCategoryByID(c *gin.Context) {
 }
 
 func UpdateCategory(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -108,8 +167,9 @@ func UpdateCategory(c *gin.Context) {
 	}
 
 	var input struct {
-		Name string `json:"name"`
-		Type string `json:"type"`
+		Name     string `json:"name"`
+		Type     string `json:"type" binding:"omitempty,oneof=income expense"`
+		ParentID *uint  `json:"parent_id"` // 0 = jadikan kategori utama
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -117,7 +177,7 @@ func UpdateCategory(c *gin.Context) {
 		return
 	}
 
-	db := database.GetDB()
+	db := utils.RequestDB(c)
 	var category models.Category
 	if err := db.Where("user_id = ? AND id = ?", userID, id).First(&category).Error; err != nil {
 		utils.RespondWithError(c, http.StatusNotFound, "Category not found")
@@ -127,10 +187,37 @@ func UpdateCategory(c *gin.Context) {
 	if input.Name != "" {
 		category.Name = input.Name
 	}
+
+	newType := category.Type
 	if input.Type != "" {
-		category.Type = input.Type
+		newType = input.Type
+	}
+	newParentID := category.ParentID
+	if input.ParentID != nil {
+		newParentID = input.ParentID
+		if *input.ParentID == 0 {
+			newParentID = nil
+		}
 	}
 
+	tree, err := services.LoadCategoryTree(db, userID)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch categories")
+		return
+	}
+	if newType != category.Type && len(tree.DescendantIDs(category.ID)) > 1 {
+		utils.RespondWithError(c, http.StatusBadRequest, "Cannot change type of a category that has sub-categories")
+		return
+	}
+	if newParentID != nil {
+		if err := tree.ValidateParent(category.ID, *newParentID, newType); err != nil {
+			respondWithServiceError(c, err)
+			return
+		}
+	}
+	category.Type = newType
+	category.ParentID = newParentID
+
 	if err := db.Save(&category).Error; err != nil {
 		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update category")
 		return
@@ -140,7 +227,7 @@ func UpdateCategory(c *gin.Context) {
 }
 
 func DeleteCategory(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -152,11 +239,107 @@ func DeleteCategory(c *gin.Context) {
 		return
 	}
 
-	db := database.GetDB()
-	if err := db.Where("user_id = ? AND id = ?", userID, id).Delete(&models.Category{}).Error; err != nil {
-		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to delete category")
+	var opts services.DeleteOptions
+	if err := c.ShouldBindQuery(&opts); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+
+	service := services.NewIntegrityService(utils.RequestDB(c))
+	if err := service.DeleteCategory(userID, uint(id), opts); err != nil {
+		respondWithServiceError(c, err)
 		return
 	}
 
 	utils.RespondWithSuccess(c, gin.H{"message": "Category deleted successfully"})
 }
+
+func MergeCategory(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid category ID")
+		return
+	}
+
+	var input struct {
+		TargetID uint `json:"target_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	service := services.NewCategoryService(utils.RequestDB(c))
+	target, err := service.Merge(userID, uint(id), input.TargetID)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	utils.RespondWithSuccess(c, gin.H{
+		"message":  "Category merged successfully",
+		"category": target,
+	})
+}
+
+func ArchiveCategory(c *gin.Context) {
+	setCategoryArchived(c, true)
+}
+
+func UnarchiveCategory(c *gin.Context) {
+	setCategoryArchived(c, false)
+}
+
+// setCategoryArchived ikut mengarsipkan / memulihkan seluruh sub-kategori.
+func setCategoryArchived(c *gin.Context, archived bool) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid category ID")
+		return
+	}
+
+	db := utils.RequestDB(c)
+	tree, err := services.LoadCategoryTree(db, userID)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch categories")
+		return
+	}
+	category, ok := tree.Get(uint(id))
+	if !ok {
+		utils.RespondWithError(c, http.StatusNotFound, "Category not found")
+		return
+	}
+	if !archived && category.ParentID != nil {
+		if parent, ok := tree.Get(*category.ParentID); ok && parent.ArchivedAt != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, "Parent category is archived")
+			return
+		}
+	}
+
+	var archivedAt *time.Time
+	if archived {
+		now := time.Now()
+		archivedAt = &now
+	}
+	if err := db.Model(&models.Category{}).
+		Where("user_id = ? AND id IN ?", userID, tree.DescendantIDs(category.ID)).
+		Update("archived_at", archivedAt).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update category")
+		return
+	}
+
+	category.ArchivedAt = archivedAt
+	utils.RespondWithSuccess(c, category)
+}
@@ -1,36 +1,65 @@
 package controllers
 
 import (
-	"finance-app/database"
 	"finance-app/models"
+	"finance-app/services"
 	"finance-app/utils"
 	"net/http"
+	"sort"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 func GetDashboard(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
-	db := database.GetDB()
+	db := utils.RequestDB(c)
 	now := time.Now()
 	currentYear, currentMonth := now.Year(), int(now.Month())
 	lastMonth := now.AddDate(0, -1, 0)
 	lastYear, lastMonthNum := lastMonth.Year(), int(lastMonth.Month())
 
+	// Role member hanya melihat data member miliknya sendiri.
+	// memberFilter dipakai untuk tabel transactions tanpa alias, tMemberFilter untuk alias t.
+	memberFilter, tMemberFilter, accMemberFilter := "", "", ""
+	var memberArgs []interface{}
+	if mid, restricted := restrictedMember(c); restricted {
+		memberFilter = " AND member_id = ?"
+		tMemberFilter = " AND t.member_id = ?"
+		accMemberFilter = " AND a.member_id = ?"
+		memberArgs = []interface{}{mid}
+	}
+	withMember := func(args ...interface{}) []interface{} {
+		return append(args, memberArgs...)
+	}
+
 	// 1. Total balance semua akun user
 	var totalBalance float64
 	db.Raw(`
 		SELECT COALESCE(SUM(a.balance),0)
 		FROM accounts a
 		JOIN members m ON m.id = a.member_id
-		WHERE m.user_id = ?
-	`, userID).Scan(&totalBalance)
+		WHERE m.user_id = ?`+accMemberFilter,
+		withMember(userID)...).Scan(&totalBalance)
+
+	// 1b. Aset non-kas & kewajiban (valuasi terakhir) untuk net worth
+	var assetTotals struct {
+		Assets      float64
+		Liabilities float64
+	}
+	today := now.Format("2006-01-02")
+	db.Raw(`
+		SELECT
+			COALESCE(SUM(CASE WHEN kind='asset' THEN current_value ELSE 0 END),0) AS assets,
+			COALESCE(SUM(CASE WHEN kind='liability' THEN current_value ELSE 0 END),0) AS liabilities
+		FROM assets
+		WHERE user_id = ? AND deleted_at IS NULL AND (closed_at IS NULL OR closed_at > ?)`+memberFilter,
+		withMember(userID, today)...).Scan(&assetTotals)
 
 	// 2. Income & Expense bulan ini
 	var currentSummary struct {
@@ -42,8 +71,8 @@ func GetDashboard(c *gin.Context) {
 			COALESCE(SUM(CASE WHEN type='income' THEN amount ELSE 0 END),0) AS income,
 			COALESCE(SUM(CASE WHEN type='expense' THEN amount ELSE 0 END),0) AS expense
 		FROM transactions
-		WHERE user_id = ? AND YEAR(date)=? AND MONTH(date)=?
-	`, userID, currentYear, currentMonth).Scan(&currentSummary)
+		WHERE user_id = ? AND YEAR(date)=? AND MONTH(date)=?`+memberFilter,
+		withMember(userID, currentYear, currentMonth)...).Scan(&currentSummary)
 
 	// 3. Income & Expense bulan lalu
 	var lastSummary struct {
@@ -55,15 +84,17 @@ func GetDashboard(c *gin.Context) {
 			COALESCE(SUM(CASE WHEN type='income' THEN amount ELSE 0 END),0) AS income,
 			COALESCE(SUM(CASE WHEN type='expense' THEN amount ELSE 0 END),0) AS expense
 		FROM transactions
-		WHERE user_id = ? AND YEAR(date)=? AND MONTH(date)=?
-	`, userID, lastYear, lastMonthNum).Scan(&lastSummary)
+		WHERE user_id = ? AND YEAR(date)=? AND MONTH(date)=?`+memberFilter,
+		withMember(userID, lastYear, lastMonthNum)...).Scan(&lastSummary)
 
 	// Struct reuse untuk kategori chart
 	type CategoryChart struct {
+		ID    uint    `json:"-"`
 		Name  string  `json:"name"`
 		Total float64 `json:"total"`
 	}
 	type BarChart struct {
+		ID       uint    `json:"-"`
 		Category string  `json:"category"`
 		Income   float64 `json:"income"`
 		Expense  float64 `json:"expense"`
@@ -72,53 +103,105 @@ func GetDashboard(c *gin.Context) {
 	// 4. Pie chart (hanya kategori user ini)
 	var pieCategories []CategoryChart
 	db.Raw(`
-		SELECT c.name, COALESCE(SUM(t.amount),0) AS total
+		SELECT c.id, c.name, COALESCE(SUM(t.amount),0) AS total
 		FROM categories c
 		LEFT JOIN transactions t 
 			ON t.category_id=c.id AND t.user_id=? AND t.type='expense'
-			AND YEAR(t.date)=? AND MONTH(t.date)=?
+			AND YEAR(t.date)=? AND MONTH(t.date)=?`+tMemberFilter+`
 		WHERE c.user_id = ? 
-		GROUP BY c.name
+		GROUP BY c.id, c.name
 		ORDER BY total DESC
-	`, userID, currentYear, currentMonth, userID).Scan(&pieCategories)
+	`, append(withMember(userID, currentYear, currentMonth), userID)...).Scan(&pieCategories)
 
 	// 5. Top transaksi terbesar bulan ini
 	var topTransactions []models.Transaction
-	db.Where("user_id = ? AND YEAR(date) = ? AND MONTH(date) = ?", userID, currentYear, currentMonth).
+	db.Where("user_id = ? AND YEAR(date) = ? AND MONTH(date) = ?"+memberFilter, withMember(userID, currentYear, currentMonth)...).
 		Order("amount DESC").Limit(5).Find(&topTransactions)
 
 	// 6. Bar chart data per kategori (income & expense)
 	var barChart []BarChart
 	db.Raw(`
-		SELECT c.name AS category,
+		SELECT c.id, c.name AS category,
 			COALESCE(SUM(CASE WHEN t.type='income' THEN t.amount ELSE 0 END),0) AS income,
 			COALESCE(SUM(CASE WHEN t.type='expense' THEN t.amount ELSE 0 END),0) AS expense
 		FROM categories c
 		LEFT JOIN transactions t 
 			ON t.category_id=c.id AND t.user_id=? 
-			AND YEAR(t.date)=? AND MONTH(t.date)=?
+			AND YEAR(t.date)=? AND MONTH(t.date)=?`+tMemberFilter+`
 		WHERE c.user_id = ?
-		GROUP BY c.name
+		GROUP BY c.id, c.name
 		ORDER BY (COALESCE(SUM(t.amount),0)) DESC
-	`, userID, currentYear, currentMonth, userID).Scan(&barChart)
+	`, append(withMember(userID, currentYear, currentMonth), userID)...).Scan(&barChart)
 
 	// 7. Top 3 kategori
 	var top3Categories []CategoryChart
 	db.Raw(`
-		SELECT c.name, COALESCE(SUM(t.amount),0) AS total
+		SELECT c.id, c.name, COALESCE(SUM(t.amount),0) AS total
 		FROM categories c
 		LEFT JOIN transactions t 
 			ON t.category_id=c.id AND t.user_id=? AND t.type='expense'
-			AND YEAR(t.date)=? AND MONTH(t.date)=?
+			AND YEAR(t.date)=? AND MONTH(t.date)=?`+tMemberFilter+`
 		WHERE c.user_id = ?
-		GROUP BY c.name
+		GROUP BY c.id, c.name
 		ORDER BY total DESC
 		LIMIT 3
-	`, userID, currentYear, currentMonth, userID).Scan(&top3Categories)
+	`, append(withMember(userID, currentYear, currentMonth), userID)...).Scan(&top3Categories)
+
+	// 8. Rollup sub-kategori ke kategori utama (?rollup=true)
+	if c.Query("rollup") == "true" {
+		tree, err := services.LoadCategoryTree(db, userID)
+		if err != nil {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch categories")
+			return
+		}
+		rootName := func(id uint) (uint, string) {
+			root := tree.RootOf(id)
+			cat, _ := tree.Get(root)
+			return root, cat.Name
+		}
+
+		pieIdx := map[uint]int{}
+		var rolledPie []CategoryChart
+		for _, p := range pieCategories {
+			root, name := rootName(p.ID)
+			if i, ok := pieIdx[root]; ok {
+				rolledPie[i].Total += p.Total
+				continue
+			}
+			pieIdx[root] = len(rolledPie)
+			rolledPie = append(rolledPie, CategoryChart{ID: root, Name: name, Total: p.Total})
+		}
+		sort.SliceStable(rolledPie, func(i, j int) bool { return rolledPie[i].Total > rolledPie[j].Total })
+		pieCategories = rolledPie
+		top3Categories = rolledPie
+		if len(top3Categories) > 3 {
+			top3Categories = top3Categories[:3]
+		}
+
+		barIdx := map[uint]int{}
+		var rolledBar []BarChart
+		for _, b := range barChart {
+			root, name := rootName(b.ID)
+			if i, ok := barIdx[root]; ok {
+				rolledBar[i].Income += b.Income
+				rolledBar[i].Expense += b.Expense
+				continue
+			}
+			barIdx[root] = len(rolledBar)
+			rolledBar = append(rolledBar, BarChart{ID: root, Category: name, Income: b.Income, Expense: b.Expense})
+		}
+		sort.SliceStable(rolledBar, func(i, j int) bool {
+			return rolledBar[i].Income+rolledBar[i].Expense > rolledBar[j].Income+rolledBar[j].Expense
+		})
+		barChart = rolledBar
+	}
 
 	// Response
 	utils.RespondWithSuccess(c, gin.H{
 		"total_balance":      totalBalance,
+		"total_assets":       assetTotals.Assets,
+		"total_liabilities":  assetTotals.Liabilities,
+		"net_worth":          totalBalance + assetTotals.Assets - assetTotals.Liabilities,
 		"income_this_month":  currentSummary.Income,
 		"expense_this_month": currentSummary.Expense,
 		"income_last_month":  lastSummary.Income,
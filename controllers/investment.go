@@ -0,0 +1,636 @@
+package controllers
+
+import (
+	"finance-app/models"
+	"finance-app/services"
+	"finance-app/utils"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxPriceCSVSize membatasi ukuran file CSV harga.
+const maxPriceCSVSize = 10 << 20
+
+// findSecurity mengambil instrumen milik ledger berdasarkan path :id.
+func findSecurity(c *gin.Context, db *gorm.DB, userID uint) (*models.Security, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid security ID")
+		return nil, false
+	}
+
+	var sec models.Security
+	if err := db.Where("user_id = ? AND id = ?", userID, id).First(&sec).Error; err != nil {
+		utils.RespondWithError(c, http.StatusNotFound, "Security not found")
+		return nil, false
+	}
+	return &sec, true
+}
+
+// memberAccountIDs membatasi query ke akun milik member untuk role member.
+func memberAccountIDs(c *gin.Context, db *gorm.DB) *gorm.DB {
+	mid, restricted := restrictedMember(c)
+	if !restricted {
+		return nil
+	}
+	return db.Model(&models.Account{}).Select("id").Where("member_id = ?", mid)
+}
+
+/* ===========================
+   Securities
+=========================== */
+
+// GetSecurities godoc
+// @Summary List securities
+// @Description Daftar instrumen investasi (reksa dana, saham, kripto, ...) beserta harga terakhirnya
+// @Tags Investments
+// @Produce json
+// @Param kind query string false "mutual_fund, stock, crypto, bond, other"
+// @Success 200 {array} models.Security
+// @Router /investments/securities [get]
+// @Security BearerAuth
+func GetSecurities(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	query := utils.RequestDB(c).Where("user_id = ?", userID)
+	if kind := c.Query("kind"); kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+
+	var securities []models.Security
+	if err := query.Order("symbol").Find(&securities).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch securities")
+		return
+	}
+
+	utils.RespondWithSuccess(c, securities)
+}
+
+// CreateSecurity godoc
+// @Summary Create security
+// @Tags Investments
+// @Accept json
+// @Produce json
+// @Param request body models.SecurityRequest true "Security"
+// @Success 201 {object} models.Security
+// @Router /investments/securities [post]
+// @Security BearerAuth
+func CreateSecurity(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var input struct {
+		Symbol   string `json:"symbol" binding:"required"`
+		Name     string `json:"name"`
+		Kind     string `json:"kind"`
+		Currency string `json:"currency"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	db := utils.RequestDB(c)
+	sec := models.Security{
+		UserID:   userID,
+		Symbol:   input.Symbol,
+		Name:     input.Name,
+		Kind:     input.Kind,
+		Currency: input.Currency,
+	}
+	if err := services.NewInvestmentService(db).ValidateSecurity(&sec); err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+	if err := db.Create(&sec).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to create security")
+		return
+	}
+
+	utils.RespondWithCreated(c, sec)
+}
+
+// UpdateSecurity godoc
+// @Summary Update security
+// @Tags Investments
+// @Accept json
+// @Produce json
+// @Param id path int true "Security ID"
+// @Param request body models.SecurityRequest true "Security"
+// @Success 200 {object} models.Security
+// @Router /investments/securities/{id} [put]
+// @Security BearerAuth
+func UpdateSecurity(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var input struct {
+		Symbol   string  `json:"symbol"`
+		Name     *string `json:"name"`
+		Kind     string  `json:"kind"`
+		Currency string  `json:"currency"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	db := utils.RequestDB(c)
+	sec, ok := findSecurity(c, db, userID)
+	if !ok {
+		return
+	}
+
+	if input.Symbol != "" {
+		sec.Symbol = input.Symbol
+	}
+	if input.Name != nil {
+		sec.Name = *input.Name
+	}
+	if input.Kind != "" {
+		sec.Kind = input.Kind
+	}
+	if input.Currency != "" {
+		sec.Currency = input.Currency
+	}
+
+	if err := services.NewInvestmentService(db).ValidateSecurity(sec); err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+	if err := db.Save(sec).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update security")
+		return
+	}
+
+	utils.RespondWithSuccess(c, sec)
+}
+
+// DeleteSecurity godoc
+// @Summary Delete security
+// @Description Hapus instrumen beserta riwayat harganya. Ditolak (409) kalau masih ada lot atau dividen.
+// @Tags Investments
+// @Produce json
+// @Param id path int true "Security ID"
+// @Success 200 {object} models.DeleteResponse
+// @Router /investments/securities/{id} [delete]
+// @Security BearerAuth
+func DeleteSecurity(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	db := utils.RequestDB(c)
+	sec, ok := findSecurity(c, db, userID)
+	if !ok {
+		return
+	}
+
+	if err := services.NewInvestmentService(db).DeleteSecurity(sec); err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	utils.RespondWithSuccess(c, gin.H{"message": "Security deleted successfully"})
+}
+
+/* ===========================
+   Prices
+=========================== */
+
+// GetSecurityPrices godoc
+// @Summary List security prices
+// @Tags Investments
+// @Produce json
+// @Param id path int true "Security ID"
+// @Param start_date query string false "YYYY-MM-DD"
+// @Param end_date query string false "YYYY-MM-DD"
+// @Success 200 {array} models.SecurityPrice
+// @Router /investments/securities/{id}/prices [get]
+// @Security BearerAuth
+func GetSecurityPrices(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	db := utils.RequestDB(c)
+	sec, ok := findSecurity(c, db, userID)
+	if !ok {
+		return
+	}
+
+	query := db.Where("security_id = ?", sec.ID)
+	if start := c.Query("start_date"); start != "" {
+		query = query.Where("date >= ?", start)
+	}
+	if end := c.Query("end_date"); end != "" {
+		query = query.Where("date <= ?", end)
+	}
+
+	var prices []models.SecurityPrice
+	if err := query.Order("date DESC").Find(&prices).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch prices")
+		return
+	}
+
+	utils.RespondWithSuccess(c, prices)
+}
+
+// CreateSecurityPrice godoc
+// @Summary Record security price
+// @Description Catat harga / NAB pada suatu tanggal. Harga di tanggal yang sama ditimpa.
+// @Tags Investments
+// @Accept json
+// @Produce json
+// @Param id path int true "Security ID"
+// @Param request body models.SecurityPriceRequest true "Price"
+// @Success 201 {object} models.SecurityPrice
+// @Router /investments/securities/{id}/prices [post]
+// @Security BearerAuth
+func CreateSecurityPrice(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var input struct {
+		Date  string   `json:"date"` // default hari ini
+		Price *float64 `json:"price" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if input.Date == "" {
+		input.Date = time.Now().Format("2006-01-02")
+	}
+
+	db := utils.RequestDB(c)
+	sec, ok := findSecurity(c, db, userID)
+	if !ok {
+		return
+	}
+
+	price, err := services.NewInvestmentService(db).AddPrice(sec, input.Date, *input.Price)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	utils.RespondWithCreated(c, price)
+}
+
+// DeleteSecurityPrice godoc
+// @Summary Delete security price
+// @Tags Investments
+// @Produce json
+// @Param id path int true "Security ID"
+// @Param price_id path int true "Price ID"
+// @Success 200 {object} models.DeleteResponse
+// @Router /investments/securities/{id}/prices/{price_id} [delete]
+// @Security BearerAuth
+func DeleteSecurityPrice(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	priceID, err := strconv.Atoi(c.Param("price_id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid price ID")
+		return
+	}
+
+	db := utils.RequestDB(c)
+	sec, ok := findSecurity(c, db, userID)
+	if !ok {
+		return
+	}
+
+	if err := services.NewInvestmentService(db).DeletePrice(sec, uint(priceID)); err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	utils.RespondWithSuccess(c, gin.H{"message": "Price deleted successfully"})
+}
+
+// ImportSecurityPrices godoc
+// @Summary Import price history from CSV
+// @Description Import harga dari CSV dengan kolom symbol,date,price (header opsional, tanggal YYYY-MM-DD, titik sebagai desimal). Kirim lewat multipart (field "file") atau langsung sebagai body text/csv. Baris dengan simbol tidak dikenal atau format salah dilewati dan dilaporkan.
+// @Tags Investments
+// @Accept multipart/form-data
+// @Accept text/csv
+// @Produce json
+// @Param file formData file false "CSV file"
+// @Success 200 {object} services.PriceImportResult
+// @Router /investments/prices/import [post]
+// @Security BearerAuth
+func ImportSecurityPrices(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxPriceCSVSize)
+
+	var reader io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		file, err := c.FormFile("file")
+		if err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, "CSV file is required")
+			return
+		}
+		f, err := file.Open()
+		if err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, "Failed to read CSV file")
+			return
+		}
+		defer f.Close()
+		reader = f
+	}
+
+	result, err := services.NewInvestmentService(utils.RequestDB(c)).ImportPrices(userID, reader)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	utils.RespondWithSuccess(c, result)
+}
+
+/* ===========================
+   Lots
+=========================== */
+
+// GetInvestmentLots godoc
+// @Summary List investment lots
+// @Description Riwayat pembelian dan penjualan di akun investasi
+// @Tags Investments
+// @Produce json
+// @Param account_id query int false "Account ID"
+// @Param security_id query int false "Security ID"
+// @Success 200 {array} models.InvestmentLot
+// @Router /investments/lots [get]
+// @Security BearerAuth
+func GetInvestmentLots(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	db := utils.RequestDB(c)
+	query := db.Preload("Security").Where("user_id = ?", userID)
+	if accountID := c.Query("account_id"); accountID != "" {
+		query = query.Where("account_id = ?", accountID)
+	}
+	if securityID := c.Query("security_id"); securityID != "" {
+		query = query.Where("security_id = ?", securityID)
+	}
+	if own := memberAccountIDs(c, db); own != nil {
+		query = query.Where("account_id IN (?)", own)
+	}
+
+	var lots []models.InvestmentLot
+	if err := query.Order("date DESC, id DESC").Find(&lots).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch lots")
+		return
+	}
+
+	utils.RespondWithSuccess(c, lots)
+}
+
+// CreateInvestmentLot godoc
+// @Summary Record buy or sell
+// @Description Catat pembelian / penjualan. Pembelian mengurangi kas akun sebesar quantity x price + fee (ditolak kalau kas kurang), penjualan menambah quantity x price - fee. Penjualan melebihi unit yang dimiliki ditolak.
+// @Tags Investments
+// @Accept json
+// @Produce json
+// @Param request body models.InvestmentLotRequest true "Lot"
+// @Success 201 {object} models.InvestmentLot
+// @Router /investments/lots [post]
+// @Security BearerAuth
+func CreateInvestmentLot(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var input struct {
+		AccountID  uint    `json:"account_id" binding:"required"`
+		SecurityID uint    `json:"security_id" binding:"required"`
+		Side       string  `json:"side" binding:"required,oneof=buy sell"`
+		Date       string  `json:"date"` // default hari ini
+		Quantity   float64 `json:"quantity" binding:"required"`
+		Price      float64 `json:"price"`
+		Fee        float64 `json:"fee"`
+		Notes      string  `json:"notes"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if input.Date == "" {
+		input.Date = time.Now().Format("2006-01-02")
+	}
+
+	db := utils.RequestDB(c)
+	if own := memberAccountIDs(c, db); own != nil {
+		var cnt int64
+		db.Model(&models.Account{}).Where("id = ? AND id IN (?)", input.AccountID, own).Count(&cnt)
+		if cnt == 0 {
+			utils.RespondWithError(c, http.StatusForbidden, "You can only trade in your own accounts")
+			return
+		}
+	}
+
+	lot := models.InvestmentLot{
+		UserID:     userID,
+		AccountID:  input.AccountID,
+		SecurityID: input.SecurityID,
+		Side:       input.Side,
+		Date:       input.Date,
+		Quantity:   input.Quantity,
+		Price:      input.Price,
+		Fee:        input.Fee,
+		Notes:      input.Notes,
+	}
+	if err := services.NewInvestmentService(db).CreateLot(&lot); err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	db.Preload("Security").First(&lot, lot.ID)
+	utils.RespondWithCreated(c, lot)
+}
+
+// DeleteInvestmentLot godoc
+// @Summary Delete investment lot
+// @Description Hapus lot dan batalkan efek kasnya. Ditolak kalau penjualan sesudahnya jadi melebihi unit yang dimiliki.
+// @Tags Investments
+// @Produce json
+// @Param id path int true "Lot ID"
+// @Success 200 {object} models.DeleteResponse
+// @Router /investments/lots/{id} [delete]
+// @Security BearerAuth
+func DeleteInvestmentLot(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid lot ID")
+		return
+	}
+
+	db := utils.RequestDB(c)
+	query := db.Where("user_id = ? AND id = ?", userID, id)
+	if own := memberAccountIDs(c, db); own != nil {
+		query = query.Where("account_id IN (?)", own)
+	}
+	var lot models.InvestmentLot
+	if err := query.First(&lot).Error; err != nil {
+		utils.RespondWithError(c, http.StatusNotFound, "Lot not found")
+		return
+	}
+
+	if err := services.NewInvestmentService(db).DeleteLot(&lot); err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	utils.RespondWithSuccess(c, gin.H{"message": "Lot deleted successfully"})
+}
+
+/* ===========================
+   Dividends
+=========================== */
+
+// GetInvestmentDividends godoc
+// @Summary List dividends
+// @Tags Investments
+// @Produce json
+// @Param account_id query int false "Account ID"
+// @Param security_id query int false "Security ID"
+// @Param start_date query string false "YYYY-MM-DD"
+// @Param end_date query string false "YYYY-MM-DD"
+// @Success 200 {array} models.InvestmentDividend
+// @Router /investments/dividends [get]
+// @Security BearerAuth
+func GetInvestmentDividends(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	db := utils.RequestDB(c)
+	query := db.Preload("Security").Where("user_id = ?", userID)
+	if accountID := c.Query("account_id"); accountID != "" {
+		query = query.Where("account_id = ?", accountID)
+	}
+	if securityID := c.Query("security_id"); securityID != "" {
+		query = query.Where("security_id = ?", securityID)
+	}
+	if start := c.Query("start_date"); start != "" {
+		query = query.Where("date >= ?", start)
+	}
+	if end := c.Query("end_date"); end != "" {
+		query = query.Where("date <= ?", end)
+	}
+	if own := memberAccountIDs(c, db); own != nil {
+		query = query.Where("account_id IN (?)", own)
+	}
+
+	var dividends []models.InvestmentDividend
+	if err := query.Order("date DESC").Find(&dividends).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch dividends")
+		return
+	}
+
+	utils.RespondWithSuccess(c, dividends)
+}
+
+// CreateInvestmentDividend godoc
+// @Summary Record dividend
+// @Description Catat dividen / kupon sebagai transaksi pemasukan di akun investasi (kategori income wajib) dan tautkan ke instrumennya. Menghapus transaksinya ikut menghapus catatan dividen.
+// @Tags Investments
+// @Accept json
+// @Produce json
+// @Param request body models.InvestmentDividendRequest true "Dividend"
+// @Success 201 {object} models.InvestmentDividend
+// @Router /investments/dividends [post]
+// @Security BearerAuth
+func CreateInvestmentDividend(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var input struct {
+		AccountID   uint    `json:"account_id" binding:"required"`
+		SecurityID  uint    `json:"security_id" binding:"required"`
+		CategoryID  uint    `json:"category_id" binding:"required"`
+		Date        string  `json:"date"`
+		Amount      float64 `json:"amount" binding:"required"`
+		Description string  `json:"description"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	db := utils.RequestDB(c)
+	if own := memberAccountIDs(c, db); own != nil {
+		var cnt int64
+		db.Model(&models.Account{}).Where("id = ? AND id IN (?)", input.AccountID, own).Count(&cnt)
+		if cnt == 0 {
+			utils.RespondWithError(c, http.StatusForbidden, "You can only record dividends in your own accounts")
+			return
+		}
+	}
+
+	dividend, err := services.NewInvestmentService(db).RecordDividend(userID, services.DividendInput{
+		AccountID:   input.AccountID,
+		SecurityID:  input.SecurityID,
+		CategoryID:  input.CategoryID,
+		Date:        input.Date,
+		Amount:      input.Amount,
+		Description: input.Description,
+	})
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	utils.RespondWithCreated(c, dividend)
+}
@@ -0,0 +1,444 @@
+package controllers
+
+import (
+	"errors"
+	"finance-app/models"
+	"finance-app/services"
+	"finance-app/utils"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// findLoan mengambil pinjaman milik ledger; role member hanya boleh pinjaman miliknya.
+func findLoan(c *gin.Context, db *gorm.DB, userID uint) (*models.Loan, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid loan ID")
+		return nil, false
+	}
+
+	query := db.Where("user_id = ? AND id = ?", userID, id)
+	if mid, restricted := restrictedMember(c); restricted {
+		query = query.Where("member_id = ?", mid)
+	}
+
+	var loan models.Loan
+	if err := query.First(&loan).Error; err != nil {
+		utils.RespondWithError(c, http.StatusNotFound, "Loan not found")
+		return nil, false
+	}
+	return &loan, true
+}
+
+func installmentNumber(c *gin.Context) (int, bool) {
+	number, err := strconv.Atoi(c.Param("number"))
+	if err != nil || number <= 0 {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid installment number")
+		return 0, false
+	}
+	return number, true
+}
+
+// GetLoans godoc
+// @Summary List loans
+// @Description Daftar pinjaman / cicilan beserta sisa pokok, jumlah angsuran yang menunggak dan jatuh tempo berikutnya
+// @Tags Loans
+// @Produce json
+// @Param status query string false "active or paid_off"
+// @Param member_id query int false "Member ID"
+// @Success 200 {array} models.Loan
+// @Router /loans [get]
+// @Security BearerAuth
+func GetLoans(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	db := utils.RequestDB(c)
+	query := db.Where("user_id = ?", userID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if memberID := c.Query("member_id"); memberID != "" {
+		query = query.Where("member_id = ?", memberID)
+	}
+	if mid, restricted := restrictedMember(c); restricted {
+		query = query.Where("member_id = ?", mid)
+	}
+
+	var loans []models.Loan
+	if err := query.Order("start_date DESC").Find(&loans).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch loans")
+		return
+	}
+	if err := services.NewLoanService(db).SummarizeAll(loans, time.Now().Format("2006-01-02")); err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch loans")
+		return
+	}
+
+	utils.RespondWithSuccess(c, loans)
+}
+
+// CreateLoan godoc
+// @Summary Create loan
+// @Description Tambah pinjaman / cicilan. Jadwal angsuran dibuat otomatis (annuity atau flat) dan sisa pokoknya dicatat sebagai kewajiban di net worth.
+// @Tags Loans
+// @Accept json
+// @Produce json
+// @Param request body models.LoanRequest true "Loan"
+// @Success 201 {object} models.Loan
+// @Router /loans [post]
+// @Security BearerAuth
+func CreateLoan(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var input struct {
+		MemberID    *uint   `json:"member_id"`
+		AccountID   *uint   `json:"account_id"`
+		CategoryID  *uint   `json:"category_id"`
+		Name        string  `json:"name" binding:"required"`
+		Lender      string  `json:"lender"`
+		Category    string  `json:"category"`
+		Principal   float64 `json:"principal" binding:"required"`
+		AnnualRate  float64 `json:"annual_rate"`
+		TenorMonths int     `json:"tenor_months" binding:"required"`
+		StartDate   string  `json:"start_date" binding:"required"`
+		Method      string  `json:"method"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if mid, restricted := restrictedMember(c); restricted {
+		input.MemberID = &mid
+	}
+
+	loan := models.Loan{
+		UserID:      userID,
+		MemberID:    input.MemberID,
+		AccountID:   input.AccountID,
+		CategoryID:  input.CategoryID,
+		Name:        input.Name,
+		Lender:      input.Lender,
+		Category:    input.Category,
+		Principal:   input.Principal,
+		AnnualRate:  input.AnnualRate,
+		TenorMonths: input.TenorMonths,
+		StartDate:   input.StartDate,
+		Method:      input.Method,
+	}
+
+	db := utils.RequestDB(c)
+	svc := services.NewLoanService(db)
+	if err := svc.Create(&loan); err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	db.First(&loan, loan.ID)
+	loan.Installments, _ = svc.Schedule(&loan, time.Now().Format("2006-01-02"))
+	utils.RespondWithCreated(c, loan)
+}
+
+// GetLoanByID godoc
+// @Summary Get loan
+// @Description Detail pinjaman beserta jadwal angsuran lengkap dan status tiap angsuran (paid, overdue, due, upcoming)
+// @Tags Loans
+// @Produce json
+// @Param id path int true "Loan ID"
+// @Success 200 {object} models.Loan
+// @Router /loans/{id} [get]
+// @Security BearerAuth
+func GetLoanByID(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	db := utils.RequestDB(c)
+	loan, ok := findLoan(c, db, userID)
+	if !ok {
+		return
+	}
+
+	installments, err := services.NewLoanService(db).Schedule(loan, time.Now().Format("2006-01-02"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch schedule")
+		return
+	}
+	loan.Installments = installments
+
+	utils.RespondWithSuccess(c, loan)
+}
+
+// GetLoanSchedule godoc
+// @Summary Get loan amortization schedule
+// @Tags Loans
+// @Produce json
+// @Param id path int true "Loan ID"
+// @Param status query string false "paid, overdue, due or upcoming"
+// @Success 200 {array} models.LoanInstallment
+// @Router /loans/{id}/schedule [get]
+// @Security BearerAuth
+func GetLoanSchedule(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	db := utils.RequestDB(c)
+	loan, ok := findLoan(c, db, userID)
+	if !ok {
+		return
+	}
+
+	installments, err := services.NewLoanService(db).Schedule(loan, time.Now().Format("2006-01-02"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch schedule")
+		return
+	}
+	if status := c.Query("status"); status != "" {
+		filtered := []models.LoanInstallment{}
+		for _, inst := range installments {
+			if inst.Status == status {
+				filtered = append(filtered, inst)
+			}
+		}
+		installments = filtered
+	}
+
+	utils.RespondWithSuccess(c, installments)
+}
+
+// UpdateLoan godoc
+// @Summary Update loan
+// @Description Ubah detail pinjaman. Pokok, bunga, tenor, tanggal mulai dan metode hanya bisa diubah selama belum ada angsuran yang dibayar (409 LOAN_HAS_PAYMENTS); jadwal lalu dibuat ulang.
+// @Tags Loans
+// @Accept json
+// @Produce json
+// @Param id path int true "Loan ID"
+// @Success 200 {object} models.Loan
+// @Router /loans/{id} [put]
+// @Security BearerAuth
+func UpdateLoan(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var input struct {
+		MemberID    *uint    `json:"member_id"`
+		AccountID   *uint    `json:"account_id"`
+		CategoryID  *uint    `json:"category_id"`
+		Name        string   `json:"name"`
+		Lender      *string  `json:"lender"`
+		Category    string   `json:"category"`
+		Principal   *float64 `json:"principal"`
+		AnnualRate  *float64 `json:"annual_rate"`
+		TenorMonths *int     `json:"tenor_months"`
+		StartDate   string   `json:"start_date"`
+		Method      string   `json:"method"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	db := utils.RequestDB(c)
+	loan, ok := findLoan(c, db, userID)
+	if !ok {
+		return
+	}
+
+	if input.MemberID != nil {
+		if _, restricted := restrictedMember(c); !restricted {
+			loan.MemberID = input.MemberID
+		}
+	}
+	if input.AccountID != nil {
+		loan.AccountID = input.AccountID
+	}
+	if input.CategoryID != nil {
+		loan.CategoryID = input.CategoryID
+	}
+	if input.Name != "" {
+		loan.Name = input.Name
+	}
+	if input.Lender != nil {
+		loan.Lender = *input.Lender
+	}
+	if input.Category != "" {
+		loan.Category = input.Category
+	}
+
+	scheduleChanged := false
+	if input.Principal != nil && *input.Principal != loan.Principal {
+		loan.Principal = *input.Principal
+		scheduleChanged = true
+	}
+	if input.AnnualRate != nil && *input.AnnualRate != loan.AnnualRate {
+		loan.AnnualRate = *input.AnnualRate
+		scheduleChanged = true
+	}
+	if input.TenorMonths != nil && *input.TenorMonths != loan.TenorMonths {
+		loan.TenorMonths = *input.TenorMonths
+		scheduleChanged = true
+	}
+	if input.StartDate != "" && input.StartDate != loan.StartDate {
+		loan.StartDate = input.StartDate
+		scheduleChanged = true
+	}
+	if input.Method != "" && input.Method != loan.Method {
+		loan.Method = input.Method
+		scheduleChanged = true
+	}
+
+	svc := services.NewLoanService(db)
+	if err := svc.Update(loan, scheduleChanged); err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	db.First(loan, loan.ID)
+	loan.Installments, _ = svc.Schedule(loan, time.Now().Format("2006-01-02"))
+	utils.RespondWithSuccess(c, loan)
+}
+
+// DeleteLoan godoc
+// @Summary Delete loan
+// @Description Hapus pinjaman beserta jadwal dan kewajibannya di net worth. Transaksi pembayaran cicilan tetap ada.
+// @Tags Loans
+// @Produce json
+// @Param id path int true "Loan ID"
+// @Success 200 {object} models.DeleteResponse
+// @Router /loans/{id} [delete]
+// @Security BearerAuth
+func DeleteLoan(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	db := utils.RequestDB(c)
+	loan, ok := findLoan(c, db, userID)
+	if !ok {
+		return
+	}
+
+	if err := services.NewLoanService(db).Delete(loan); err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to delete loan")
+		return
+	}
+
+	utils.RespondWithSuccess(c, gin.H{"message": "Loan deleted successfully"})
+}
+
+// PayLoanInstallment godoc
+// @Summary Pay loan installment
+// @Description Tandai angsuran sebagai dibayar. Kirim transaction_id (pengeluaran) atau transfer_id untuk menautkan pembayaran yang sudah dicatat; tanpa keduanya, transaksi pengeluaran baru dibuat dari account_id/category_id (default dari pinjaman) dengan amount default sebesar angsuran.
+// @Tags Loans
+// @Accept json
+// @Produce json
+// @Param id path int true "Loan ID"
+// @Param number path int true "Installment number"
+// @Param request body models.LoanPaymentRequest true "Payment"
+// @Success 200 {object} models.LoanInstallment
+// @Router /loans/{id}/installments/{number}/pay [post]
+// @Security BearerAuth
+func PayLoanInstallment(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	number, ok := installmentNumber(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		TransactionID *uint   `json:"transaction_id"`
+		TransferID    *uint   `json:"transfer_id"`
+		AccountID     *uint   `json:"account_id"`
+		CategoryID    *uint   `json:"category_id"`
+		Date          string  `json:"date"`
+		Amount        float64 `json:"amount"`
+		Description   string  `json:"description"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	db := utils.RequestDB(c)
+	loan, ok := findLoan(c, db, userID)
+	if !ok {
+		return
+	}
+
+	inst, err := services.NewLoanService(db).PayInstallment(loan, number, services.PaymentInput{
+		TransactionID: input.TransactionID,
+		TransferID:    input.TransferID,
+		AccountID:     input.AccountID,
+		CategoryID:    input.CategoryID,
+		Date:          input.Date,
+		Amount:        input.Amount,
+		Description:   input.Description,
+	})
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	utils.RespondWithSuccess(c, inst)
+}
+
+// UnpayLoanInstallment godoc
+// @Summary Unlink installment payment
+// @Description Lepas tautan pembayaran angsuran. Transaksi/transfer pembayarannya tidak dihapus.
+// @Tags Loans
+// @Produce json
+// @Param id path int true "Loan ID"
+// @Param number path int true "Installment number"
+// @Success 200 {object} models.DeleteResponse
+// @Router /loans/{id}/installments/{number}/payment [delete]
+// @Security BearerAuth
+func UnpayLoanInstallment(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	number, ok := installmentNumber(c)
+	if !ok {
+		return
+	}
+
+	db := utils.RequestDB(c)
+	loan, ok := findLoan(c, db, userID)
+	if !ok {
+		return
+	}
+
+	if err := services.NewLoanService(db).UnpayInstallment(loan, number); err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	utils.RespondWithSuccess(c, gin.H{"message": "Installment payment unlinked"})
+}
@@ -1,8 +1,8 @@
 package controllers
 
 import (
-	"finance-app/database"
 	"finance-app/models"
+	"finance-app/services"
 	"finance-app/utils"
 	"net/http"
 	"strconv"
@@ -11,15 +11,20 @@ import (
 )
 
 func GetMembers(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
-	db := database.GetDB()
+	db := utils.RequestDB(c)
+	query := db.Where("user_id = ?", userID)
+	if mid, restricted := restrictedMember(c); restricted {
+		query = query.Where("id = ?", mid)
+	}
+
 	var members []models.Member
-	if err := db.Where("user_id = ?", userID).Find(&members).Error; err != nil {
+	if err := query.Find(&members).Error; err != nil {
 		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch members")
 		return
 	}
@@ -28,27 +33,34 @@ func GetMembers(c *gin.Context) {
 }
 
 func CreateMember(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
 	var input struct {
-		Name string `json:"name" binding:"required"`
+		Name              string  `json:"name" binding:"required"`
+		SpendingCap       float64 `json:"spending_cap" binding:"gte=0"`
+		SpendingCapPeriod string  `json:"spending_cap_period" binding:"omitempty,oneof=weekly monthly"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
 		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	if input.SpendingCapPeriod == "" {
+		input.SpendingCapPeriod = "monthly"
+	}
 
 	member := models.Member{
-		UserID: userID,
-		Name:   input.Name,
+		UserID:            userID,
+		Name:              input.Name,
+		SpendingCap:       input.SpendingCap,
+		SpendingCapPeriod: input.SpendingCapPeriod,
 	}
 
-	db := database.GetDB()
+	db := utils.RequestDB(c)
 	if err := db.Create(&member).Error; err != nil {
 		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to create member")
 		return
@@ -58,7 +70,7 @@ func CreateMember(c *gin.Context) {
 }
 
 func GetMemberByID(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -70,7 +82,12 @@ func GetMemberByID(c *gin.Context) {
 		return
 	}
 
-	db := database.GetDB()
+	if mid, restricted := restrictedMember(c); restricted && uint(id) != mid {
+		utils.RespondWithError(c, http.StatusNotFound, "Member not found")
+		return
+	}
+
+	db := utils.RequestDB(c)
 	var member models.Member
 	if err := db.Where("user_id = ? AND id = ?", userID, id).First(&member).Error; err != nil {
 		utils.RespondWithError(c, http.StatusNotFound, "Member not found")
@@ -81,7 +98,7 @@ func GetMemberByID(c *gin.Context) {
 }
 
 func UpdateMember(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -94,7 +111,9 @@ func UpdateMember(c *gin.Context) {
 	}
 
 	var input struct {
-		Name string `json:"name" binding:"required"`
+		Name              string   `json:"name" binding:"required"`
+		SpendingCap       *float64 `json:"spending_cap" binding:"omitempty,gte=0"`
+		SpendingCapPeriod string   `json:"spending_cap_period" binding:"omitempty,oneof=weekly monthly"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -102,7 +121,7 @@ func UpdateMember(c *gin.Context) {
 		return
 	}
 
-	db := database.GetDB()
+	db := utils.RequestDB(c)
 	var member models.Member
 	if err := db.Where("user_id = ? AND id = ?", userID, id).First(&member).Error; err != nil {
 		utils.RespondWithError(c, http.StatusNotFound, "Member not found")
@@ -110,6 +129,12 @@ func UpdateMember(c *gin.Context) {
 	}
 
 	member.Name = input.Name
+	if input.SpendingCap != nil {
+		member.SpendingCap = *input.SpendingCap
+	}
+	if input.SpendingCapPeriod != "" {
+		member.SpendingCapPeriod = input.SpendingCapPeriod
+	}
 	if err := db.Save(&member).Error; err != nil {
 		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update member")
 		return
@@ -119,7 +144,7 @@ func UpdateMember(c *gin.Context) {
 }
 
 func DeleteMember(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -131,9 +156,15 @@ func DeleteMember(c *gin.Context) {
 		return
 	}
 
-	db := database.GetDB()
-	if err := db.Where("user_id = ? AND id = ?", userID, id).Delete(&models.Member{}).Error; err != nil {
-		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to delete member")
+	var opts services.DeleteOptions
+	if err := c.ShouldBindQuery(&opts); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+
+	service := services.NewIntegrityService(utils.RequestDB(c))
+	if err := service.DeleteMember(userID, uint(id), opts); err != nil {
+		respondWithServiceError(c, err)
 		return
 	}
 
@@ -1,7 +1,6 @@
 package controllers
 
 import (
-	"finance-app/database"
 	"finance-app/models"
 	"finance-app/utils"
 	"net/http"
@@ -11,7 +10,7 @@ import (
 )
 
 func GetRecurringTransactions(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -19,12 +18,15 @@ func GetRecurringTransactions(c *gin.Context) {
 
 	isActive := c.Query("is_active")
 
-	db := database.GetDB()
+	db := utils.RequestDB(c)
 	query := db.Where("user_id = ?", userID)
 
 	if isActive != "" {
 		query = query.Where("is_active = ?", isActive == "true")
 	}
+	if mid, restricted := restrictedMember(c); restricted {
+		query = query.Where("member_id = ?", mid)
+	}
 
 	var recurringTransactions []models.RecurringTransaction
 	if err := query.Find(&recurringTransactions).Error; err != nil {
@@ -36,7 +38,7 @@ func GetRecurringTransactions(c *gin.Context) {
 }
 
 func CreateRecurringTransaction(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -61,7 +63,7 @@ func CreateRecurringTransaction(c *gin.Context) {
 	}
 
 	// Verify member belongs to user
-	db := database.GetDB()
+	db := utils.RequestDB(c)
 	var member models.Member
 	if err := db.Where("user_id = ? AND id = ?", userID, input.MemberID).First(&member).Error; err != nil {
 		utils.RespondWithError(c, http.StatusNotFound, "Member not found")
@@ -82,6 +84,11 @@ func CreateRecurringTransaction(c *gin.Context) {
 		return
 	}
 
+	if account.ArchivedAt != nil || category.ArchivedAt != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Account or category is archived")
+		return
+	}
+
 	recurringTransaction := models.RecurringTransaction{
 		UserID:      userID,
 		MemberID:    input.MemberID,
@@ -105,7 +112,7 @@ func CreateRecurringTransaction(c *gin.Context) {
 }
 
 func GetRecurringTransactionByID(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -117,18 +124,22 @@ func GetRecurringTransactionByID(c *gin.Context) {
 		return
 	}
 
-	db := database.GetDB()
+	db := utils.RequestDB(c)
 	var recurringTransaction models.RecurringTransaction
 	if err := db.Where("user_id = ? AND id = ?", userID, id).First(&recurringTransaction).Error; err != nil {
 		utils.RespondWithError(c, http.StatusNotFound, "Recurring transaction not found")
 		return
 	}
+	if mid, restricted := restrictedMember(c); restricted && recurringTransaction.MemberID != mid {
+		utils.RespondWithError(c, http.StatusNotFound, "Recurring transaction not found")
+		return
+	}
 
 	utils.RespondWithSuccess(c, recurringTransaction)
 }
 
 func UpdateRecurringTransaction(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -155,7 +166,7 @@ func UpdateRecurringTransaction(c *gin.Context) {
 		return
 	}
 
-	db := database.GetDB()
+	db := utils.RequestDB(c)
 	var recurringTransaction models.RecurringTransaction
 	if err := db.Where("user_id = ? AND id = ?", userID, id).First(&recurringTransaction).Error; err != nil {
 		utils.RespondWithError(c, http.StatusNotFound, "Recurring transaction not found")
@@ -170,6 +181,10 @@ func UpdateRecurringTransaction(c *gin.Context) {
 			utils.RespondWithError(c, http.StatusNotFound, "Account not found")
 			return
 		}
+		if account.ArchivedAt != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, "Account is archived")
+			return
+		}
 		recurringTransaction.AccountID = input.AccountID
 	}
 	if input.CategoryID != 0 {
@@ -204,7 +219,7 @@ func UpdateRecurringTransaction(c *gin.Context) {
 }
 
 func DeleteRecurringTransaction(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -216,7 +231,7 @@ func DeleteRecurringTransaction(c *gin.Context) {
 		return
 	}
 
-	db := database.GetDB()
+	db := utils.RequestDB(c)
 	if err := db.Where("user_id = ? AND id = ?", userID, id).Delete(&models.RecurringTransaction{}).Error; err != nil {
 		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to delete recurring transaction")
 		return
@@ -8,8 +8,8 @@ import (
 	"strconv"
 	"time"
 
-	"finance-app/database"
 	"finance-app/models"
+	"finance-app/services"
 	"finance-app/utils"
 
 	"github.com/gin-gonic/gin"
@@ -34,11 +34,26 @@ func formatAmount(val interface{}) string {
 	return p.Sprintf("%.2f", f) // contoh: 5.000.000,00
 }
 
+// ===============================
+// Helper: Rollup nama sub-kategori ke kategori utama
+// ===============================
+func rollupCategoryNames(tree *services.CategoryTree, rows []map[string]interface{}, nameKey string) {
+	for _, r := range rows {
+		id, err := strconv.ParseUint(fmt.Sprintf("%v", r["category_id"]), 10, 64)
+		if err != nil {
+			continue
+		}
+		if root, ok := tree.Get(tree.RootOf(uint(id))); ok {
+			r[nameKey] = root.Name
+		}
+	}
+}
+
 // ===============================
 // 1. Laporan Transaksi (Detail + Filter)
 // ===============================
 func GetReportTransactions(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -49,8 +64,17 @@ func GetReportTransactions(c *gin.Context) {
 	categoryID := c.Query("category_id")
 	accountID := c.Query("account_id")
 	tType := c.Query("type")
+	rollup := c.Query("rollup") == "true"
+
+	db := utils.RequestDB(c)
+	var tree *services.CategoryTree
+	if rollup {
+		if tree, err = services.LoadCategoryTree(db, userID); err != nil {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch categories")
+			return
+		}
+	}
 
-	db := database.GetDB()
 	query := db.Table("transactions").
 		Select("transactions.*, categories.name as category_name, accounts.name as account_name").
 		Joins("JOIN members ON members.id = transactions.member_id").
@@ -63,7 +87,12 @@ func GetReportTransactions(c *gin.Context) {
 		query = query.Where("transactions.date BETWEEN ? AND ?", startDate, endDate)
 	}
 	if categoryID != "" {
-		query = query.Where("transactions.category_id = ?", categoryID)
+		// dengan rollup, filter parent ikut mencakup sub-kategorinya
+		if id, err := strconv.Atoi(categoryID); err == nil && rollup {
+			query = query.Where("transactions.category_id IN ?", tree.DescendantIDs(uint(id)))
+		} else {
+			query = query.Where("transactions.category_id = ?", categoryID)
+		}
 	}
 	if accountID != "" {
 		query = query.Where("transactions.account_id = ?", accountID)
@@ -77,6 +106,9 @@ func GetReportTransactions(c *gin.Context) {
 		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch transactions report")
 		return
 	}
+	if rollup {
+		rollupCategoryNames(tree, results, "category_name")
+	}
 
 	utils.RespondWithSuccess(c, results)
 }
@@ -85,7 +117,7 @@ func GetReportTransactions(c *gin.Context) {
 // 2. Summary Income vs Expense
 // ===============================
 func GetReportSummary(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -98,7 +130,7 @@ func GetReportSummary(c *gin.Context) {
 	startDate, _ := time.Parse("2006-01-02", month+"-01")
 	endDate := startDate.AddDate(0, 1, -1)
 
-	db := database.GetDB()
+	db := utils.RequestDB(c)
 	var income float64
 	var expense float64
 
@@ -124,7 +156,7 @@ func GetReportSummary(c *gin.Context) {
 // 3. Budget vs Realisasi
 // ===============================
 func GetBudgetReport(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -158,17 +190,15 @@ func GetBudgetReport(c *gin.Context) {
 		endDate = startDate.AddDate(0, 1, -1)
 	}
 
-	db := database.GetDB()
+	db := utils.RequestDB(c)
 	query := db.Table("budget_categories").
 		Select(`budget_categories.id as budget_id,
                 budget_categories.category_id,
                 categories.name as category_name,
-                budget_categories.amount as budget_amount,
-                COALESCE(SUM(transactions.amount), 0) as actual_amount`).
+                budget_categories.amount as budget_amount`).
 		Joins("JOIN categories ON categories.id = budget_categories.category_id").
-		Joins("LEFT JOIN transactions ON transactions.category_id = budget_categories.category_id AND transactions.type = 'expense' AND transactions.date BETWEEN ? AND ? AND transactions.deleted_at IS NULL", startDate, endDate).
 		Where("budget_categories.user_id = ? AND budget_categories.period = ?", userID, period).
-		Group("budget_categories.id, budget_categories.category_id, categories.name, budget_categories.amount")
+		Where("budget_categories.deleted_at IS NULL")
 
 	var reports []struct {
 		BudgetID     uint
@@ -183,6 +213,35 @@ func GetBudgetReport(c *gin.Context) {
 		return
 	}
 
+	// Realisasi per kategori, lalu budget di parent ikut menghitung sub-kategorinya
+	var spent []struct {
+		CategoryID uint
+		Total      float64
+	}
+	if err := db.Table("transactions").
+		Select("category_id, COALESCE(SUM(amount),0) as total").
+		Where("user_id = ? AND type = 'expense' AND date BETWEEN ? AND ? AND deleted_at IS NULL", userID, startDate, endDate).
+		Group("category_id").
+		Scan(&spent).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch budget report")
+		return
+	}
+	spentByCategory := map[uint]float64{}
+	for _, sp := range spent {
+		spentByCategory[sp.CategoryID] = sp.Total
+	}
+
+	tree, err := services.LoadCategoryTree(db, userID)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch categories")
+		return
+	}
+	for i := range reports {
+		for _, id := range tree.DescendantIDs(reports[i].CategoryID) {
+			reports[i].ActualAmount += spentByCategory[id]
+		}
+	}
+
 	type Result struct {
 		BudgetID     uint    `json:"budget_id"`
 		CategoryID   uint    `json:"category_id"`
@@ -220,31 +279,47 @@ func GetBudgetReport(c *gin.Context) {
 // 4. Saving Target
 // ===============================
 func GetSavingReport(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
-	db := database.GetDB()
+	db := utils.RequestDB(c)
 	var savings []models.SavingTarget
 	if err := db.Where("user_id = ?", userID).Find(&savings).Error; err != nil {
 		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch saving targets")
 		return
 	}
 
+	// Ambil semua ledger kontribusi sekaligus untuk forecast per target
+	var contributions []models.SavingContribution
+	if err := db.Where("user_id = ?", userID).Find(&contributions).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch saving contributions")
+		return
+	}
+	byTarget := map[uint][]models.SavingContribution{}
+	for _, ct := range contributions {
+		byTarget[ct.SavingTargetID] = append(byTarget[ct.SavingTargetID], ct)
+	}
+
 	type Result struct {
-		ID          uint    `json:"id"`
-		Name        string  `json:"name"`
-		Target      float64 `json:"target"`
-		Current     float64 `json:"current"`
-		ProgressPct float64 `json:"progress_pct"`
-		Status      string  `json:"status"`
+		ID          uint                    `json:"id"`
+		Name        string                  `json:"name"`
+		Target      float64                 `json:"target"`
+		Current     float64                 `json:"current"`
+		ProgressPct float64                 `json:"progress_pct"`
+		Status      string                  `json:"status"`
+		Forecast    services.SavingForecast `json:"forecast"`
 	}
 
+	now := time.Now()
 	var results []Result
 	for _, s := range savings {
-		progress := (s.CurrentAmount / s.TargetAmount) * 100
+		progress := 0.0
+		if s.TargetAmount > 0 {
+			progress = (s.CurrentAmount / s.TargetAmount) * 100
+		}
 		status := "in progress"
 		if s.CurrentAmount >= s.TargetAmount {
 			status = "achieved"
@@ -256,6 +331,7 @@ func GetSavingReport(c *gin.Context) {
 			Current:     s.CurrentAmount,
 			ProgressPct: progress,
 			Status:      status,
+			Forecast:    services.ForecastSavingTarget(s, byTarget[s.ID], now),
 		})
 	}
 
@@ -266,7 +342,7 @@ func GetSavingReport(c *gin.Context) {
 // Export CSV
 // ===============================
 func ExportTransactionsCSV(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -275,9 +351,9 @@ func ExportTransactionsCSV(c *gin.Context) {
 	startDate := c.Query("start_date")
 	endDate := c.Query("end_date")
 
-	db := database.GetDB()
+	db := utils.RequestDB(c)
 	query := db.Table("transactions").
-		Select("transactions.id, transactions.date, transactions.type, transactions.amount, categories.name as category, accounts.name as account").
+		Select("transactions.id, transactions.date, transactions.type, transactions.amount, transactions.category_id, categories.name as category, accounts.name as account").
 		Joins("JOIN members ON members.id = transactions.member_id").
 		Joins("JOIN categories ON categories.id = transactions.category_id").
 		Joins("JOIN accounts ON accounts.id = transactions.account_id").
@@ -293,6 +369,14 @@ func ExportTransactionsCSV(c *gin.Context) {
 		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch data")
 		return
 	}
+	if c.Query("rollup") == "true" {
+		tree, err := services.LoadCategoryTree(db, userID)
+		if err != nil {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch categories")
+			return
+		}
+		rollupCategoryNames(tree, results, "category")
+	}
 
 	var b bytes.Buffer
 	writer := csv.NewWriter(&b)
@@ -319,7 +403,7 @@ func ExportTransactionsCSV(c *gin.Context) {
 // Export PDF
 // ===============================
 func ExportTransactionsPDF(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -328,9 +412,9 @@ func ExportTransactionsPDF(c *gin.Context) {
 	startDate := c.Query("start_date")
 	endDate := c.Query("end_date")
 
-	db := database.GetDB()
+	db := utils.RequestDB(c)
 	query := db.Table("transactions").
-		Select("transactions.id, transactions.date, transactions.type, transactions.amount, categories.name as category, accounts.name as account").
+		Select("transactions.id, transactions.date, transactions.type, transactions.amount, transactions.category_id, categories.name as category, accounts.name as account").
 		Joins("JOIN members ON members.id = transactions.member_id").
 		Joins("JOIN categories ON categories.id = transactions.category_id").
 		Joins("JOIN accounts ON accounts.id = transactions.account_id").
@@ -346,6 +430,14 @@ func ExportTransactionsPDF(c *gin.Context) {
 		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch data")
 		return
 	}
+	if c.Query("rollup") == "true" {
+		tree, err := services.LoadCategoryTree(db, userID)
+		if err != nil {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch categories")
+			return
+		}
+		rollupCategoryNames(tree, results, "category")
+	}
 
 	pdf := gofpdf.New("P", "mm", "A4", "")
 	pdf.AddPage()
@@ -389,13 +481,13 @@ func ExportTransactionsPDF(c *gin.Context) {
 // 5. Laporan Perbandingan Antar Member
 // ===============================
 func GetMemberComparisonChart(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
-	db := database.GetDB()
+	db := utils.RequestDB(c)
 	var members []models.Member
 	if err := db.Where("user_id = ?", userID).Find(&members).Error; err != nil {
 		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch members")
@@ -450,7 +542,7 @@ func GetMemberComparisonChart(c *gin.Context) {
 // 5. Laporan Perbandingan Antar Member
 // ===============================
 func GetMembersComparisonReport(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -459,7 +551,7 @@ func GetMembersComparisonReport(c *gin.Context) {
 	startDate := c.Query("start_date")
 	endDate := c.Query("end_date")
 
-	db := database.GetDB()
+	db := utils.RequestDB(c)
 	query := db.Table("members").
 		Select(`
             members.id as member_id,
@@ -515,3 +607,125 @@ func GetMembersComparisonReport(c *gin.Context) {
 		"members":    finalResults,
 	})
 }
+
+// ===============================
+// 6. Net Worth (Kekayaan Bersih)
+// ===============================
+
+// GetNetWorthReport godoc
+// @Summary Net worth over time
+// @Description Deret waktu kekayaan bersih dari snapshot saldo harian, dipecah per tipe akun dan member. Nilai tiap periode diambil dari snapshot terakhir di periode itu.
+// @Tags Reports
+// @Produce json
+// @Param from query string false "YYYY-MM-DD (default 12 bulan lalu)"
+// @Param to query string false "YYYY-MM-DD (default hari ini)"
+// @Param interval query string false "day, week, month (default month)"
+// @Success 200 {object} services.NetWorthSeries
+// @Router /reports/net-worth [get]
+// @Security BearerAuth
+func GetNetWorthReport(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var q services.NetWorthQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+	if mid, restricted := restrictedMember(c); restricted {
+		q.MemberID = &mid
+	}
+
+	series, err := services.NewNetWorthService(utils.RequestDB(c)).Series(userID, q)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	utils.RespondWithSuccess(c, series)
+}
+
+// ===============================
+// 7. Portfolio (Investasi)
+// ===============================
+
+// GetPortfolioReport godoc
+// @Summary Investment portfolio
+// @Description Posisi per akun investasi dan instrumen: unit, harga perolehan (FIFO atau rata-rata), nilai pasar dari harga terakhir <= as_of, unrealized gain, serta realized gain dan dividen dalam periode from..as_of. Kas akun memakai saldo saat ini.
+// @Tags Reports
+// @Produce json
+// @Param as_of query string false "YYYY-MM-DD (default hari ini)"
+// @Param from query string false "Awal periode realized gain & dividen (default sejak awal)"
+// @Param method query string false "fifo (default) or average"
+// @Param account_id query int false "Account ID"
+// @Success 200 {object} services.Portfolio
+// @Router /reports/portfolio [get]
+// @Security BearerAuth
+func GetPortfolioReport(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var q services.PortfolioQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+	if mid, restricted := restrictedMember(c); restricted {
+		q.MemberID = &mid
+	}
+
+	portfolio, err := services.NewInvestmentService(utils.RequestDB(c)).Portfolio(userID, q)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	utils.RespondWithSuccess(c, portfolio)
+}
+
+// ===============================
+// 8. Forecast (Proyeksi Saldo)
+// ===============================
+
+// GetForecastReport godoc
+// @Summary Cash-flow forecast
+// @Description Proyeksi saldo harian tiap akun mulai besok sampai days hari ke depan dari saldo saat ini, aturan transaksi berulang aktif, angsuran pinjaman yang belum dibayar (yang lewat jatuh tempo dihitung di hari pertama) dan uang saku terjadwal. Dengan include_discretionary, rata-rata pengeluaran harian di luar kategori berulang dan angsuran selama history_days terakhir ikut dikurangkan. Akun yang saldonya diproyeksikan negatif muncul di alerts.
+// @Tags Reports
+// @Produce json
+// @Param days query int false "Jumlah hari proyeksi (default 90, maks 365)"
+// @Param account_id query int false "Account ID"
+// @Param include_discretionary query bool false "Sertakan rata-rata pengeluaran tak terjadwal"
+// @Param history_days query int false "Periode riwayat untuk rata-rata (default 90)"
+// @Success 200 {object} services.Forecast
+// @Router /reports/forecast [get]
+// @Security BearerAuth
+func GetForecastReport(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var q services.ForecastQuery
+	if err := c.ShouldBindQuery(&q); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+	if mid, restricted := restrictedMember(c); restricted {
+		q.MemberID = &mid
+	}
+
+	forecast, err := services.NewForecastService(utils.RequestDB(c)).Project(userID, q)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	utils.RespondWithSuccess(c, forecast)
+}
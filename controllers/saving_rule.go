@@ -0,0 +1,222 @@
+package controllers
+
+import (
+	"finance-app/models"
+	"finance-app/services"
+	"finance-app/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func validateSavingRule(db *gorm.DB, userID uint, rule *models.SavingRule) string {
+	switch rule.Type {
+	case models.SavingRuleRoundUp:
+		if rule.RoundTo <= 0 {
+			return "round_to must be greater than zero"
+		}
+	case models.SavingRulePercentage:
+		if rule.Percentage <= 0 || rule.Percentage > 100 {
+			return "percentage must be between 0 and 100"
+		}
+	default:
+		return "Invalid rule type, allowed: round_up, percentage"
+	}
+
+	if rule.TransactionType != "income" && rule.TransactionType != "expense" {
+		return "transaction_type must be income or expense"
+	}
+
+	if rule.CategoryID != nil {
+		var category models.Category
+		if err := db.Where("user_id = ? AND id = ?", userID, *rule.CategoryID).First(&category).Error; err != nil {
+			return "Category not found"
+		}
+		if category.Type != rule.TransactionType {
+			return "Category type does not match transaction_type"
+		}
+	}
+	return ""
+}
+
+func GetSavingRules(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	target, err := services.NewSavingService(utils.RequestDB(c)).FindTarget(userID, c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusNotFound, "Saving target not found")
+		return
+	}
+	if mid, restricted := restrictedMember(c); restricted && target.MemberID != mid {
+		utils.RespondWithError(c, http.StatusNotFound, "Saving target not found")
+		return
+	}
+
+	db := utils.RequestDB(c)
+	var rules []models.SavingRule
+	if err := db.Where("user_id = ? AND saving_target_id = ?", userID, target.ID).Find(&rules).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch saving rules")
+		return
+	}
+
+	utils.RespondWithSuccess(c, rules)
+}
+
+func CreateSavingRule(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var input struct {
+		Type            string  `json:"type" binding:"required,oneof=round_up percentage"`
+		TransactionType string  `json:"transaction_type" binding:"required,oneof=income expense"`
+		CategoryID      *uint   `json:"category_id"`
+		RoundTo         float64 `json:"round_to"`
+		Percentage      float64 `json:"percentage"`
+		IsActive        *bool   `json:"is_active"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	db := utils.RequestDB(c)
+	target, err := services.NewSavingService(db).FindTarget(userID, c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusNotFound, "Saving target not found")
+		return
+	}
+
+	rule := models.SavingRule{
+		UserID:          userID,
+		SavingTargetID:  target.ID,
+		Type:            input.Type,
+		TransactionType: input.TransactionType,
+		CategoryID:      input.CategoryID,
+		RoundTo:         input.RoundTo,
+		Percentage:      input.Percentage,
+		IsActive:        true,
+	}
+	if input.IsActive != nil {
+		rule.IsActive = *input.IsActive
+	}
+
+	if msg := validateSavingRule(db, userID, &rule); msg != "" {
+		utils.RespondWithError(c, http.StatusBadRequest, msg)
+		return
+	}
+
+	if err := db.Create(&rule).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to create saving rule")
+		return
+	}
+
+	utils.RespondWithCreated(c, rule)
+}
+
+func UpdateSavingRule(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	ruleID, err := strconv.Atoi(c.Param("rule_id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid saving rule ID")
+		return
+	}
+
+	var input struct {
+		Type            string   `json:"type" binding:"omitempty,oneof=round_up percentage"`
+		TransactionType string   `json:"transaction_type" binding:"omitempty,oneof=income expense"`
+		CategoryID      *uint    `json:"category_id"`
+		RoundTo         *float64 `json:"round_to"`
+		Percentage      *float64 `json:"percentage"`
+		IsActive        *bool    `json:"is_active"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	db := utils.RequestDB(c)
+	var rule models.SavingRule
+	if err := db.Where("user_id = ? AND saving_target_id = ? AND id = ?", userID, c.Param("id"), ruleID).
+		First(&rule).Error; err != nil {
+		utils.RespondWithError(c, http.StatusNotFound, "Saving rule not found")
+		return
+	}
+
+	if input.Type != "" {
+		rule.Type = input.Type
+	}
+	if input.TransactionType != "" {
+		rule.TransactionType = input.TransactionType
+	}
+	if input.CategoryID != nil {
+		// category_id = 0 menghapus filter kategori
+		if *input.CategoryID == 0 {
+			rule.CategoryID = nil
+		} else {
+			rule.CategoryID = input.CategoryID
+		}
+	}
+	if input.RoundTo != nil {
+		rule.RoundTo = *input.RoundTo
+	}
+	if input.Percentage != nil {
+		rule.Percentage = *input.Percentage
+	}
+	if input.IsActive != nil {
+		rule.IsActive = *input.IsActive
+	}
+
+	if msg := validateSavingRule(db, userID, &rule); msg != "" {
+		utils.RespondWithError(c, http.StatusBadRequest, msg)
+		return
+	}
+
+	if err := db.Save(&rule).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update saving rule")
+		return
+	}
+
+	utils.RespondWithSuccess(c, rule)
+}
+
+func DeleteSavingRule(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	ruleID, err := strconv.Atoi(c.Param("rule_id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid saving rule ID")
+		return
+	}
+
+	db := utils.RequestDB(c)
+	result := db.Where("user_id = ? AND saving_target_id = ? AND id = ?", userID, c.Param("id"), ruleID).
+		Delete(&models.SavingRule{})
+	if result.Error != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to delete saving rule")
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.RespondWithError(c, http.StatusNotFound, "Saving rule not found")
+		return
+	}
+
+	utils.RespondWithSuccess(c, gin.H{"message": "Saving rule deleted successfully"})
+}
@@ -1,17 +1,20 @@
 package controllers
 
 import (
-	"finance-app/database"
+	"errors"
 	"finance-app/models"
+	"finance-app/services"
 	"finance-app/utils"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 func GetSavingTargets(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -21,7 +24,7 @@ func GetSavingTargets(c *gin.Context) {
 	accountID := c.Query("account_id")
 	isCompleted := c.Query("is_completed")
 
-	db := database.GetDB()
+	db := utils.RequestDB(c)
 	query := db.Joins("JOIN members ON members.id = saving_targets.member_id").
 		Where("members.user_id = ?", userID)
 
@@ -31,6 +34,9 @@ func GetSavingTargets(c *gin.Context) {
 	if accountID != "" {
 		query = query.Where("saving_targets.account_id = ?", accountID)
 	}
+	if mid, restricted := restrictedMember(c); restricted {
+		query = query.Where("saving_targets.member_id = ?", mid)
+	}
 	if isCompleted != "" {
 		if isCompleted == "true" {
 			query = query.Where("saving_targets.current_amount >= saving_targets.target_amount")
@@ -49,7 +55,7 @@ func GetSavingTargets(c *gin.Context) {
 }
 
 func CreateSavingTarget(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -69,8 +75,13 @@ func CreateSavingTarget(c *gin.Context) {
 		return
 	}
 
+	if _, err := time.Parse("2006-01-02", input.TargetDate); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid target_date format, use YYYY-MM-DD")
+		return
+	}
+
 	// Verify member belongs to user
-	db := database.GetDB()
+	db := utils.RequestDB(c)
 	var member models.Member
 	if err := db.Where("user_id = ? AND id = ?", userID, input.MemberID).First(&member).Error; err != nil {
 		utils.RespondWithError(c, http.StatusNotFound, "Member not found")
@@ -104,7 +115,7 @@ func CreateSavingTarget(c *gin.Context) {
 }
 
 func GetSavingTargetByID(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -116,7 +127,7 @@ func GetSavingTargetByID(c *gin.Context) {
 		return
 	}
 
-	db := database.GetDB()
+	db := utils.RequestDB(c)
 	var savingTarget models.SavingTarget
 	if err := db.Joins("JOIN members ON members.id = saving_targets.member_id").
 		Where("members.user_id = ? AND saving_targets.id = ?", userID, id).
@@ -124,12 +135,16 @@ func GetSavingTargetByID(c *gin.Context) {
 		utils.RespondWithError(c, http.StatusNotFound, "Saving target not found")
 		return
 	}
+	if mid, restricted := restrictedMember(c); restricted && savingTarget.MemberID != mid {
+		utils.RespondWithError(c, http.StatusNotFound, "Saving target not found")
+		return
+	}
 
 	utils.RespondWithSuccess(c, savingTarget)
 }
 
 func UpdateSavingTarget(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -142,11 +157,10 @@ func UpdateSavingTarget(c *gin.Context) {
 	}
 
 	var input struct {
-		Name          string  `json:"name"`
-		TargetAmount  float64 `json:"target_amount"`
-		CurrentAmount float64 `json:"current_amount"`
-		TargetDate    string  `json:"target_date"`
-		Description   string  `json:"description"`
+		Name         string  `json:"name"`
+		TargetAmount float64 `json:"target_amount"`
+		TargetDate   string  `json:"target_date"`
+		Description  string  `json:"description"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -154,7 +168,7 @@ func UpdateSavingTarget(c *gin.Context) {
 		return
 	}
 
-	db := database.GetDB()
+	db := utils.RequestDB(c)
 	var savingTarget models.SavingTarget
 	if err := db.Joins("JOIN members ON members.id = saving_targets.member_id").
 		Where("members.user_id = ? AND saving_targets.id = ?", userID, id).
@@ -170,10 +184,11 @@ func UpdateSavingTarget(c *gin.Context) {
 	if input.TargetAmount != 0 {
 		savingTarget.TargetAmount = input.TargetAmount
 	}
-	if input.CurrentAmount != 0 {
-		savingTarget.CurrentAmount = input.CurrentAmount
-	}
 	if input.TargetDate != "" {
+		if _, err := time.Parse("2006-01-02", input.TargetDate); err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, "Invalid target_date format, use YYYY-MM-DD")
+			return
+		}
 		savingTarget.TargetDate = input.TargetDate
 	}
 	if input.Description != "" {
@@ -189,7 +204,7 @@ func UpdateSavingTarget(c *gin.Context) {
 }
 
 func DeleteSavingTarget(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -201,13 +216,116 @@ func DeleteSavingTarget(c *gin.Context) {
 		return
 	}
 
-	db := database.GetDB()
-	if err := db.Joins("JOIN members ON members.id = saving_targets.member_id").
-		Where("members.user_id = ? AND saving_targets.id = ?", userID, id).
-		Delete(&models.SavingTarget{}).Error; err != nil {
-		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to delete saving target")
+	var opts services.DeleteOptions
+	if err := c.ShouldBindQuery(&opts); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+
+	service := services.NewIntegrityService(utils.RequestDB(c))
+	if err := service.DeleteSavingTarget(userID, uint(id), opts); err != nil {
+		respondWithServiceError(c, err)
 		return
 	}
 
 	utils.RespondWithSuccess(c, gin.H{"message": "Saving target deleted successfully"})
 }
+
+func GetSavingContributions(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	service := services.NewSavingService(utils.RequestDB(c))
+	if !savingTargetAllowed(c, service, userID) {
+		return
+	}
+	contributions, err := service.GetContributions(userID, c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusNotFound, "Saving target not found")
+		return
+	}
+
+	utils.RespondWithSuccess(c, contributions)
+}
+
+func CreateSavingContribution(c *gin.Context) {
+	recordSavingContribution(c, models.SavingContributionDeposit)
+}
+
+func CreateSavingWithdrawal(c *gin.Context) {
+	recordSavingContribution(c, models.SavingContributionWithdrawal)
+}
+
+func recordSavingContribution(c *gin.Context, cType string) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var input services.SavingContributionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	service := services.NewSavingService(utils.RequestDB(c))
+	if !savingTargetAllowed(c, service, userID) {
+		return
+	}
+	if mid, restricted := restrictedMember(c); restricted && input.CounterAccountID != nil {
+		var cnt int64
+		utils.RequestDB(c).Model(&models.Account{}).Where("member_id = ? AND id = ?", mid, *input.CounterAccountID).Count(&cnt)
+		if cnt == 0 {
+			utils.RespondWithError(c, http.StatusForbidden, "You can only use your own accounts")
+			return
+		}
+	}
+	var contribution *models.SavingContribution
+	if cType == models.SavingContributionWithdrawal {
+		contribution, err = service.Withdraw(userID, c.Param("id"), input)
+	} else {
+		contribution, err = service.Contribute(userID, c.Param("id"), input)
+	}
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	utils.RespondWithCreated(c, contribution)
+}
+
+// savingTargetAllowed memastikan role member hanya menyentuh target miliknya.
+func savingTargetAllowed(c *gin.Context, service *services.SavingService, userID uint) bool {
+	mid, restricted := restrictedMember(c)
+	if !restricted {
+		return true
+	}
+	target, err := service.FindTarget(userID, c.Param("id"))
+	if err != nil || target.MemberID != mid {
+		utils.RespondWithError(c, http.StatusNotFound, "Saving target not found")
+		return false
+	}
+	return true
+}
+
+// respondWithServiceError memakai status dari AppError kalau ada.
+func respondWithServiceError(c *gin.Context, err error) {
+	var appErr *utils.AppError
+	if errors.As(err, &appErr) {
+		utils.RespondWithError(c, appErr.StatusCode, appErr)
+		return
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.RespondWithError(c, http.StatusNotFound, "Record not found")
+		return
+	}
+	if errors.Is(err, services.ErrInsufficientBalance) {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	utils.RespondWithError(c, http.StatusInternalServerError, err.Error())
+}
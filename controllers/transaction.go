@@ -1,17 +1,48 @@
 package controllers
 
 import (
+	"fmt"
 	"net/http"
 
-	"finance-app/database"
 	"finance-app/services"
 	"finance-app/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
+// ownsTransaction memastikan role member hanya menyentuh transaksi miliknya.
+func ownsTransaction(c *gin.Context, service *services.TransactionService, userID uint, id string) bool {
+	mid, restricted := restrictedMember(c)
+	if !restricted {
+		return true
+	}
+	trx, err := service.GetTransactionByID(userID, id)
+	if err != nil || trx.MemberID != mid {
+		utils.RespondWithError(c, http.StatusNotFound, "Transaction not found")
+		return false
+	}
+	return true
+}
+
+// requestMemberAllowed menolak member_id milik orang lain dari role member.
+func requestMemberAllowed(c *gin.Context, req map[string]interface{}) bool {
+	mid, restricted := restrictedMember(c)
+	if !restricted {
+		return true
+	}
+	v, ok := req["member_id"]
+	if !ok {
+		return true
+	}
+	if f, ok := v.(float64); ok && uint(f) == mid {
+		return true
+	}
+	utils.RespondWithError(c, http.StatusForbidden, "You can only record transactions for your own member")
+	return false
+}
+
 func GetTransactions(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, err)
 		return
@@ -22,8 +53,11 @@ func GetTransactions(c *gin.Context) {
 		utils.RespondWithError(c, http.StatusBadRequest, "Invalid query parameters")
 		return
 	}
+	if mid, restricted := restrictedMember(c); restricted {
+		q.MemberID = fmt.Sprint(mid)
+	}
 
-	service := services.NewTransactionService(database.GetDB())
+	service := services.NewTransactionService(utils.RequestDB(c))
 	transactions, total, err := service.GetTransactions(userID, q)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusInternalServerError, err.Error())
@@ -34,23 +68,27 @@ func GetTransactions(c *gin.Context) {
 }
 
 func GetTransactionByID(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, err)
 		return
 	}
 
-	service := services.NewTransactionService(database.GetDB())
+	service := services.NewTransactionService(utils.RequestDB(c))
 	trx, err := service.GetTransactionByID(userID, c.Param("id"))
 	if err != nil {
 		utils.RespondWithError(c, http.StatusNotFound, "Transaction not found")
 		return
 	}
+	if mid, restricted := restrictedMember(c); restricted && trx.MemberID != mid {
+		utils.RespondWithError(c, http.StatusNotFound, "Transaction not found")
+		return
+	}
 	utils.RespondWithSuccess(c, trx)
 }
 
 func CreateTransaction(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, err)
 		return
@@ -61,18 +99,26 @@ func CreateTransaction(c *gin.Context) {
 		utils.RespondWithError(c, http.StatusBadRequest, utils.FormatValidationError(err))
 		return
 	}
+	if mid, restricted := restrictedMember(c); restricted {
+		if _, ok := req["member_id"]; !ok {
+			req["member_id"] = float64(mid)
+		}
+	}
+	if !requestMemberAllowed(c, req) {
+		return
+	}
 
-	service := services.NewTransactionService(database.GetDB())
+	service := services.NewTransactionService(utils.RequestDB(c))
 	trx, err := service.Create(userID, req)
 	if err != nil {
-		utils.RespondWithError(c, http.StatusInternalServerError, err.Error())
+		respondWithServiceError(c, err)
 		return
 	}
 	utils.RespondWithCreated(c, trx)
 }
 
 func UpdateTransaction(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, err)
 		return
@@ -84,25 +130,31 @@ func UpdateTransaction(c *gin.Context) {
 		return
 	}
 
-	service := services.NewTransactionService(database.GetDB())
+	service := services.NewTransactionService(utils.RequestDB(c))
+	if !ownsTransaction(c, service, userID, c.Param("id")) || !requestMemberAllowed(c, req) {
+		return
+	}
 	trx, err := service.Update(userID, c.Param("id"), req)
 	if err != nil {
-		utils.RespondWithError(c, http.StatusInternalServerError, err.Error())
+		respondWithServiceError(c, err)
 		return
 	}
 	utils.RespondWithSuccess(c, trx)
 }
 
 func DeleteTransaction(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, err)
 		return
 	}
 
-	service := services.NewTransactionService(database.GetDB())
+	service := services.NewTransactionService(utils.RequestDB(c))
+	if !ownsTransaction(c, service, userID, c.Param("id")) {
+		return
+	}
 	if err := service.Delete(userID, c.Param("id")); err != nil {
-		utils.RespondWithError(c, http.StatusInternalServerError, err.Error())
+		respondWithServiceError(c, err)
 		return
 	}
 	utils.RespondWithSuccess(c, gin.H{"message": "Transaction deleted successfully"})
@@ -4,8 +4,8 @@ import (
 	"net/http"
 	"time"
 
-	"finance-app/database"
 	"finance-app/models"
+	"finance-app/services"
 	"finance-app/utils"
 
 	"github.com/gin-gonic/gin"
@@ -14,7 +14,7 @@ import (
 
 // GET /transfers?member_id=&account_id=
 func GetTransfers(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetLedgerUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, err)
 		return
@@ -23,7 +23,7 @@ func GetTransfers(c *gin.Context) {
 	accountID := c.Query("account_id")
 
 	var transfers []models.Transfer
-	db := database.DB.Preload("Member").Preload("FromAccount").Preload("ToAccount")
+	db := utils.RequestDB(c).Preload("Member").Preload("FromAccount").Preload("ToAccount")
 
 	query := db.Joins("JOIN members ON members.id = transfers.member_id").
 		Where("members.user_id = ?", userID)
@@ -34,6 +34,9 @@ func GetTransfers(c *gin.Context) {
 	if accountID != "" {
 		query = query.Where("transfers.from_account_id = ? OR transfers.to_account_id = ?", accountID, accountID)
 	}
+	if mid, restricted := restrictedMember(c); restricted {
+		query = query.Where("transfers.member_id = ?", mid)
+	}
 
 	if err := query.Find(&transfers).Error; err != nil {
 		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to get transfers")
@@ -45,7 +48,7 @@ func GetTransfers(c *gin.Context) {
 
 // POST /transfers
 func CreateTransfer(c *gin.Context) {
-	userID, emsg := utils.GetUserIDFromToken(c)
+	userID, emsg := utils.GetLedgerUserID(c)
 	if emsg != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, emsg)
 		return
@@ -62,7 +65,26 @@ func CreateTransfer(c *gin.Context) {
 		return
 	}
 
-	err := database.DB.Transaction(func(tx *gorm.DB) error {
+	for _, accID := range []uint{input.FromAccountID, input.ToAccountID} {
+		if err := services.EnsureAccountActive(utils.RequestDB(c), accID); err != nil {
+			respondWithServiceError(c, err)
+			return
+		}
+	}
+
+	// role member hanya boleh transfer antar akun miliknya sendiri
+	if mid, restricted := restrictedMember(c); restricted {
+		var cnt int64
+		utils.RequestDB(c).Model(&models.Account{}).
+			Where("member_id = ? AND id IN ?", mid, []uint{input.FromAccountID, input.ToAccountID}).
+			Count(&cnt)
+		if input.MemberID != mid || cnt != 2 {
+			utils.RespondWithError(c, http.StatusForbidden, "You can only transfer between your own accounts")
+			return
+		}
+	}
+
+	err := utils.RequestDB(c).Transaction(func(tx *gorm.DB) error {
 		var member models.Member
 		if err := tx.Where("id = ? AND user_id = ?", input.MemberID, userID).First(&member).Error; err != nil {
 			return err
@@ -112,18 +134,22 @@ func CreateTransfer(c *gin.Context) {
 
 // GET /transfers/:id
 func GetTransferByID(c *gin.Context) {
-	userID, emsg := utils.GetUserIDFromToken(c)
+	userID, emsg := utils.GetLedgerUserID(c)
 	if emsg != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, emsg)
 		return
 	}
 	id := c.Param("id")
 
-	var transfer models.Transfer
-	if err := database.DB.Preload("Member").Preload("FromAccount").Preload("ToAccount").
+	query := utils.RequestDB(c).Preload("Member").Preload("FromAccount").Preload("ToAccount").
 		Joins("JOIN members ON members.id = transfers.member_id").
-		Where("transfers.id = ? AND members.user_id = ?", id, userID).
-		First(&transfer).Error; err != nil {
+		Where("transfers.id = ? AND members.user_id = ?", id, userID)
+	if mid, restricted := restrictedMember(c); restricted {
+		query = query.Where("transfers.member_id = ?", mid)
+	}
+
+	var transfer models.Transfer
+	if err := query.First(&transfer).Error; err != nil {
 		utils.RespondWithError(c, http.StatusNotFound, "Transfer not found")
 		return
 	}
@@ -133,19 +159,24 @@ func GetTransferByID(c *gin.Context) {
 
 // DELETE /transfers/:id
 func DeleteTransfer(c *gin.Context) {
-	userID, emsg := utils.GetUserIDFromToken(c)
+	userID, emsg := utils.GetLedgerUserID(c)
 	if emsg != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, emsg)
 		return
 	}
 	id := c.Param("id")
+	mid, restricted := restrictedMember(c)
 
-	err := database.DB.Transaction(func(tx *gorm.DB) error {
-		var transfer models.Transfer
-		if err := tx.Preload("FromAccount").Preload("ToAccount").
+	err := utils.RequestDB(c).Transaction(func(tx *gorm.DB) error {
+		query := tx.Preload("FromAccount").Preload("ToAccount").
 			Joins("JOIN members ON members.id = transfers.member_id").
-			Where("transfers.id = ? AND members.user_id = ?", id, userID).
-			First(&transfer).Error; err != nil {
+			Where("transfers.id = ? AND members.user_id = ?", id, userID)
+		if restricted {
+			query = query.Where("transfers.member_id = ?", mid)
+		}
+
+		var transfer models.Transfer
+		if err := query.First(&transfer).Error; err != nil {
 			return err
 		}
 
@@ -158,6 +189,9 @@ func DeleteTransfer(c *gin.Context) {
 		if err := tx.Save(&transfer.ToAccount).Error; err != nil {
 			return err
 		}
+		if err := services.ReleaseLoanPayments(tx, "transfer_id", transfer.ID); err != nil {
+			return err
+		}
 
 		if err := tx.Delete(&transfer).Error; err != nil {
 			return err
@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"finance-app/services"
+	"finance-app/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTrash godoc
+// @Summary List trash
+// @Description Data yang dihapus dalam masa retensi (default 30 hari), dikelompokkan per jenis: transactions, transfers, accounts, categories.
+// @Tags Trash
+// @Produce json
+// @Param type query string false "transactions, transfers, accounts, categories"
+// @Success 200 {object} map[string]interface{}
+// @Router /trash [get]
+// @Security BearerAuth
+func GetTrash(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	trashType := c.Query("type")
+	if trashType != "" && !services.IsTrashType(trashType) {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid trash type")
+		return
+	}
+
+	items, err := services.NewTrashService(utils.RequestDB(c)).List(userID, trashType)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	utils.RespondWithSuccess(c, items)
+}
+
+// RestoreTrashItem godoc
+// @Summary Restore deleted item
+// @Description Memulihkan data dari trash. Transaksi dan transfer menerapkan ulang efek saldonya ke akun.
+// @Tags Trash
+// @Produce json
+// @Param type path string true "transactions, transfers, accounts, categories"
+// @Param id path int true "Item ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /trash/{type}/{id}/restore [post]
+// @Security BearerAuth
+func RestoreTrashItem(c *gin.Context) {
+	userID, err := utils.GetLedgerUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	trashType := c.Param("type")
+	if !services.IsTrashType(trashType) {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid trash type")
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid item ID")
+		return
+	}
+
+	if err := services.NewTrashService(utils.RequestDB(c)).Restore(userID, trashType, uint(id)); err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	utils.RespondWithSuccess(c, gin.H{"message": "Item restored successfully"})
+}
@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"finance-app/models"
+	"finance-app/services"
+	"finance-app/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTwoFactorStatus godoc
+// @Summary Get 2FA status
+// @Description Status 2FA user dan sisa recovery code
+// @Tags User
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /user/2fa [get]
+// @Security BearerAuth
+func GetTwoFactorStatus(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	db := utils.RequestDB(c)
+	var user models.User
+	if err := db.First(&user, userID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusNotFound, "User not found")
+		return
+	}
+
+	remaining, err := services.NewTwoFactorService(db).RemainingRecoveryCodes(userID)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch 2FA status")
+		return
+	}
+
+	utils.RespondWithSuccess(c, gin.H{
+		"enabled":                  user.TwoFactorEnabled,
+		"recovery_codes_remaining": remaining,
+	})
+}
+
+// SetupTwoFactor godoc
+// @Summary Start 2FA enrollment
+// @Description Buat secret TOTP baru. Response berisi otpauth URI dan QR PNG (base64). 2FA aktif setelah dikonfirmasi.
+// @Tags User
+// @Produce json
+// @Success 200 {object} services.TwoFactorSetup
+// @Failure 409 {object} map[string]string
+// @Router /user/2fa/setup [post]
+// @Security BearerAuth
+func SetupTwoFactor(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	setup, err := services.NewTwoFactorService(utils.RequestDB(c)).Setup(userID)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	utils.RespondWithSuccess(c, setup)
+}
+
+// ConfirmTwoFactor godoc
+// @Summary Confirm 2FA enrollment
+// @Description Aktifkan 2FA dengan kode dari aplikasi authenticator. Recovery code hanya ditampilkan sekali.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param body body models.TwoFactorCodeRequest true "Kode TOTP"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Router /user/2fa/confirm [post]
+// @Security BearerAuth
+func ConfirmTwoFactor(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var input struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	codes, err := services.NewTwoFactorService(utils.RequestDB(c)).Confirm(userID, input.Code)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	utils.RespondWithSuccess(c, gin.H{
+		"message":        "Two-factor authentication enabled",
+		"recovery_codes": codes,
+	})
+}
+
+// DisableTwoFactor godoc
+// @Summary Disable 2FA
+// @Description Matikan 2FA. Butuh password dan kode TOTP atau recovery code.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param body body models.TwoFactorDisableRequest true "Password dan kode"
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /user/2fa/disable [post]
+// @Security BearerAuth
+func DisableTwoFactor(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var input struct {
+		Password string `json:"password" binding:"required"`
+		Code     string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := services.NewTwoFactorService(utils.RequestDB(c)).Disable(userID, input.Password, input.Code); err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	utils.RespondWithSuccess(c, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Regenerate recovery codes
+// @Description Ganti semua recovery code 2FA. Kode lama langsung tidak berlaku.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Param body body models.TwoFactorCodeRequest true "Kode TOTP"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Router /user/2fa/recovery-codes [post]
+// @Security BearerAuth
+func RegenerateRecoveryCodes(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var input struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	codes, err := services.NewTwoFactorService(utils.RequestDB(c)).RegenerateRecoveryCodes(userID, input.Code)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	utils.RespondWithSuccess(c, gin.H{"recovery_codes": codes})
+}
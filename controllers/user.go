@@ -1,10 +1,11 @@
 package controllers
 
 import (
-	"finance-app/database"
 	"finance-app/models"
+	"finance-app/services"
 	"finance-app/utils"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
@@ -20,14 +21,14 @@ import (
 // @Router /user [get]
 // @Security BearerAuth
 func GetCurrentUser(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
 	var user models.User
-	db := database.GetDB()
+	db := utils.RequestDB(c)
 	if err := db.First(&user, userID).Error; err != nil {
 		utils.RespondWithError(c, http.StatusNotFound, "User not found")
 		return
@@ -40,7 +41,7 @@ func GetCurrentUser(c *gin.Context) {
 
 // UpdateUser godoc
 // @Summary Update current user
-// @Description Update data user yang sedang login
+// @Description Update data user yang sedang login. Jika password diganti, semua sesi dicabut dan response berisi {user, tokens} baru.
 // @Tags User
 // @Accept json
 // @Produce json
@@ -50,7 +51,7 @@ func GetCurrentUser(c *gin.Context) {
 // @Router /user [put]
 // @Security BearerAuth
 func UpdateUser(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
@@ -67,7 +68,7 @@ func UpdateUser(c *gin.Context) {
 		return
 	}
 
-	db := database.GetDB()
+	db := utils.RequestDB(c)
 	var user models.User
 	if err := db.First(&user, userID).Error; err != nil {
 		utils.RespondWithError(c, http.StatusNotFound, "User not found")
@@ -78,8 +79,11 @@ func UpdateUser(c *gin.Context) {
 	if input.Username != "" {
 		user.Username = input.Username
 	}
-	if input.Email != "" {
+	emailChanged := input.Email != "" && input.Email != user.Email
+	if emailChanged {
+		// email baru harus diverifikasi ulang
 		user.Email = input.Email
+		user.EmailVerifiedAt = nil
 	}
 	if input.Password != "" {
 		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
@@ -95,31 +99,151 @@ func UpdateUser(c *gin.Context) {
 		return
 	}
 
+	if emailChanged {
+		services.NewUserTokenService(db, services.MailerFromConfig()).SendEmailVerificationAsync(user)
+	}
+
 	user.Password = ""
-	utils.RespondWithSuccess(c, user)
+	if input.Password == "" {
+		utils.RespondWithSuccess(c, user)
+		return
+	}
+
+	// Ganti password = logout dari semua sesi, lalu beri token baru untuk sesi ini
+	tokens := services.NewTokenService(db)
+	if err := tokens.RevokeAllForUser(user.ID); err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to revoke sessions")
+		return
+	}
+	pair, err := tokens.IssueTokenPair(user.ID, sessionMeta(c, ""))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to generate token")
+		return
+	}
+
+	utils.RespondWithSuccess(c, gin.H{"user": user, "tokens": pair})
 }
 
 // DeleteUser godoc
 // @Summary Delete current user
-// @Description Hapus user yang sedang login
+// @Description Hapus akun beserta seluruh data miliknya secara permanen. Butuh password (dan kode 2FA kalau aktif). Kalau ACCOUNT_DELETION_GRACE diset, akun hanya dijadwalkan untuk dihapus dan bisa dibatalkan.
 // @Tags User
+// @Accept json
 // @Produce json
+// @Param request body models.AccountDeleteRequest true "Konfirmasi password"
 // @Success 200 {object} models.DeleteResponse
 // @Failure 401 {object} map[string]string
 // @Router /user [delete]
 // @Security BearerAuth
 func DeleteUser(c *gin.Context) {
-	userID, err := utils.GetUserIDFromToken(c)
+	userID, err := utils.GetUserID(c)
 	if err != nil {
 		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
-	db := database.GetDB()
-	if err := db.Delete(&models.User{}, userID).Error; err != nil {
-		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to delete user")
+	var input struct {
+		Password string `json:"password" binding:"required"`
+		Code     string `json:"code"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	service := services.NewAccountDeletionService(utils.RequestDB(c), services.MailerFromConfig())
+	scheduledAt, err := service.RequestDeletion(userID, input.Password, input.Code)
+	if err != nil {
+		respondWithServiceError(c, err)
 		return
 	}
 
+	if scheduledAt != nil {
+		utils.RespondWithSuccess(c, gin.H{
+			"message":               "Account scheduled for deletion",
+			"deletion_scheduled_at": scheduledAt,
+		})
+		return
+	}
 	utils.RespondWithSuccess(c, gin.H{"message": "User deleted successfully"})
 }
+
+// CancelAccountDeletion godoc
+// @Summary Cancel scheduled account deletion
+// @Description Membatalkan penghapusan akun yang masih dalam masa tenggang
+// @Tags User
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Failure 409 {object} map[string]interface{}
+// @Router /user/deletion/cancel [post]
+// @Security BearerAuth
+func CancelAccountDeletion(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	if err := services.NewAccountDeletionService(utils.RequestDB(c), nil).CancelDeletion(userID); err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	utils.RespondWithSuccess(c, gin.H{"message": "Account deletion cancelled"})
+}
+
+// GetSessions godoc
+// @Summary List active sessions
+// @Description Daftar sesi login aktif (perangkat, IP, user agent, terakhir aktif) milik user
+// @Tags User
+// @Produce json
+// @Success 200 {array} models.Session
+// @Failure 401 {object} map[string]string
+// @Router /user/sessions [get]
+// @Security BearerAuth
+func GetSessions(c *gin.Context) {
+	principal, err := utils.GetPrincipal(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	sessions, err := services.NewTokenService(utils.RequestDB(c)).ListSessions(principal.UserID, principal.SessionID)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch sessions")
+		return
+	}
+
+	utils.RespondWithSuccess(c, sessions)
+}
+
+// RevokeSession godoc
+// @Summary Revoke a session
+// @Description Cabut satu sesi (mis. HP yang hilang). Access & refresh token sesi itu langsung tidak berlaku.
+// @Tags User
+// @Produce json
+// @Param id path int true "Session ID"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /user/sessions/{id} [delete]
+// @Security BearerAuth
+func RevokeSession(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid session ID")
+		return
+	}
+
+	if err := services.NewTokenService(utils.RequestDB(c)).RevokeSession(userID, uint(id)); err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	utils.RespondWithSuccess(c, gin.H{"message": "Session revoked"})
+}
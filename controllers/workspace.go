@@ -0,0 +1,250 @@
+package controllers
+
+import (
+	"finance-app/services"
+	"finance-app/utils"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func workspaceScope(c *gin.Context) (*utils.WorkspaceScope, bool) {
+	scope, err := utils.GetWorkspaceScope(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return nil, false
+	}
+	return scope, true
+}
+
+// restrictedMember mengembalikan member milik user role "member".
+// ok = false berarti user boleh mengakses semua member di workspace.
+func restrictedMember(c *gin.Context) (uint, bool) {
+	scope, err := utils.GetWorkspaceScope(c)
+	if err != nil {
+		return 0, true
+	}
+	return scope.RestrictedMemberID()
+}
+
+// GET /workspaces — semua workspace yang bisa diakses user login
+func GetMyWorkspaces(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	service := services.NewWorkspaceService(utils.RequestDB(c))
+	memberships, err := service.ListForUser(userID)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch workspaces")
+		return
+	}
+
+	utils.RespondWithSuccess(c, memberships)
+}
+
+// GET /workspace — workspace aktif
+func GetCurrentWorkspace(c *gin.Context) {
+	scope, ok := workspaceScope(c)
+	if !ok {
+		return
+	}
+
+	service := services.NewWorkspaceService(utils.RequestDB(c))
+	ws, err := service.GetWorkspace(scope)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusNotFound, "Workspace not found")
+		return
+	}
+
+	utils.RespondWithSuccess(c, gin.H{
+		"workspace": ws,
+		"role":      scope.Role,
+		"member_id": scope.MemberID,
+	})
+}
+
+// PUT /workspace
+func UpdateWorkspace(c *gin.Context) {
+	scope, ok := workspaceScope(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Name string `json:"name" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	service := services.NewWorkspaceService(utils.RequestDB(c))
+	ws, err := service.Rename(scope, input.Name)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update workspace")
+		return
+	}
+
+	utils.RespondWithSuccess(c, ws)
+}
+
+// GET /workspace/memberships
+func GetWorkspaceMemberships(c *gin.Context) {
+	scope, ok := workspaceScope(c)
+	if !ok {
+		return
+	}
+
+	service := services.NewWorkspaceService(utils.RequestDB(c))
+	memberships, err := service.ListMemberships(scope)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch memberships")
+		return
+	}
+
+	utils.RespondWithSuccess(c, memberships)
+}
+
+// PUT /workspace/memberships/:id
+func UpdateWorkspaceMembership(c *gin.Context) {
+	scope, ok := workspaceScope(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Role     string `json:"role"`
+		MemberID *uint  `json:"member_id"` // 0 = lepas tautan member
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	service := services.NewWorkspaceService(utils.RequestDB(c))
+	membership, err := service.UpdateMembership(scope, c.Param("id"), input.Role, input.MemberID)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	utils.RespondWithSuccess(c, membership)
+}
+
+// DELETE /workspace/memberships/:id
+func DeleteWorkspaceMembership(c *gin.Context) {
+	scope, ok := workspaceScope(c)
+	if !ok {
+		return
+	}
+
+	service := services.NewWorkspaceService(utils.RequestDB(c))
+	if err := service.RemoveMembership(scope, c.Param("id")); err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	utils.RespondWithSuccess(c, gin.H{"message": "Membership removed successfully"})
+}
+
+// GET /workspace/invitations
+func GetWorkspaceInvitations(c *gin.Context) {
+	scope, ok := workspaceScope(c)
+	if !ok {
+		return
+	}
+
+	service := services.NewWorkspaceService(utils.RequestDB(c))
+	invitations, err := service.ListInvitations(scope)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch invitations")
+		return
+	}
+
+	utils.RespondWithSuccess(c, invitations)
+}
+
+// POST /workspace/invitations
+func CreateWorkspaceInvitation(c *gin.Context) {
+	scope, ok := workspaceScope(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Email    string `json:"email" binding:"required,email"`
+		Role     string `json:"role" binding:"required"`
+		MemberID *uint  `json:"member_id"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	service := services.NewWorkspaceService(utils.RequestDB(c))
+	invitation, token, err := service.Invite(scope, input.Email, input.Role, input.MemberID)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	mailer := services.MailerFromConfig()
+	sent := services.MailConfigured(mailer)
+	if err := service.SendInvitation(mailer, invitation, token); err != nil {
+		log.Printf("⚠️  failed to send workspace invitation to %s: %v", invitation.Email, err)
+		sent = false
+	}
+
+	// token mentah hanya dikembalikan kalau email tidak terkirim
+	response := gin.H{"invitation": invitation, "email_sent": sent}
+	if !sent {
+		response["token"] = token
+	}
+	utils.RespondWithCreated(c, response)
+}
+
+// DELETE /workspace/invitations/:id
+func DeleteWorkspaceInvitation(c *gin.Context) {
+	scope, ok := workspaceScope(c)
+	if !ok {
+		return
+	}
+
+	service := services.NewWorkspaceService(utils.RequestDB(c))
+	if err := service.RevokeInvitation(scope, c.Param("id")); err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	utils.RespondWithSuccess(c, gin.H{"message": "Invitation revoked successfully"})
+}
+
+// POST /invitations/accept
+func AcceptWorkspaceInvitation(c *gin.Context) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var input struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	service := services.NewWorkspaceService(utils.RequestDB(c))
+	membership, err := service.AcceptInvitation(userID, input.Token)
+	if err != nil {
+		respondWithServiceError(c, err)
+		return
+	}
+
+	utils.RespondWithSuccess(c, membership)
+}
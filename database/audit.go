@@ -0,0 +1,368 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"time"
+
+	"finance-app/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// auditedTables memetakan tabel yang diaudit ke nama entity di audit log.
+var auditedTables = map[string]string{
+	"transactions":           "transaction",
+	"transfers":              "transfer",
+	"accounts":               "account",
+	"budget_categories":      "budget",
+	"saving_targets":         "saving_target",
+	"recurring_transactions": "recurring_transaction",
+	"assets":                 "asset",
+	"asset_valuations":       "asset_valuation",
+	"loans":                  "loan",
+	"loan_installments":      "loan_installment",
+	"securities":             "security",
+	"investment_lots":        "investment_lot",
+	"investment_dividends":   "investment_dividend",
+}
+
+// AuditEntities mengembalikan daftar nama entity yang diaudit.
+func AuditEntities() []string {
+	entities := make([]string, 0, len(auditedTables))
+	for _, e := range auditedTables {
+		entities = append(entities, e)
+	}
+	return entities
+}
+
+// AuditActor adalah pelaku perubahan; nil di context berarti proses sistem (scheduler).
+type AuditActor struct {
+	UserID   uint
+	APIKeyID *uint
+	IP       string
+}
+
+type auditActorKey struct{}
+
+// WithAuditActor menempelkan pelaku ke context yang dipakai query GORM.
+func WithAuditActor(ctx context.Context, actor *AuditActor) context.Context {
+	return context.WithValue(ctx, auditActorKey{}, actor)
+}
+
+func auditActorFrom(ctx context.Context) *AuditActor {
+	if ctx == nil {
+		return nil
+	}
+	actor, _ := ctx.Value(auditActorKey{}).(*AuditActor)
+	return actor
+}
+
+const auditBeforeKey = "audit:before"
+
+// RegisterAuditCallbacks memasang callback GORM yang mencatat setiap create,
+// update dan delete pada tabel di auditedTables ke tabel audit_logs, di dalam
+// transaksi yang sama dengan perubahannya.
+func RegisterAuditCallbacks(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Create().After("gorm:create").Register("audit:after_create", auditAfterCreate); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:setup_reflect_value").Before("gorm:update").Register("audit:before_update", auditBeforeMutation); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:update").Register("audit:after_update", auditAfterUpdate); err != nil {
+		return err
+	}
+	if err := cb.Delete().Before("gorm:delete").Register("audit:before_delete", auditBeforeMutation); err != nil {
+		return err
+	}
+	return cb.Delete().After("gorm:delete").Register("audit:after_delete", auditAfterDelete)
+}
+
+func auditEntity(db *gorm.DB) (string, bool) {
+	if db.Statement.Schema == nil {
+		return "", false
+	}
+	entity, ok := auditedTables[db.Statement.Schema.Table]
+	return entity, ok
+}
+
+/* ===========================
+   Snapshots
+=========================== */
+
+type auditSnapshot map[string]interface{}
+
+// snapshotOf mengambil nilai semua kolom dari satu struct model.
+func snapshotOf(db *gorm.DB, rv reflect.Value) auditSnapshot {
+	for rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	snap := auditSnapshot{}
+	for _, field := range db.Statement.Schema.Fields {
+		if field.DBName == "" {
+			continue
+		}
+		value, _ := field.ValueOf(db.Statement.Context, rv)
+		snap[field.DBName] = value
+	}
+	return snap
+}
+
+func (s auditSnapshot) id() uint {
+	id, _ := s["id"].(uint)
+	return id
+}
+
+// ledgerUserID mengambil pemilik data. Tabel tanpa user_id (accounts) ditelusuri lewat member.
+func (s auditSnapshot) ledgerUserID(db *gorm.DB) uint {
+	if id, ok := s["user_id"].(uint); ok {
+		return id
+	}
+	memberID, ok := s["member_id"].(uint)
+	if !ok || memberID == 0 {
+		return 0
+	}
+	var userID uint
+	db.Session(&gorm.Session{NewDB: true}).
+		Table("members").Select("user_id").Where("id = ?", memberID).
+		Scan(&userID)
+	return userID
+}
+
+// loadRows membaca baris yang akan terkena statement (primary key dari model
+// dan/atau klausa WHERE) sebelum statement dijalankan.
+func loadRows(db *gorm.DB, ids []uint) []auditSnapshot {
+	stmt := db.Statement
+	modelType := stmt.Schema.ModelType
+
+	tx := db.Session(&gorm.Session{NewDB: true, SkipHooks: true}).
+		Model(reflect.New(modelType).Interface())
+	if stmt.Unscoped {
+		tx = tx.Unscoped()
+	}
+
+	if ids != nil {
+		tx = tx.Where("id IN ?", ids)
+	} else {
+		conds := false
+		if pk := primaryKeyValues(db); len(pk) > 0 {
+			tx = tx.Where("id IN ?", pk)
+			conds = true
+		}
+		if c, ok := stmt.Clauses["WHERE"]; ok {
+			if where, ok := c.Expression.(clause.Where); ok && len(where.Exprs) > 0 {
+				tx = tx.Clauses(clause.Where{Exprs: where.Exprs})
+				conds = true
+			}
+		}
+		if !conds {
+			return nil
+		}
+	}
+
+	rows := reflect.New(reflect.SliceOf(modelType))
+	if err := tx.Find(rows.Interface()).Error; err != nil {
+		log.Printf("⚠️  audit: failed to load %s rows: %v", stmt.Schema.Table, err)
+		return nil
+	}
+
+	out := make([]auditSnapshot, 0, rows.Elem().Len())
+	for i := 0; i < rows.Elem().Len(); i++ {
+		out = append(out, snapshotOf(db, rows.Elem().Index(i)))
+	}
+	return out
+}
+
+// primaryKeyValues mengambil ID dari model/dest statement (struct atau slice).
+func primaryKeyValues(db *gorm.DB) []uint {
+	rv := db.Statement.ReflectValue
+	var ids []uint
+	add := func(v reflect.Value) {
+		for v.Kind() == reflect.Ptr {
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return
+		}
+		if id, ok := snapshotOf(db, v)["id"].(uint); ok && id != 0 {
+			ids = append(ids, id)
+		}
+	}
+
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			add(rv.Index(i))
+		}
+	case reflect.Struct:
+		add(rv)
+	}
+	return ids
+}
+
+/* ===========================
+   Callbacks
+=========================== */
+
+func auditBeforeMutation(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+	if _, ok := auditEntity(db); !ok {
+		return
+	}
+	db.InstanceSet(auditBeforeKey, loadRows(db, nil))
+}
+
+func auditAfterCreate(db *gorm.DB) {
+	entity, ok := auditEntity(db)
+	if !ok || db.Error != nil {
+		return
+	}
+
+	var logs []models.AuditLog
+	add := func(v reflect.Value) {
+		after := snapshotOf(db, v)
+		logs = append(logs, newAuditLog(db, models.AuditActionCreate, entity, after.id(), after.ledgerUserID(db), nil, after, nil))
+	}
+	rv := db.Statement.ReflectValue
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			add(rv.Index(i))
+		}
+	case reflect.Struct:
+		add(rv)
+	}
+	writeAuditLogs(db, logs)
+}
+
+func auditAfterUpdate(db *gorm.DB) {
+	entity, ok := auditEntity(db)
+	if !ok || db.Error != nil || db.RowsAffected == 0 {
+		return
+	}
+	before := beforeRows(db)
+	if len(before) == 0 {
+		return
+	}
+
+	ids := make([]uint, 0, len(before))
+	for _, b := range before {
+		ids = append(ids, b.id())
+	}
+	// baca ulang supaya nilai hasil ekspresi (mis. balance + ?) ikut tercatat
+	afterByID := map[uint]auditSnapshot{}
+	for _, a := range loadRows(db, ids) {
+		afterByID[a.id()] = a
+	}
+
+	var logs []models.AuditLog
+	for _, b := range before {
+		after, ok := afterByID[b.id()]
+		if !ok {
+			// baris jadi tidak terlihat (mis. deleted_at di-set lewat Update)
+			logs = append(logs, newAuditLog(db, models.AuditActionDelete, entity, b.id(), b.ledgerUserID(db), b, nil, nil))
+			continue
+		}
+		changes := diffSnapshots(b, after)
+		if len(changes) == 0 {
+			continue
+		}
+		logs = append(logs, newAuditLog(db, models.AuditActionUpdate, entity, b.id(), b.ledgerUserID(db), b, after, changes))
+	}
+	writeAuditLogs(db, logs)
+}
+
+func auditAfterDelete(db *gorm.DB) {
+	entity, ok := auditEntity(db)
+	if !ok || db.Error != nil || db.RowsAffected == 0 {
+		return
+	}
+
+	var logs []models.AuditLog
+	for _, b := range beforeRows(db) {
+		logs = append(logs, newAuditLog(db, models.AuditActionDelete, entity, b.id(), b.ledgerUserID(db), b, nil, nil))
+	}
+	writeAuditLogs(db, logs)
+}
+
+func beforeRows(db *gorm.DB) []auditSnapshot {
+	v, ok := db.InstanceGet(auditBeforeKey)
+	if !ok {
+		return nil
+	}
+	rows, _ := v.([]auditSnapshot)
+	return rows
+}
+
+/* ===========================
+   Helpers
+=========================== */
+
+// diffSnapshots mengembalikan kolom yang berubah dalam bentuk {kolom: {from, to}}.
+func diffSnapshots(before, after auditSnapshot) map[string]interface{} {
+	changes := map[string]interface{}{}
+	for col, newVal := range after {
+		if col == "updated_at" {
+			continue
+		}
+		oldVal := before[col]
+		if auditValueString(oldVal) != auditValueString(newVal) {
+			changes[col] = map[string]interface{}{"from": oldVal, "to": newVal}
+		}
+	}
+	return changes
+}
+
+func auditValueString(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func toJSONText(v interface{}) models.JSONText {
+	if v == nil || reflect.ValueOf(v).IsNil() {
+		return ""
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return models.JSONText(fmt.Sprintf("%q", err.Error()))
+	}
+	return models.JSONText(b)
+}
+
+func newAuditLog(db *gorm.DB, action, entity string, entityID, ledgerUserID uint, before, after auditSnapshot, changes map[string]interface{}) models.AuditLog {
+	entry := models.AuditLog{
+		CreatedAt:    time.Now(),
+		LedgerUserID: ledgerUserID,
+		Action:       action,
+		Entity:       entity,
+		EntityID:     entityID,
+		Before:       toJSONText(before),
+		After:        toJSONText(after),
+		Changes:      toJSONText(changes),
+	}
+	if actor := auditActorFrom(db.Statement.Context); actor != nil {
+		userID := actor.UserID
+		entry.ActorUserID = &userID
+		entry.ActorAPIKeyID = actor.APIKeyID
+		entry.IPAddress = actor.IP
+	}
+	return entry
+}
+
+func writeAuditLogs(db *gorm.DB, logs []models.AuditLog) {
+	if len(logs) == 0 {
+		return
+	}
+	// ikut transaksi statement asal: kalau perubahan di-rollback, log-nya juga
+	if err := db.Session(&gorm.Session{NewDB: true, SkipHooks: true}).Create(&logs).Error; err != nil {
+		db.AddError(fmt.Errorf("audit log: %w", err))
+	}
+}
@@ -12,7 +12,7 @@ import (
 var DB *gorm.DB
 
 func InitDB() {
-	config := config.LoadConfig()
+	config := config.Get()
 
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
 		config.DBUser,
@@ -28,6 +28,10 @@ func InitDB() {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
+	if err := RegisterAuditCallbacks(DB); err != nil {
+		log.Fatal("Failed to register audit callbacks:", err)
+	}
+
 	log.Println("Database connected successfully")
 }
 
@@ -3,6 +3,9 @@ package database
 import (
 	"finance-app/models"
 	"log"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
 func MigrateDB() {
@@ -16,10 +19,112 @@ func MigrateDB() {
 		&models.RecurringTransaction{},
 		&models.Transfer{},
 		&models.SavingTarget{},
+		&models.SavingContribution{},
+		&models.SavingRule{},
+		&models.Workspace{},
+		&models.WorkspaceMembership{},
+		&models.WorkspaceInvitation{},
+		&models.Allowance{},
+		&models.RefreshToken{},
+		&models.Session{},
+		&models.UserToken{},
+		&models.RecoveryCode{},
+		&models.LoginAttempt{},
+		&models.APIKey{},
+		&models.AuditLog{},
+		&models.RevokedToken{},
+		&models.AccountBalanceSnapshot{},
+		&models.NetWorthSnapshot{},
+		&models.AssetValueSnapshot{},
+		&models.Asset{},
+		&models.AssetValuation{},
+		&models.Loan{},
+		&models.LoanInstallment{},
+		&models.Security{},
+		&models.SecurityPrice{},
+		&models.InvestmentLot{},
+		&models.InvestmentDividend{},
 	)
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
 
+	migrateAccountTypeCheck()
+	backfillSavingContributions()
+	backfillAllowanceStartDates()
+
 	log.Println("Database migration completed")
 }
+
+// migrateAccountTypeCheck membuat ulang check constraint tipe akun yang dibuat
+// sebelum tipe Investment ada; AutoMigrate tidak mengubah constraint yang sudah ada.
+func migrateAccountTypeCheck() {
+	if DB.Dialector.Name() != "mysql" {
+		return
+	}
+	// MySQL < 8.0.16 tidak punya tabel ini (dan tidak menegakkan CHECK), jadi error diabaikan
+	quiet := DB.Session(&gorm.Session{Logger: logger.Default.LogMode(logger.Silent)})
+	var outdated int64
+	if err := quiet.Raw(`SELECT COUNT(*) FROM information_schema.CHECK_CONSTRAINTS
+		WHERE CONSTRAINT_SCHEMA = DATABASE() AND CONSTRAINT_NAME = 'chk_accounts_type'
+		AND CHECK_CLAUSE NOT LIKE '%Investment%'`).Scan(&outdated).Error; err != nil || outdated == 0 {
+		return
+	}
+
+	if err := DB.Migrator().DropConstraint(&models.Account{}, "chk_accounts_type"); err != nil {
+		log.Println("Failed to drop account type check:", err)
+		return
+	}
+	if err := DB.Migrator().CreateConstraint(&models.Account{}, "chk_accounts_type"); err != nil {
+		log.Println("Failed to recreate account type check:", err)
+		return
+	}
+	log.Println("Account type check now allows Investment")
+}
+
+// backfillSavingContributions mengubah CurrentAmount lama (yang diisi manual)
+// menjadi kontribusi pembuka, supaya ledger dan CurrentAmount tetap sama.
+// Dijalankan dalam satu transaksi; kalau gagal, tidak ada yang ditulis dan
+// backfill dicoba lagi saat start berikutnya.
+func backfillSavingContributions() {
+	var targets []models.SavingTarget
+	if err := DB.Where("current_amount > 0 AND id NOT IN (?)",
+		DB.Model(&models.SavingContribution{}).Select("saving_target_id")).
+		Find(&targets).Error; err != nil {
+		log.Println("Failed to load saving targets for backfill:", err)
+		return
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		for _, t := range targets {
+			if err := tx.Create(&models.SavingContribution{
+				UserID:         t.UserID,
+				SavingTargetID: t.ID,
+				Type:           models.SavingContributionDeposit,
+				Amount:         t.CurrentAmount,
+				Date:           t.CreatedAt.Format("2006-01-02"),
+				Description:    "Opening balance",
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Println("Failed to backfill saving target opening balances:", err)
+		return
+	}
+	log.Printf("Backfilled %d saving target opening balances", len(targets))
+}
+
+// backfillAllowanceStartDates mengisi acuan jadwal uang saku lama dari jadwal berikutnya.
+func backfillAllowanceStartDates() {
+	if err := DB.Model(&models.Allowance{}).
+		Where("start_date IS NULL OR start_date = ''").
+		Update("start_date", gorm.Expr("next_run_date")).Error; err != nil {
+		log.Println("Failed to backfill allowance start dates:", err)
+	}
+}
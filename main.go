@@ -4,12 +4,12 @@ import (
 	"finance-app/config"
 	"finance-app/database"
 	"finance-app/routes"
+	"finance-app/services"
 	"fmt"
 	"time"
 
 	_ "finance-app/docs" // docs swagger hasil dari swag init
 
-	"github.com/gin-contrib/cors"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
@@ -35,7 +35,7 @@ import (
 // @description Format: "Bearer {token}"
 func main() {
 	// Load config
-	cfg := config.LoadConfig()
+	cfg := config.Get()
 
 	// DEBUG: print JWT secret yang dipakai
 	fmt.Println("🚀 Using JWT_SECRET:", cfg.JWTSecret)
@@ -44,19 +44,19 @@ func main() {
 	database.InitDB()
 	database.MigrateDB()
 
+	// Background jobs
+	services.StartScheduler(
+		services.Job{Name: "allowances", Interval: time.Hour, Run: services.NewAllowanceService(database.GetDB()).ProcessDue},
+		services.Job{Name: "token-cleanup", Interval: 6 * time.Hour, Run: services.NewTokenService(database.GetDB()).CleanupExpired},
+		services.Job{Name: "user-token-cleanup", Interval: 6 * time.Hour, Run: services.NewUserTokenService(database.GetDB(), nil).CleanupExpired},
+		services.Job{Name: "net-worth-snapshot", Interval: time.Hour, Run: services.NewNetWorthService(database.GetDB()).Snapshot},
+		services.Job{Name: "account-purge", Interval: time.Hour, Run: services.NewAccountDeletionService(database.GetDB(), services.MailerFromConfig()).PurgeDue},
+		services.Job{Name: "trash-purge", Interval: 24 * time.Hour, Run: services.NewTrashService(database.GetDB()).Purge},
+	)
+
 	// Setup router
 	router := routes.SetupRouter()
 
-	// Tambahin middleware CORS
-	router.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"http://localhost:5173"}, // alamat frontend
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true,
-		MaxAge:           12 * time.Hour,
-	}))
-
 	// Swagger endpoint
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
@@ -1,27 +1,44 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 const (
 	AccountTypeBank    = "Bank"
 	AccountTypeEWallet = "e-Wallet"
 	AccountTypeCash    = "Cash"
+	// Akun investasi (RDN / rekening efek): saldo adalah kas, unitnya dicatat lewat InvestmentLot
+	AccountTypeInvestment = "Investment"
 )
 
 var ValidAccountTypes = map[string]bool{
-	AccountTypeBank:    true,
-	AccountTypeEWallet: true,
-	AccountTypeCash:    true,
+	AccountTypeBank:       true,
+	AccountTypeEWallet:    true,
+	AccountTypeCash:       true,
+	AccountTypeInvestment: true,
 }
 
 type Account struct {
 	gorm.Model
 	MemberID uint    `gorm:"not null"`
 	Name     string  `gorm:"not null"`
-	Type     string  `gorm:"not null;check:type IN ('Bank','e-Wallet','Cash')"`
+	Type     string  `gorm:"not null;check:type IN ('Bank','e-Wallet','Cash','Investment')"`
 	Balance  float64 `gorm:"not null;default:0"`
 	Currency string  `gorm:"not null;default:'IDR'"`
 
+	// Akun yang diarsipkan disembunyikan dari daftar tapi tetap muncul di laporan
+	ArchivedAt *time.Time `gorm:"index"`
+
+	// Khusus akun Investment: Asset yang mencatat nilai pasar portofolionya, dikelola otomatis
+	PortfolioAssetID *uint `json:",omitempty"`
+
+	// Id batch hapus akun (cascade): akun dan semua baris yang ikut terhapus
+	// ditandai dengan nilai yang sama supaya bisa dipulihkan bersama dari trash
+	DeletionBatch string `gorm:"size:32;index" json:"-"`
+
 	// relasi
 	Member Member `json:"Member" gorm:"foreignKey:MemberID"`
 }
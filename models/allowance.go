@@ -0,0 +1,24 @@
+package models
+
+import "gorm.io/gorm"
+
+// Allowance adalah uang saku berkala yang otomatis ditransfer ke akun member.
+type Allowance struct {
+	gorm.Model
+	UserID        uint    `gorm:"not null"`
+	MemberID      uint    `gorm:"not null;index"` // member penerima
+	FromAccountID uint    `gorm:"not null"`
+	ToAccountID   uint    `gorm:"not null"`
+	Amount        float64 `gorm:"not null"`
+	Frequency     string  `gorm:"not null"` // "weekly" or "monthly"
+	StartDate     string  `gorm:"size:10"`  // acuan jadwal, supaya tanggal 31 tidak bergeser
+	NextRunDate   string  `gorm:"not null"`
+	LastRunDate   string
+	IsActive      bool `gorm:"default:true"`
+
+	DeletionBatch string `gorm:"size:32;index" json:"-"` // lihat Account.DeletionBatch
+
+	Member      Member  `json:",omitempty" gorm:"foreignKey:MemberID"`
+	FromAccount Account `json:",omitempty" gorm:"foreignKey:FromAccountID"`
+	ToAccount   Account `json:",omitempty" gorm:"foreignKey:ToAccountID"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// APIKey adalah kunci pribadi untuk script/integrasi. Kunci asli hanya ditampilkan
+// sekali saat dibuat; yang disimpan hanya hash dan prefix untuk identifikasi.
+type APIKey struct {
+	ID         uint `gorm:"primarykey"`
+	CreatedAt  time.Time
+	UserID     uint   `gorm:"not null;index" json:"-"`
+	Name       string `gorm:"not null;size:100"`
+	Prefix     string `gorm:"not null;size:16"`
+	KeyHash    string `gorm:"not null;uniqueIndex;size:64" json:"-"`
+	Scopes     string `gorm:"not null;size:500" json:"-"` // dipisah koma
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	LastUsedIP string `gorm:"size:45"`
+	RevokedAt  *time.Time
+
+	ScopeList []string `gorm:"-"`
+}
+
+// ScopeSlice mengembalikan Scopes sebagai slice.
+func (k *APIKey) ScopeSlice() []string {
+	if k.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(k.Scopes, ",")
+}
+
+// HasScope mengecek apakah key punya scope tertentu.
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.ScopeSlice() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package models
+
+import "gorm.io/gorm"
+
+const (
+	AssetKindAsset     = "asset"
+	AssetKindLiability = "liability"
+)
+
+// AssetCategories adalah kategori yang valid per jenis.
+var AssetCategories = map[string][]string{
+	AssetKindAsset:     {"property", "vehicle", "gold", "investment", "receivable", "other"},
+	AssetKindLiability: {"mortgage", "loan", "credit_card", "payable", "other"},
+}
+
+// Asset adalah harta non-kas (tanah, kendaraan, emas, ...) atau kewajiban
+// (KPR, pinjaman, ...) yang nilainya dicatat manual lewat AssetValuation.
+type Asset struct {
+	gorm.Model
+	UserID   uint   `gorm:"not null;index"`
+	MemberID *uint  `gorm:"index"`            // pemilik, kosong = milik bersama
+	Kind     string `gorm:"not null;size:16"` // "asset" or "liability"
+	Category string `gorm:"not null;size:32"`
+	Name     string `gorm:"not null"`
+	Currency string `gorm:"not null;default:'IDR'"`
+
+	// Jumlah fisik opsional, contoh 25 (gram emas) atau 300 (m2 tanah)
+	Quantity float64
+	Unit     string `gorm:"size:16"`
+	Notes    string
+
+	// Valuasi terakhir, diturunkan dari AssetValuation, jangan diubah manual
+	CurrentValue float64 `gorm:"not null;default:0"`
+	ValuedAt     string  `gorm:"size:10"` // YYYY-MM-DD
+
+	// Tanggal dijual / lunas; sejak tanggal ini tidak dihitung di net worth
+	ClosedAt *string `gorm:"size:10"`
+
+	DeletionBatch string `gorm:"size:32;index" json:"-"` // lihat Account.DeletionBatch
+
+	Member *Member `json:",omitempty" gorm:"foreignKey:MemberID"`
+}
+
+// AssetValuation adalah nilai satu aset/kewajiban pada satu tanggal.
+type AssetValuation struct {
+	gorm.Model
+	UserID  uint    `gorm:"not null;index"`
+	AssetID uint    `gorm:"not null;index"`
+	Date    string  `gorm:"not null;size:10"` // YYYY-MM-DD
+	Value   float64 `gorm:"not null"`
+	Note    string
+
+	Asset Asset `json:"-" gorm:"foreignKey:AssetID"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+)
+
+// AuditLog adalah catatan append-only setiap perubahan data keuangan.
+// Before/After berisi snapshot kolom (JSON), Changes hanya kolom yang berubah.
+type AuditLog struct {
+	ID            uint      `gorm:"primarykey"`
+	CreatedAt     time.Time `gorm:"index"`
+	LedgerUserID  uint      `gorm:"index" json:"-"` // user_id pemilik data (pemilik workspace)
+	ActorUserID   *uint     `gorm:"index"`
+	ActorAPIKeyID *uint     `json:",omitempty"`
+	IPAddress     string    `gorm:"size:45"`
+	Action        string    `gorm:"not null;size:16"`
+	Entity        string    `gorm:"not null;size:50;index:idx_audit_entity"`
+	EntityID      uint      `gorm:"not null;index:idx_audit_entity"`
+	Before        JSONText  `gorm:"type:text" json:",omitempty"`
+	After         JSONText  `gorm:"type:text" json:",omitempty"`
+	Changes       JSONText  `gorm:"type:text" json:",omitempty"`
+}
+
+// JSONText adalah JSON yang disimpan sebagai teks tapi dikirim ke client apa adanya.
+type JSONText string
+
+func (j JSONText) MarshalJSON() ([]byte, error) {
+	if j == "" {
+		return []byte("null"), nil
+	}
+	return []byte(j), nil
+}
@@ -1,10 +1,17 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Category struct {
 	gorm.Model
-	UserID uint   `gorm:"not null"`
-	Name   string `gorm:"not null"`
-	Type   string `gorm:"not null"` // "income" or "expense"
+	UserID   uint   `gorm:"not null"`
+	ParentID *uint  `gorm:"index"` // nil = kategori utama
+	Name     string `gorm:"not null"`
+	Type     string `gorm:"not null"` // "income" or "expense"
+
+	ArchivedAt *time.Time `gorm:"index"`
 }
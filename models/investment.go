@@ -0,0 +1,76 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	LotSideBuy  = "buy"
+	LotSideSell = "sell"
+
+	CostMethodFIFO    = "fifo"
+	CostMethodAverage = "average"
+)
+
+// SecurityKinds adalah jenis instrumen investasi yang didukung.
+var SecurityKinds = []string{"mutual_fund", "stock", "crypto", "bond", "other"}
+
+// Security adalah instrumen investasi (reksa dana, saham, kripto, ...) milik ledger.
+type Security struct {
+	gorm.Model
+	UserID   uint   `gorm:"not null;uniqueIndex:idx_security_user_symbol"`
+	Symbol   string `gorm:"not null;size:32;uniqueIndex:idx_security_user_symbol"` // contoh BBCA, BTC, SUCORINVEST-MM
+	Name     string
+	Kind     string `gorm:"not null;size:16"`
+	Currency string `gorm:"not null;default:'IDR'"`
+
+	// Harga terakhir, diturunkan dari SecurityPrice, jangan diubah manual
+	LastPrice     float64 `gorm:"not null;default:0"`
+	LastPriceDate string  `gorm:"size:10"`
+}
+
+// SecurityPrice adalah harga penutupan / NAB satu instrumen pada satu tanggal.
+type SecurityPrice struct {
+	ID         uint `gorm:"primarykey"`
+	CreatedAt  time.Time
+	UserID     uint    `gorm:"not null;index"`
+	SecurityID uint    `gorm:"not null;uniqueIndex:idx_security_price_date"`
+	Date       string  `gorm:"not null;size:10;uniqueIndex:idx_security_price_date"` // YYYY-MM-DD
+	Price      float64 `gorm:"not null"`
+}
+
+// InvestmentLot adalah satu pembelian atau penjualan di akun investasi. Nilai
+// lot (quantity x price, ditambah / dikurangi fee) memindahkan saldo kas akun.
+type InvestmentLot struct {
+	gorm.Model
+	UserID     uint    `gorm:"not null;index"`
+	AccountID  uint    `gorm:"not null;index"`
+	SecurityID uint    `gorm:"not null;index"`
+	Side       string  `gorm:"not null;size:4"` // "buy" or "sell"
+	Date       string  `gorm:"not null;size:10"`
+	Quantity   float64 `gorm:"not null"` // unit / lembar / koin
+	Price      float64 `gorm:"not null"` // harga per unit
+	Fee        float64 `gorm:"not null;default:0"`
+	Notes      string
+
+	DeletionBatch string `gorm:"size:32;index" json:"-"` // lihat Account.DeletionBatch
+
+	Security Security `json:",omitempty" gorm:"foreignKey:SecurityID"`
+}
+
+// InvestmentDividend menautkan transaksi pemasukan dividen / kupon ke instrumennya.
+type InvestmentDividend struct {
+	gorm.Model
+	UserID        uint    `gorm:"not null;index"`
+	AccountID     uint    `gorm:"not null;index"`
+	SecurityID    uint    `gorm:"not null;index"`
+	TransactionID uint    `gorm:"not null;uniqueIndex"`
+	Date          string  `gorm:"not null;size:10"`
+	Amount        float64 `gorm:"not null"`
+
+	DeletionBatch string `gorm:"size:32;index" json:"-"` // lihat Account.DeletionBatch
+
+	Security Security `json:",omitempty" gorm:"foreignKey:SecurityID"`
+}
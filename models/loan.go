@@ -0,0 +1,73 @@
+package models
+
+import "gorm.io/gorm"
+
+const (
+	LoanMethodAnnuity = "annuity" // cicilan tetap, bunga menurun mengikuti sisa pokok
+	LoanMethodFlat    = "flat"    // bunga flat dari pokok awal
+
+	LoanStatusActive  = "active"
+	LoanStatusPaidOff = "paid_off"
+
+	InstallmentStatusPaid     = "paid"
+	InstallmentStatusOverdue  = "overdue"
+	InstallmentStatusDue      = "due" // jatuh tempo hari ini
+	InstallmentStatusUpcoming = "upcoming"
+)
+
+// Loan adalah pinjaman / cicilan dengan jadwal angsuran tetap. Sisa pokoknya
+// ikut dihitung di net worth lewat Asset kewajiban yang ditautkan (AssetID).
+type Loan struct {
+	gorm.Model
+	UserID      uint    `gorm:"not null;index"`
+	MemberID    *uint   `gorm:"index"`
+	AccountID   *uint   // akun default untuk bayar cicilan
+	CategoryID  *uint   // kategori default transaksi cicilan
+	AssetID     *uint   // kewajiban di net worth, dikelola otomatis
+	Name        string  `gorm:"not null"`
+	Lender      string  // bank / leasing / toko
+	Category    string  `gorm:"not null;size:32;default:'loan'"` // kategori kewajiban: loan, mortgage, credit_card, ...
+	Principal   float64 `gorm:"not null"`
+	AnnualRate  float64 `gorm:"not null;default:0"` // persen per tahun, contoh 9.5
+	TenorMonths int     `gorm:"not null"`
+	StartDate   string  `gorm:"not null;size:10"` // tanggal pencairan, angsuran pertama sebulan setelahnya
+	Method      string  `gorm:"not null;size:16;default:'annuity'"`
+
+	// Diturunkan dari angsuran yang sudah dibayar, jangan diubah manual
+	OutstandingPrincipal float64 `gorm:"not null;default:0"`
+	Status               string  `gorm:"not null;size:16;default:'active'"`
+
+	Installments []LoanInstallment `json:",omitempty" gorm:"foreignKey:LoanID"`
+
+	// Ringkasan untuk response, tidak disimpan
+	OverdueCount int     `gorm:"-"`
+	NextDueDate  string  `gorm:"-" json:",omitempty"`
+	NextDueTotal float64 `gorm:"-" json:",omitempty"`
+}
+
+// LoanInstallment adalah satu baris jadwal angsuran.
+type LoanInstallment struct {
+	gorm.Model
+	UserID       uint    `gorm:"not null;index"`
+	LoanID       uint    `gorm:"not null;index"`
+	Number       int     `gorm:"not null"`
+	DueDate      string  `gorm:"not null;size:10;index"`
+	Principal    float64 `gorm:"not null"`
+	Interest     float64 `gorm:"not null"`
+	Total        float64 `gorm:"not null"`
+	BalanceAfter float64 `gorm:"not null"` // sisa pokok terjadwal setelah angsuran ini
+
+	// Pembayaran: tautan ke transaksi (pengeluaran) atau transfer
+	PaidDate      *string `gorm:"size:10"`
+	PaidAmount    float64
+	TransactionID *uint `gorm:"index"`
+	TransferID    *uint `gorm:"index"`
+
+	// Pembayaran yang dilepas karena transaksi / transfernya ikut terhapus bersama
+	// akun (cascade); ditautkan lagi kalau akun dipulihkan dari trash
+	DeletionBatch         string `gorm:"size:32;index" json:"-"`
+	ReleasedTransactionID *uint  `json:"-"`
+	ReleasedTransferID    *uint  `json:"-"`
+
+	Status string `gorm:"-"`
+}
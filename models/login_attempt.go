@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// LoginAttempt mencatat login yang gagal atau diblokir, untuk audit.
+type LoginAttempt struct {
+	ID        uint      `gorm:"primarykey"`
+	CreatedAt time.Time `gorm:"index"`
+	Identity  string    `gorm:"index;size:255"` // "email:..." atau "user:<id>" (langkah 2FA)
+	IPAddress string    `gorm:"index;size:45"`
+	UserAgent string    `gorm:"size:255"`
+	Route     string    `gorm:"size:100"`
+	Reason    string    `gorm:"size:32"` // invalid_credentials | locked_out
+}
@@ -7,4 +7,11 @@ type Member struct {
 	UserID   uint      `gorm:"not null"`
 	Name     string    `gorm:"not null"`
 	Accounts []Account `gorm:"foreignKey:MemberID"`
+
+	// User login yang ditautkan ke member ini (opsional)
+	LoginUserID *uint `gorm:"index"`
+
+	// Batas pengeluaran per periode, 0 = tanpa batas
+	SpendingCap       float64 `gorm:"not null;default:0"`
+	SpendingCapPeriod string  `gorm:"not null;default:'monthly'"` // "weekly" or "monthly"
 }
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// AccountBalanceSnapshot adalah saldo satu akun pada satu tanggal. Job snapshot
+// menimpa baris hari yang sama, jadi nilainya adalah saldo terakhir hari itu.
+type AccountBalanceSnapshot struct {
+	ID          uint `gorm:"primarykey"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uint    `gorm:"not null;index:idx_balance_snapshot_user_date"` // pemilik ledger
+	AccountID   uint    `gorm:"not null;uniqueIndex:idx_balance_snapshot_account_date"`
+	MemberID    uint    `gorm:"not null"`
+	AccountType string  `gorm:"not null;size:20"`
+	Currency    string  `gorm:"not null;size:10"`
+	Date        string  `gorm:"not null;size:10;uniqueIndex:idx_balance_snapshot_account_date;index:idx_balance_snapshot_user_date"` // YYYY-MM-DD
+	Balance     float64 `gorm:"not null"`
+}
+
+// AssetValueSnapshot adalah nilai satu aset / kewajiban pada satu tanggal, disalin
+// dari CurrentValue oleh job snapshot. Riwayat net worth dibaca dari sini, jadi
+// valuasi yang diubah atau aset yang dihapus belakangan tidak menulis ulang masa lalu.
+type AssetValueSnapshot struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uint `gorm:"not null;index:idx_asset_snapshot_user_date"`
+	AssetID   uint `gorm:"not null;uniqueIndex:idx_asset_snapshot_asset_date"`
+	MemberID  *uint
+	Kind      string  `gorm:"not null;size:16"`
+	Category  string  `gorm:"not null;size:32"`
+	Date      string  `gorm:"not null;size:10;uniqueIndex:idx_asset_snapshot_asset_date;index:idx_asset_snapshot_user_date"`
+	Value     float64 `gorm:"not null"`
+}
+
+// NetWorthSnapshot adalah total kekayaan bersih satu ledger pada satu tanggal.
+type NetWorthSnapshot struct {
+	ID           uint `gorm:"primarykey"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	UserID       uint    `gorm:"not null;uniqueIndex:idx_net_worth_user_date"`
+	Date         string  `gorm:"not null;size:10;uniqueIndex:idx_net_worth_user_date"` // YYYY-MM-DD
+	Total        float64 `gorm:"not null"`                                             // accounts + assets - liabilities
+	AccountCount int     `gorm:"not null"`
+
+	AccountsTotal    float64 `gorm:"not null;default:0"`
+	AssetsTotal      float64 `gorm:"not null;default:0"`
+	LiabilitiesTotal float64 `gorm:"not null;default:0"`
+}
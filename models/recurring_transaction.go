@@ -15,4 +15,6 @@ type RecurringTransaction struct {
 	StartDate   string `gorm:"not null"`
 	EndDate     string
 	IsActive    bool `gorm:"default:true"`
+
+	DeletionBatch string `gorm:"size:32;index" json:"-"` // lihat Account.DeletionBatch
 }
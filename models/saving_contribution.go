@@ -0,0 +1,31 @@
+package models
+
+import "gorm.io/gorm"
+
+const (
+	SavingContributionDeposit    = "contribution"
+	SavingContributionWithdrawal = "withdrawal"
+)
+
+type SavingContribution struct {
+	gorm.Model
+	UserID         uint    `gorm:"not null"`
+	SavingTargetID uint    `gorm:"not null;index"`
+	Type           string  `gorm:"not null"` // "contribution" or "withdrawal"
+	Amount         float64 `gorm:"not null"`
+	Date           string  `gorm:"not null"`
+	Description    string
+
+	// Akun lawan: kalau diisi, dana dipindah lewat Transfer ke/dari akun target.
+	// Kalau kosong, kontribusi hanya earmark saldo yang sudah ada di akun target.
+	CounterAccountID *uint
+	TransferID       *uint
+
+	// Terisi kalau kontribusi dibuat otomatis oleh SavingRule dari sebuah transaksi
+	SavingRuleID  *uint
+	TransactionID *uint
+
+	DeletionBatch string `gorm:"size:32;index" json:"-"` // lihat Account.DeletionBatch
+
+	SavingTarget SavingTarget `json:"-" gorm:"foreignKey:SavingTargetID"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import "gorm.io/gorm"
+
+const (
+	SavingRuleRoundUp    = "round_up"
+	SavingRulePercentage = "percentage"
+)
+
+// SavingRule otomatis membuat SavingContribution saat transaksi yang cocok dibuat.
+type SavingRule struct {
+	gorm.Model
+	UserID          uint    `gorm:"not null"`
+	SavingTargetID  uint    `gorm:"not null;index"`
+	Type            string  `gorm:"not null"` // "round_up" or "percentage"
+	TransactionType string  `gorm:"not null"` // "income" or "expense"
+	CategoryID      *uint   // kosong = semua kategori
+	RoundTo         float64 // untuk round_up, contoh 10000
+	Percentage      float64 // untuk percentage, contoh 10 = 10%
+	IsActive        bool    `gorm:"default:true"`
+
+	DeletionBatch string `gorm:"size:32;index" json:"-"` // lihat Account.DeletionBatch
+
+	SavingTarget SavingTarget `json:"-" gorm:"foreignKey:SavingTargetID"`
+}
+
+// Amount menghitung nominal yang disisihkan dari sebuah transaksi.
+func (r SavingRule) Amount(trxAmount float64) float64 {
+	switch r.Type {
+	case SavingRuleRoundUp:
+		if r.RoundTo <= 0 {
+			return 0
+		}
+		steps := int64(trxAmount / r.RoundTo)
+		if float64(steps)*r.RoundTo < trxAmount {
+			steps++
+		}
+		return float64(steps)*r.RoundTo - trxAmount
+	case SavingRulePercentage:
+		return trxAmount * r.Percentage / 100
+	}
+	return 0
+}
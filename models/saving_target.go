@@ -4,12 +4,17 @@ import "gorm.io/gorm"
 
 type SavingTarget struct {
 	gorm.Model
-	UserID        uint    `gorm:"not null"`
-	MemberID      uint    `gorm:"not null"`
-	AccountID     uint    `gorm:"not null"`
-	Name          string  `gorm:"not null"`
-	TargetAmount  float64 `gorm:"not null"`
+	UserID       uint    `gorm:"not null"`
+	MemberID     uint    `gorm:"not null"`
+	AccountID    uint    `gorm:"not null"`
+	Name         string  `gorm:"not null"`
+	TargetAmount float64 `gorm:"not null"`
+	// CurrentAmount diturunkan dari ledger SavingContribution, jangan diubah manual.
 	CurrentAmount float64 `gorm:"not null;default:0"`
 	TargetDate    string  `gorm:"not null"`
 	Description   string
+
+	DeletionBatch string `gorm:"size:32;index" json:"-"` // lihat Account.DeletionBatch
+
+	Contributions []SavingContribution `json:",omitempty" gorm:"foreignKey:SavingTargetID"`
 }
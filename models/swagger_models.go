@@ -5,6 +5,7 @@ type AuthRegisterRequest struct {
 	Username string `json:"username" example:"example"`
 	Email    string `json:"email" example:"example@mail.com"`
 	Password string `json:"password" example:"123456"`
+	Locale   string `json:"locale" example:"id"`
 }
 
 // AuthLoginRequest digunakan untuk request login
@@ -15,8 +16,145 @@ type AuthLoginRequest struct {
 
 // AuthResponse digunakan untuk response login/register
 type AuthResponse struct {
-	Token string       `json:"token"`
-	User  UserResponse `json:"user"`
+	Token        string       `json:"token"`
+	RefreshToken string       `json:"refresh_token"`
+	ExpiresIn    int64        `json:"expires_in" example:"900"`
+	User         UserResponse `json:"user"`
+}
+
+// AuthRefreshRequest digunakan untuk request refresh token
+type AuthRefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// ForgotPasswordRequest digunakan untuk request lupa password
+type ForgotPasswordRequest struct {
+	Email string `json:"email" example:"example@mail.com"`
+}
+
+// ResetPasswordRequest digunakan untuk reset password dengan token dari email
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password" example:"newpassword"`
+}
+
+// VerifyEmailRequest digunakan untuk verifikasi email
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// AuthTwoFactorRequest digunakan untuk langkah kedua login 2FA
+type AuthTwoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code" example:"123456"`
+	Device         string `json:"device" example:"Pixel 7"`
+}
+
+// TwoFactorCodeRequest digunakan untuk konfirmasi 2FA / generate ulang recovery code
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" example:"123456"`
+}
+
+// TwoFactorDisableRequest digunakan untuk mematikan 2FA
+type TwoFactorDisableRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code" example:"123456"`
+}
+
+// AccountDeleteRequest digunakan untuk konfirmasi hapus akun
+type AccountDeleteRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code" example:"123456"` // wajib kalau 2FA aktif
+}
+
+// AssetRequest digunakan untuk membuat aset atau kewajiban
+type AssetRequest struct {
+	MemberID  *uint   `json:"member_id"`
+	Kind      string  `json:"kind" example:"asset"`
+	Category  string  `json:"category" example:"gold"`
+	Name      string  `json:"name" example:"Emas Antam"`
+	Currency  string  `json:"currency" example:"IDR"`
+	Quantity  float64 `json:"quantity" example:"25"`
+	Unit      string  `json:"unit" example:"gram"`
+	Notes     string  `json:"notes"`
+	Value     float64 `json:"value" example:"30000000"`
+	ValueDate string  `json:"value_date" example:"2024-05-01"`
+}
+
+// AssetValuationRequest digunakan untuk mencatat nilai aset
+type AssetValuationRequest struct {
+	Date  string  `json:"date" example:"2024-06-01"`
+	Value float64 `json:"value" example:"31500000"`
+	Note  string  `json:"note"`
+}
+
+// LoanRequest digunakan untuk membuat pinjaman / cicilan
+type LoanRequest struct {
+	MemberID    *uint   `json:"member_id"`
+	AccountID   *uint   `json:"account_id" example:"1"`
+	CategoryID  *uint   `json:"category_id" example:"7"`
+	Name        string  `json:"name" example:"Cicilan Motor"`
+	Lender      string  `json:"lender" example:"Adira Finance"`
+	Category    string  `json:"category" example:"loan"`
+	Principal   float64 `json:"principal" example:"24000000"`
+	AnnualRate  float64 `json:"annual_rate" example:"9.5"`
+	TenorMonths int     `json:"tenor_months" example:"24"`
+	StartDate   string  `json:"start_date" example:"2024-05-10"`
+	Method      string  `json:"method" example:"annuity"`
+}
+
+// LoanPaymentRequest digunakan untuk membayar satu angsuran
+type LoanPaymentRequest struct {
+	TransactionID *uint   `json:"transaction_id"`
+	TransferID    *uint   `json:"transfer_id"`
+	AccountID     *uint   `json:"account_id" example:"1"`
+	CategoryID    *uint   `json:"category_id" example:"7"`
+	Date          string  `json:"date" example:"2024-06-10"`
+	Amount        float64 `json:"amount" example:"1102000"`
+	Description   string  `json:"description"`
+}
+
+// SecurityRequest digunakan untuk membuat instrumen investasi
+type SecurityRequest struct {
+	Symbol   string `json:"symbol" example:"BBCA"`
+	Name     string `json:"name" example:"Bank Central Asia"`
+	Kind     string `json:"kind" example:"stock"`
+	Currency string `json:"currency" example:"IDR"`
+}
+
+// SecurityPriceRequest digunakan untuk mencatat harga instrumen
+type SecurityPriceRequest struct {
+	Date  string  `json:"date" example:"2024-06-03"`
+	Price float64 `json:"price" example:"9350"`
+}
+
+// InvestmentLotRequest digunakan untuk mencatat pembelian / penjualan
+type InvestmentLotRequest struct {
+	AccountID  uint    `json:"account_id" example:"4"`
+	SecurityID uint    `json:"security_id" example:"1"`
+	Side       string  `json:"side" example:"buy"`
+	Date       string  `json:"date" example:"2024-06-03"`
+	Quantity   float64 `json:"quantity" example:"500"`
+	Price      float64 `json:"price" example:"9350"`
+	Fee        float64 `json:"fee" example:"7013"`
+	Notes      string  `json:"notes"`
+}
+
+// InvestmentDividendRequest digunakan untuk mencatat dividen
+type InvestmentDividendRequest struct {
+	AccountID   uint    `json:"account_id" example:"4"`
+	SecurityID  uint    `json:"security_id" example:"1"`
+	CategoryID  uint    `json:"category_id" example:"3"`
+	Date        string  `json:"date" example:"2024-07-10"`
+	Amount      float64 `json:"amount" example:"135000"`
+	Description string  `json:"description"`
+}
+
+// APIKeyCreateRequest digunakan untuk membuat API key
+type APIKeyCreateRequest struct {
+	Name      string   `json:"name" example:"Import script"`
+	Scopes    []string `json:"scopes" example:"transactions:read,transactions:write"`
+	ExpiresAt string   `json:"expires_at" example:"2026-12-31"`
 }
 
 // UserResponse digunakan untuk response user
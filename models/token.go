@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+const (
+	RevokedTokenJTI    = "jti"    // satu access token
+	RevokedTokenFamily = "family" // seluruh access token dari satu sesi refresh
+)
+
+// RefreshToken disimpan sebagai hash. Setiap refresh merotasi token dalam FamilyID
+// yang sama; token lama yang dipakai ulang akan mencabut seluruh family.
+type RefreshToken struct {
+	ID         uint `gorm:"primarykey"`
+	CreatedAt  time.Time
+	UserID     uint      `gorm:"not null;index"`
+	FamilyID   string    `gorm:"not null;index;size:64"`
+	TokenHash  string    `gorm:"not null;uniqueIndex;size:64"`
+	ExpiresAt  time.Time `gorm:"not null"`
+	RevokedAt  *time.Time
+	ReplacedAt *time.Time
+}
+
+// RevokedToken adalah daftar pencabutan yang dicek oleh JWTAuthMiddleware.
+type RevokedToken struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	Kind      string    `gorm:"not null;size:16"`
+	Value     string    `gorm:"not null;uniqueIndex;size:64"`
+	UserID    uint      `gorm:"not null;index"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
+
+// Session mewakili satu login (satu family refresh token) di sebuah perangkat.
+type Session struct {
+	ID         uint `gorm:"primarykey"`
+	CreatedAt  time.Time
+	UserID     uint   `gorm:"not null;index" json:"-"`
+	FamilyID   string `gorm:"not null;uniqueIndex;size:64" json:"-"`
+	Device     string `gorm:"size:100"`
+	IPAddress  string `gorm:"size:45"`
+	UserAgent  string `gorm:"size:255"`
+	LastSeenAt time.Time
+	ExpiresAt  time.Time  `gorm:"not null;index"`
+	RevokedAt  *time.Time `json:"-"`
+
+	Current bool `gorm:"-"`
+}
+
+const (
+	UserTokenPasswordReset     = "password_reset"
+	UserTokenEmailVerification = "email_verification"
+)
+
+// UserToken adalah token sekali pakai yang dikirim lewat email
+// (reset password / verifikasi email). Hanya hash-nya yang disimpan.
+type UserToken struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UserID    uint      `gorm:"not null;index"`
+	Purpose   string    `gorm:"not null;size:32"`
+	Email     string    `gorm:"not null"` // alamat tujuan saat token dibuat
+	TokenHash string    `gorm:"not null;uniqueIndex;size:64"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+	UsedAt    *time.Time
+}
@@ -13,6 +13,8 @@ type Transaction struct {
 	Description string
 	Type        string `gorm:"not null"` // "income" or "expense"
 
+	DeletionBatch string `gorm:"size:32;index" json:"-"` // lihat Account.DeletionBatch
+
 	// Relasi
 	Member   Member   `gorm:"foreignKey:MemberID"`
 	Account  Account  `gorm:"foreignKey:AccountID"`
@@ -13,6 +13,8 @@ type Transfer struct {
 	Description   string
 	Fee           float64
 
+	DeletionBatch string `gorm:"size:32;index" json:"-"` // lihat Account.DeletionBatch
+
 	Member      Member  `gorm:"foreignKey:MemberID"`
 	FromAccount Account `gorm:"foreignKey:FromAccountID"`
 	ToAccount   Account `gorm:"foreignKey:ToAccountID"`
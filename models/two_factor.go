@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// RecoveryCode adalah kode cadangan 2FA sekali pakai (disimpan sebagai hash).
+type RecoveryCode struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UserID    uint   `gorm:"not null;index"`
+	CodeHash  string `gorm:"not null;size:64"`
+	UsedAt    *time.Time
+}
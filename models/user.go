@@ -1,11 +1,25 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type User struct {
 	gorm.Model
-	Username string   `gorm:"unique;not null"`
-	Email    string   `gorm:"unique;not null"`
-	Password string   `gorm:"not null"`
-	Members  []Member `gorm:"foreignKey:UserID"`
+	Username        string `gorm:"unique;not null"`
+	Email           string `gorm:"unique;not null"`
+	Password        string `gorm:"not null"`
+	EmailVerifiedAt *time.Time
+
+	// 2FA TOTP; secret tidak pernah dikirim ke client
+	TwoFactorEnabled bool   `gorm:"default:false"`
+	TOTPSecret       string `gorm:"size:64" json:"-"`
+	TOTPLastStep     int64  `json:"-"` // time step terakhir yang dipakai, cegah replay kode
+
+	// Terisi kalau user minta hapus akun dan masih dalam masa tenggang
+	DeletionScheduledAt *time.Time
+
+	Members []Member `gorm:"foreignKey:UserID"`
 }
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	WorkspaceRoleOwner  = "owner"
+	WorkspaceRoleEditor = "editor"
+	WorkspaceRoleViewer = "viewer"
+	WorkspaceRoleMember = "member" // hanya data member yang ditautkan
+)
+
+var ValidWorkspaceRoles = map[string]bool{
+	WorkspaceRoleOwner:  true,
+	WorkspaceRoleEditor: true,
+	WorkspaceRoleViewer: true,
+	WorkspaceRoleMember: true,
+}
+
+// Workspace adalah satu buku keuangan rumah tangga. Semua data ledger
+// (member, akun, transaksi, dst) tetap disimpan dengan user_id = OwnerID,
+// sehingga setiap user memiliki paling banyak satu workspace.
+type Workspace struct {
+	gorm.Model
+	Name    string `gorm:"not null"`
+	OwnerID uint   `gorm:"not null;uniqueIndex"`
+
+	Memberships []WorkspaceMembership `json:",omitempty" gorm:"foreignKey:WorkspaceID"`
+}
+
+type WorkspaceMembership struct {
+	gorm.Model
+	WorkspaceID uint   `gorm:"not null;uniqueIndex:idx_workspace_user"`
+	UserID      uint   `gorm:"not null;uniqueIndex:idx_workspace_user"`
+	Role        string `gorm:"not null"`
+	MemberID    *uint  // member ledger yang ditautkan ke user login ini
+
+	Workspace Workspace `json:",omitempty" gorm:"foreignKey:WorkspaceID"`
+	User      User      `json:"-" gorm:"foreignKey:UserID"`
+}
+
+type WorkspaceInvitation struct {
+	gorm.Model
+	WorkspaceID uint   `gorm:"not null;index"`
+	InvitedByID uint   `gorm:"not null"`
+	Email       string `gorm:"not null"`
+	Role        string `gorm:"not null"`
+	MemberID    *uint
+	TokenHash   string    `json:"-" gorm:"not null;uniqueIndex;size:64"`
+	ExpiresAt   time.Time `gorm:"not null"`
+	AcceptedAt  *time.Time
+}
@@ -2,6 +2,7 @@ package routes
 
 import (
 	"finance-app/controllers"
+	"finance-app/models"
 	"finance-app/utils"
 	"time"
 
@@ -16,7 +17,7 @@ func SetupRouter() *gin.Engine {
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:5173"}, // frontend dev
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", utils.WorkspaceHeader, utils.APIKeyHeader},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
@@ -28,25 +29,62 @@ func SetupRouter() *gin.Engine {
 		// Public routes
 		// =========================
 		api.POST("/register", controllers.Register)
-		api.POST("/login", controllers.Login)
-		api.POST("/logout", utils.JWTAuthMiddleware(), controllers.Logout)
+		loginGuard := utils.DefaultBruteForceGuard
+		api.POST("/login", loginGuard.Middleware(utils.LoginEmailIdentity), controllers.Login)
+		api.POST("/login/2fa", loginGuard.Middleware(utils.ChallengeIdentity), controllers.LoginTwoFactor)
+		api.POST("/logout", utils.JWTAuthMiddleware(), utils.JWTOnly(), controllers.Logout)
+		api.POST("/token/refresh", controllers.RefreshToken)
+		api.POST("/password/forgot", controllers.ForgotPassword)
+		api.POST("/password/reset", controllers.ResetPassword)
+		api.POST("/email/verify", controllers.VerifyEmail)
 
 		// =========================
 		// Authenticated routes
 		// =========================
 		auth := api.Group("")
-		auth.Use(utils.JWTAuthMiddleware())
+		auth.Use(utils.JWTAuthMiddleware(), utils.WorkspaceMiddleware())
 		{
 			// ========== User ==========
-			user := auth.Group("/user")
+			user := auth.Group("/user", utils.JWTOnly())
 			{
 				user.GET("", controllers.GetCurrentUser)
 				user.PUT("", controllers.UpdateUser)
 				user.DELETE("", controllers.DeleteUser)
+				user.POST("/deletion/cancel", controllers.CancelAccountDeletion)
+				user.POST("/email/verification", controllers.ResendEmailVerification)
+				user.GET("/sessions", controllers.GetSessions)
+				user.DELETE("/sessions/:id", controllers.RevokeSession)
+				user.GET("/2fa", controllers.GetTwoFactorStatus)
+				user.POST("/2fa/setup", controllers.SetupTwoFactor)
+				user.POST("/2fa/confirm", controllers.ConfirmTwoFactor)
+				user.POST("/2fa/disable", controllers.DisableTwoFactor)
+				user.POST("/2fa/recovery-codes", controllers.RegenerateRecoveryCodes)
+				user.GET("/api-keys", controllers.GetAPIKeys)
+				user.POST("/api-keys", controllers.CreateAPIKey)
+				user.DELETE("/api-keys/:id", controllers.RevokeAPIKey)
+				user.GET("/export", controllers.ExportUserData)
+				user.POST("/import", controllers.ImportUserData)
+			}
+
+			// ========== Workspace (household) ==========
+			auth.GET("/workspaces", utils.JWTOnly(), controllers.GetMyWorkspaces)
+			auth.POST("/invitations/accept", utils.JWTOnly(), controllers.AcceptWorkspaceInvitation)
+			workspace := auth.Group("/workspace", utils.JWTOnly())
+			{
+				ownerOnly := utils.RequireWorkspaceRole(models.WorkspaceRoleOwner)
+
+				workspace.GET("", controllers.GetCurrentWorkspace)
+				workspace.PUT("", ownerOnly, controllers.UpdateWorkspace)
+				workspace.GET("/memberships", controllers.GetWorkspaceMemberships)
+				workspace.PUT("/memberships/:id", ownerOnly, controllers.UpdateWorkspaceMembership)
+				workspace.DELETE("/memberships/:id", controllers.DeleteWorkspaceMembership)
+				workspace.GET("/invitations", ownerOnly, controllers.GetWorkspaceInvitations)
+				workspace.POST("/invitations", ownerOnly, controllers.CreateWorkspaceInvitation)
+				workspace.DELETE("/invitations/:id", ownerOnly, controllers.DeleteWorkspaceInvitation)
 			}
 
 			// ========== Members ==========
-			members := auth.Group("/members")
+			members := auth.Group("/members", utils.RequireScope("members"), utils.WorkspaceWriteGuard(), utils.RestrictMemberWrites())
 			{
 				members.GET("", controllers.GetMembers)
 				members.POST("", controllers.CreateMember)
@@ -56,7 +94,7 @@ func SetupRouter() *gin.Engine {
 			}
 
 			// ========== Accounts ==========
-			accounts := auth.Group("/accounts")
+			accounts := auth.Group("/accounts", utils.RequireScope("accounts"), utils.WorkspaceWriteGuard(), utils.RestrictMemberWrites())
 			{
 				accounts.GET("", controllers.GetAccounts)
 				accounts.POST("", controllers.CreateAccount)
@@ -64,20 +102,26 @@ func SetupRouter() *gin.Engine {
 				accounts.GET("/:id", controllers.GetAccountByID)
 				accounts.PUT("/:id", controllers.UpdateAccount)
 				accounts.DELETE("/:id", controllers.DeleteAccount)
+				accounts.POST("/:id/archive", controllers.ArchiveAccount)
+				accounts.POST("/:id/unarchive", controllers.UnarchiveAccount)
 			}
 
 			// ========== Categories ==========
-			categories := auth.Group("/categories")
+			categories := auth.Group("/categories", utils.RequireScope("categories"), utils.WorkspaceWriteGuard(), utils.RestrictMemberWrites())
 			{
 				categories.GET("", controllers.GetCategories)
 				categories.POST("", controllers.CreateCategory)
+				categories.GET("/tree", controllers.GetCategoryTree)
 				categories.GET("/:id", controllers.GetCategoryByID)
 				categories.PUT("/:id", controllers.UpdateCategory)
 				categories.DELETE("/:id", controllers.DeleteCategory)
+				categories.POST("/:id/merge", controllers.MergeCategory)
+				categories.POST("/:id/archive", controllers.ArchiveCategory)
+				categories.POST("/:id/unarchive", controllers.UnarchiveCategory)
 			}
 
 			// ========== Budgets ==========
-			budgets := auth.Group("/budgets")
+			budgets := auth.Group("/budgets", utils.RequireScope("budgets"), utils.WorkspaceWriteGuard(), utils.RestrictMemberWrites())
 			{
 				budgets.GET("", controllers.GetBudgets)
 				budgets.POST("", controllers.CreateBudget)
@@ -87,7 +131,7 @@ func SetupRouter() *gin.Engine {
 			}
 
 			// ========== Transactions ==========
-			transactions := auth.Group("/transactions")
+			transactions := auth.Group("/transactions", utils.RequireScope("transactions"), utils.WorkspaceWriteGuard())
 			{
 				transactions.GET("", controllers.GetTransactions)
 				transactions.GET("/:id", controllers.GetTransactionByID)
@@ -97,7 +141,7 @@ func SetupRouter() *gin.Engine {
 			}
 
 			// ========== Recurring Transactions ==========
-			recurring := auth.Group("/recurring-transactions")
+			recurring := auth.Group("/recurring-transactions", utils.RequireScope("recurring"), utils.WorkspaceWriteGuard(), utils.RestrictMemberWrites())
 			{
 				recurring.GET("", controllers.GetRecurringTransactions)
 				recurring.POST("", controllers.CreateRecurringTransaction)
@@ -107,7 +151,7 @@ func SetupRouter() *gin.Engine {
 			}
 
 			// ========== Transfers ==========
-			transfers := auth.Group("/transfers")
+			transfers := auth.Group("/transfers", utils.RequireScope("transfers"), utils.WorkspaceWriteGuard())
 			{
 				transfers.GET("", controllers.GetTransfers)
 				transfers.POST("", controllers.CreateTransfer)
@@ -116,20 +160,103 @@ func SetupRouter() *gin.Engine {
 			}
 
 			// ========== Saving Targets ==========
-			saving := auth.Group("/saving-targets")
+			saving := auth.Group("/saving-targets", utils.RequireScope("savings"), utils.WorkspaceWriteGuard())
 			{
+				// role member hanya boleh setor/tarik ke target miliknya
+				memberReadOnly := utils.RestrictMemberWrites()
+
 				saving.GET("", controllers.GetSavingTargets)
-				saving.POST("", controllers.CreateSavingTarget)
+				saving.POST("", memberReadOnly, controllers.CreateSavingTarget)
 				saving.GET("/:id", controllers.GetSavingTargetByID)
-				saving.PUT("/:id", controllers.UpdateSavingTarget)
-				saving.DELETE("/:id", controllers.DeleteSavingTarget)
+				saving.PUT("/:id", memberReadOnly, controllers.UpdateSavingTarget)
+				saving.DELETE("/:id", memberReadOnly, controllers.DeleteSavingTarget)
+				saving.GET("/:id/contributions", controllers.GetSavingContributions)
+				saving.POST("/:id/contributions", controllers.CreateSavingContribution)
+				saving.POST("/:id/withdrawals", controllers.CreateSavingWithdrawal)
+				saving.GET("/:id/rules", controllers.GetSavingRules)
+				saving.POST("/:id/rules", memberReadOnly, controllers.CreateSavingRule)
+				saving.PUT("/:id/rules/:rule_id", memberReadOnly, controllers.UpdateSavingRule)
+				saving.DELETE("/:id/rules/:rule_id", memberReadOnly, controllers.DeleteSavingRule)
+			}
+
+			// ========== Allowances ==========
+			allowances := auth.Group("/allowances", utils.RequireScope("allowances"), utils.WorkspaceWriteGuard(), utils.RestrictMemberWrites())
+			{
+				allowances.GET("", controllers.GetAllowances)
+				allowances.POST("", controllers.CreateAllowance)
+				allowances.PUT("/:id", controllers.UpdateAllowance)
+				allowances.DELETE("/:id", controllers.DeleteAllowance)
+			}
+
+			// ========== Assets & Liabilities ==========
+			assets := auth.Group("/assets", utils.RequireScope("assets"), utils.WorkspaceWriteGuard())
+			{
+				assets.GET("", controllers.GetAssets)
+				assets.POST("", controllers.CreateAsset)
+				assets.GET("/categories", controllers.GetAssetCategories)
+				assets.GET("/:id", controllers.GetAssetByID)
+				assets.PUT("/:id", controllers.UpdateAsset)
+				assets.DELETE("/:id", controllers.DeleteAsset)
+				assets.GET("/:id/valuations", controllers.GetAssetValuations)
+				assets.POST("/:id/valuations", controllers.CreateAssetValuation)
+				assets.DELETE("/:id/valuations/:valuation_id", controllers.DeleteAssetValuation)
+			}
+
+			// ========== Loans ==========
+			loans := auth.Group("/loans", utils.RequireScope("loans"), utils.WorkspaceWriteGuard())
+			{
+				loans.GET("", controllers.GetLoans)
+				loans.POST("", controllers.CreateLoan)
+				loans.GET("/:id", controllers.GetLoanByID)
+				loans.PUT("/:id", controllers.UpdateLoan)
+				loans.DELETE("/:id", controllers.DeleteLoan)
+				loans.GET("/:id/schedule", controllers.GetLoanSchedule)
+				loans.POST("/:id/installments/:number/pay", controllers.PayLoanInstallment)
+				loans.DELETE("/:id/installments/:number/payment", controllers.UnpayLoanInstallment)
+			}
+
+			// ========== Investments ==========
+			investments := auth.Group("/investments", utils.RequireScope("investments"), utils.WorkspaceWriteGuard())
+			{
+				// instrumen & harga dipakai seluruh ledger; role member hanya boleh membaca
+				memberReadOnly := utils.RestrictMemberWrites()
+
+				investments.GET("/securities", controllers.GetSecurities)
+				investments.POST("/securities", memberReadOnly, controllers.CreateSecurity)
+				investments.PUT("/securities/:id", memberReadOnly, controllers.UpdateSecurity)
+				investments.DELETE("/securities/:id", memberReadOnly, controllers.DeleteSecurity)
+				investments.GET("/securities/:id/prices", controllers.GetSecurityPrices)
+				investments.POST("/securities/:id/prices", memberReadOnly, controllers.CreateSecurityPrice)
+				investments.DELETE("/securities/:id/prices/:price_id", memberReadOnly, controllers.DeleteSecurityPrice)
+				investments.POST("/prices/import", memberReadOnly, controllers.ImportSecurityPrices)
+				investments.GET("/lots", controllers.GetInvestmentLots)
+				investments.POST("/lots", controllers.CreateInvestmentLot)
+				investments.DELETE("/lots/:id", controllers.DeleteInvestmentLot)
+				investments.GET("/dividends", controllers.GetInvestmentDividends)
+				investments.POST("/dividends", controllers.CreateInvestmentDividend)
 			}
 
 			// ========== Dashboard ==========
-			auth.GET("/dashboard", controllers.GetDashboard)
+			auth.GET("/dashboard", utils.RequireScope("reports"), controllers.GetDashboard)
+
+			// ========== Audit log ==========
+			auth.GET("/audit", utils.JWTOnly(), utils.RequireWorkspaceRole(
+				models.WorkspaceRoleOwner, models.WorkspaceRoleEditor, models.WorkspaceRoleViewer,
+			), controllers.GetAuditLogs)
+
+			// ========== Trash ==========
+			trash := auth.Group("/trash", utils.JWTOnly(), utils.RequireWorkspaceRole(
+				models.WorkspaceRoleOwner, models.WorkspaceRoleEditor,
+			))
+			{
+				trash.GET("", controllers.GetTrash)
+				trash.POST("/:type/:id/restore", controllers.RestoreTrashItem)
+			}
 
 			// ========== Reports ==========
-			reports := auth.Group("/reports")
+			reports := auth.Group("/reports", utils.RequireScope("reports"), utils.RequireWorkspaceRole(
+				models.WorkspaceRoleOwner, models.WorkspaceRoleEditor, models.WorkspaceRoleViewer,
+			))
 			{
 				reports.GET("/transactions", controllers.GetReportTransactions)
 				reports.GET("/summary", controllers.GetReportSummary)
@@ -137,6 +264,9 @@ func SetupRouter() *gin.Engine {
 				reports.GET("/saving", controllers.GetSavingReport)
 				reports.GET("/members-comparison", controllers.GetMembersComparisonReport)
 				reports.GET("/members-comparison-chart", controllers.GetMemberComparisonChart)
+				reports.GET("/net-worth", controllers.GetNetWorthReport)
+				reports.GET("/portfolio", controllers.GetPortfolioReport)
+				reports.GET("/forecast", controllers.GetForecastReport)
 
 				// Report export
 				reports.GET("/export/csv", controllers.ExportTransactionsCSV)
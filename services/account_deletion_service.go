@@ -0,0 +1,237 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"finance-app/config"
+	"finance-app/models"
+	"finance-app/utils"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var ErrDeletionNotScheduled = &utils.AppError{
+	Message:    "Account deletion is not scheduled",
+	StatusCode: http.StatusConflict,
+	Code:       "DELETION_NOT_SCHEDULED",
+}
+
+// AccountDeletionService menghapus akun user beserta seluruh datanya secara permanen.
+type AccountDeletionService struct {
+	db     *gorm.DB
+	mailer Mailer
+}
+
+func NewAccountDeletionService(db *gorm.DB, mailer Mailer) *AccountDeletionService {
+	return &AccountDeletionService{db: db, mailer: mailer}
+}
+
+// RequestDeletion memverifikasi password (dan kode 2FA kalau aktif) lalu
+// menghapus akun. Kalau ACCOUNT_DELETION_GRACE > 0, akun hanya dijadwalkan
+// untuk dihapus dan semua sesi serta API key dicabut; user masih bisa login
+// untuk membatalkan sebelum jadwal tiba. Mengembalikan jadwal purge, atau nil
+// kalau akun sudah langsung dihapus.
+func (s *AccountDeletionService) RequestDeletion(userID uint, password, code string) (*time.Time, error) {
+	var user models.User
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&user, userID).Error; err != nil {
+			return err
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+			return &utils.AppError{Message: "Invalid password", StatusCode: http.StatusUnauthorized}
+		}
+		if user.TwoFactorEnabled {
+			return NewTwoFactorService(tx).verify(tx, &user, code)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	grace := config.Get().AccountDeletionGrace
+	if grace <= 0 {
+		return nil, s.Purge(userID)
+	}
+
+	scheduledAt := time.Now().Add(grace)
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("deletion_scheduled_at", scheduledAt).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.APIKey{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", time.Now()).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := NewTokenService(s.db).RevokeAllForUser(userID); err != nil {
+		return nil, err
+	}
+
+	s.notify(user.Email, "Your account is scheduled for deletion", fmt.Sprintf(
+		"Hi %s,\n\nYour account and all of its data will be permanently deleted on %s.\nSign in before then and cancel the deletion if this was a mistake.\n",
+		user.Username, scheduledAt.Format("2006-01-02 15:04 MST")))
+	return &scheduledAt, nil
+}
+
+// CancelDeletion membatalkan penghapusan yang masih dalam masa tenggang.
+func (s *AccountDeletionService) CancelDeletion(userID uint) error {
+	res := s.db.Model(&models.User{}).
+		Where("id = ? AND deletion_scheduled_at IS NOT NULL", userID).
+		Update("deletion_scheduled_at", nil)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrDeletionNotScheduled
+	}
+	return nil
+}
+
+// PurgeDue menghapus permanen akun yang masa tenggangnya sudah lewat.
+func (s *AccountDeletionService) PurgeDue(now time.Time) error {
+	var ids []uint
+	if err := s.db.Model(&models.User{}).
+		Where("deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= ?", now).
+		Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := s.Purge(id); err != nil {
+			log.Printf("⚠️  failed to purge user %d: %v", id, err)
+		}
+	}
+	return nil
+}
+
+// Purge menghapus permanen user dan semua baris miliknya di satu transaksi.
+// Jejak user di ledger orang lain (audit log, member tertaut) dianonimkan.
+func (s *AccountDeletionService) Purge(userID uint) error {
+	var user models.User
+	if err := s.db.Unscoped().First(&user, userID).Error; err != nil {
+		return err
+	}
+
+	// cabut sesi dulu supaya access token yang masih hidup langsung ditolak;
+	// baris revoked_tokens sengaja dibiarkan sampai kedaluwarsa sendiri
+	if err := NewTokenService(s.db).RevokeAllForUser(userID); err != nil {
+		return err
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		del := tx.Unscoped().Session(&gorm.Session{})
+		memberIDs := del.Model(&models.Member{}).Select("id").Where("user_id = ?", userID)
+
+		// urutan mengikuti foreign key: anak dulu baru induk
+		ledger := []interface{}{
+			&models.InvestmentDividend{},
+			&models.InvestmentLot{},
+			&models.SecurityPrice{},
+			&models.Security{},
+			&models.LoanInstallment{},
+			&models.Loan{},
+			&models.SavingContribution{},
+			&models.SavingRule{},
+			&models.SavingTarget{},
+			&models.Allowance{},
+			&models.Transaction{},
+			&models.Transfer{},
+			&models.RecurringTransaction{},
+			&models.BudgetCategory{},
+			&models.AssetValuation{},
+			&models.Asset{},
+			&models.AccountBalanceSnapshot{},
+			&models.NetWorthSnapshot{},
+			&models.AssetValueSnapshot{},
+		}
+		for _, model := range ledger {
+			if err := del.Where("user_id = ?", userID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		if err := del.Where("member_id IN (?)", memberIDs).Delete(&models.Account{}).Error; err != nil {
+			return err
+		}
+		if err := del.Where("user_id = ?", userID).Delete(&models.Category{}).Error; err != nil {
+			return err
+		}
+		if err := del.Where("user_id = ?", userID).Delete(&models.Member{}).Error; err != nil {
+			return err
+		}
+
+		// workspace milik user beserta anggota & undangannya
+		workspaceIDs := del.Model(&models.Workspace{}).Select("id").Where("owner_id = ?", userID)
+		if err := del.Where("workspace_id IN (?) OR invited_by_id = ?", workspaceIDs, userID).
+			Delete(&models.WorkspaceInvitation{}).Error; err != nil {
+			return err
+		}
+		if err := del.Where("workspace_id IN (?) OR user_id = ?", workspaceIDs, userID).
+			Delete(&models.WorkspaceMembership{}).Error; err != nil {
+			return err
+		}
+		if err := del.Where("owner_id = ?", userID).Delete(&models.Workspace{}).Error; err != nil {
+			return err
+		}
+
+		// kredensial & data autentikasi
+		auth := []interface{}{
+			&models.RefreshToken{},
+			&models.Session{},
+			&models.UserToken{},
+			&models.RecoveryCode{},
+			&models.APIKey{},
+		}
+		for _, model := range auth {
+			if err := del.Where("user_id = ?", userID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		identities := []string{
+			"email:" + strings.ToLower(user.Email),
+			"user:" + strconv.FormatUint(uint64(userID), 10),
+		}
+		if err := del.Where("identity IN ?", identities).Delete(&models.LoginAttempt{}).Error; err != nil {
+			return err
+		}
+
+		// jejak di ledger orang lain dianonimkan, bukan dihapus
+		if err := tx.Model(&models.Member{}).Where("login_user_id = ?", userID).
+			Update("login_user_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.AuditLog{}).Where("actor_user_id = ? AND ledger_user_id <> ?", userID, userID).
+			Updates(map[string]interface{}{"actor_user_id": nil, "actor_api_key_id": nil, "ip_address": ""}).Error; err != nil {
+			return err
+		}
+		// terakhir, karena penghapusan di atas ikut menulis audit log ledger ini
+		if err := del.Where("ledger_user_id = ?", userID).Delete(&models.AuditLog{}).Error; err != nil {
+			return err
+		}
+
+		return del.Delete(&user).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	s.notify(user.Email, "Your account has been deleted", fmt.Sprintf(
+		"Hi %s,\n\nYour account and all of its data have been permanently deleted.\n", user.Username))
+	return nil
+}
+
+func (s *AccountDeletionService) notify(to, subject, body string) {
+	if s.mailer == nil {
+		return
+	}
+	if err := s.mailer.Send(MailMessage{To: to, Subject: subject, Body: body}); err != nil {
+		log.Printf("⚠️  failed to send account deletion email to %s: %v", to, err)
+	}
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"finance-app/models"
+	"finance-app/utils"
+
+	"gorm.io/gorm"
+)
+
+type AllowanceService struct {
+	db *gorm.DB
+}
+
+func NewAllowanceService(db *gorm.DB) *AllowanceService {
+	return &AllowanceService{db: db}
+}
+
+// nextAllowanceDate mengembalikan jadwal pertama setelah date. Jadwal selalu
+// dihitung dari StartDate supaya uang saku tanggal 31 kembali ke akhir bulan.
+func nextAllowanceDate(a *models.Allowance, date time.Time) time.Time {
+	anchor, err := time.Parse("2006-01-02", a.StartDate)
+	if err != nil || anchor.After(date) {
+		anchor = date
+	}
+	if a.Frequency == "weekly" {
+		weeks := int(date.Sub(anchor).Hours()/24)/7 + 1
+		return anchor.AddDate(0, 0, weeks*7)
+	}
+	months := (date.Year()-anchor.Year())*12 + int(date.Month()-anchor.Month())
+	next := addMonths(anchor, months)
+	for !next.After(date) {
+		months++
+		next = addMonths(anchor, months)
+	}
+	return next
+}
+
+// Validate memastikan akun sumber milik workspace dan akun tujuan milik member penerima.
+func (s *AllowanceService) Validate(a *models.Allowance) error {
+	if a.Amount <= 0 {
+		return utils.NewAppError("Amount must be greater than zero", http.StatusBadRequest)
+	}
+	if a.Frequency != "weekly" && a.Frequency != "monthly" {
+		return utils.NewAppError("Frequency must be weekly or monthly", http.StatusBadRequest)
+	}
+	if _, err := time.Parse("2006-01-02", a.NextRunDate); err != nil {
+		return utils.NewAppError("Invalid next_run_date format", http.StatusBadRequest)
+	}
+	if a.FromAccountID == a.ToAccountID {
+		return utils.NewAppError("Source and destination account cannot be the same", http.StatusBadRequest)
+	}
+
+	var cnt int64
+	s.db.Model(&models.Member{}).Where("user_id = ? AND id = ?", a.UserID, a.MemberID).Count(&cnt)
+	if cnt == 0 {
+		return utils.NewAppError("Member not found", http.StatusNotFound)
+	}
+	cnt = 0
+	s.db.Model(&models.Account{}).
+		Joins("JOIN members ON members.id = accounts.member_id").
+		Where("members.user_id = ? AND accounts.id = ?", a.UserID, a.FromAccountID).
+		Count(&cnt)
+	if cnt == 0 {
+		return utils.NewAppError("Source account not found", http.StatusNotFound)
+	}
+	cnt = 0
+	s.db.Model(&models.Account{}).Where("member_id = ? AND id = ?", a.MemberID, a.ToAccountID).Count(&cnt)
+	if cnt == 0 {
+		return utils.NewAppError("Destination account must belong to the member", http.StatusBadRequest)
+	}
+	return nil
+}
+
+// ProcessDue mentransfer semua uang saku yang jatuh tempo sampai hari ini.
+// Jadwal yang tertinggal dikejar satu per satu; kalau saldo kurang, dicoba lagi di run berikutnya.
+func (s *AllowanceService) ProcessDue(now time.Time) error {
+	today := now.Format("2006-01-02")
+
+	var allowances []models.Allowance
+	if err := s.db.Where("is_active = ? AND next_run_date <= ?", true, today).Find(&allowances).Error; err != nil {
+		return err
+	}
+
+	for _, a := range allowances {
+		for a.NextRunDate <= today {
+			err := s.db.Transaction(func(tx *gorm.DB) error {
+				var from, to models.Account
+				if err := tx.First(&from, a.FromAccountID).Error; err != nil {
+					return err
+				}
+				if err := tx.First(&to, a.ToAccountID).Error; err != nil {
+					return err
+				}
+				if from.ArchivedAt != nil || to.ArchivedAt != nil {
+					return utils.NewAppError("Account is archived", http.StatusBadRequest)
+				}
+				if _, err := moveFunds(tx, a.UserID, a.MemberID, &from, &to, a.Amount, a.NextRunDate, "Allowance"); err != nil {
+					return err
+				}
+
+				runDate, _ := time.Parse("2006-01-02", a.NextRunDate)
+				a.LastRunDate = a.NextRunDate
+				a.NextRunDate = nextAllowanceDate(&a, runDate).Format("2006-01-02")
+				return tx.Model(&a).Updates(map[string]interface{}{
+					"last_run_date": a.LastRunDate,
+					"next_run_date": a.NextRunDate,
+				}).Error
+			})
+			if err != nil {
+				var appErr *utils.AppError
+				if errors.Is(err, ErrInsufficientBalance) || errors.As(err, &appErr) {
+					log.Printf("allowance %d postponed: %v", a.ID, err)
+					break
+				}
+				// akun sumber / tujuan sudah dihapus: nonaktifkan, jangan hentikan job
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					log.Printf("allowance %d deactivated: account no longer exists", a.ID)
+					if err := s.db.Model(&a).Update("is_active", false).Error; err != nil {
+						return err
+					}
+					break
+				}
+				return err
+			}
+		}
+	}
+	return nil
+}
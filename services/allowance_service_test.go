@@ -0,0 +1,51 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"finance-app/models"
+)
+
+func TestNextAllowanceDate(t *testing.T) {
+	day := func(s string) time.Time {
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+
+	tests := []struct {
+		name      string
+		allowance models.Allowance
+		runs      []string // tanggal jadwal berturut-turut mulai dari NextRunDate
+	}{
+		{
+			name:      "bulanan tanggal 31 kembali ke akhir bulan",
+			allowance: models.Allowance{Frequency: "monthly", StartDate: "2026-01-31"},
+			runs:      []string{"2026-01-31", "2026-02-28", "2026-03-31", "2026-04-30", "2026-05-31"},
+		},
+		{
+			name:      "mingguan",
+			allowance: models.Allowance{Frequency: "weekly", StartDate: "2026-10-01"},
+			runs:      []string{"2026-10-01", "2026-10-08", "2026-10-15", "2026-10-22"},
+		},
+		{
+			name:      "tanpa StartDate dihitung dari tanggal sebelumnya",
+			allowance: models.Allowance{Frequency: "monthly"},
+			runs:      []string{"2026-01-15", "2026-02-15", "2026-03-15"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 1; i < len(tt.runs); i++ {
+				got := nextAllowanceDate(&tt.allowance, day(tt.runs[i-1])).Format("2006-01-02")
+				if got != tt.runs[i] {
+					t.Errorf("after %s: got %s, want %s", tt.runs[i-1], got, tt.runs[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"finance-app/models"
+	"finance-app/utils"
+
+	"gorm.io/gorm"
+)
+
+type APIKeyService struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyService(db *gorm.DB) *APIKeyService {
+	return &APIKeyService{db: db}
+}
+
+// Create membuat API key baru. Kunci mentah dikembalikan sekali ini saja.
+func (s *APIKeyService) Create(userID uint, name string, scopes []string, expiresAt *time.Time) (*models.APIKey, string, error) {
+	if len(scopes) == 0 {
+		return nil, "", &utils.AppError{Message: "At least one scope is required", StatusCode: http.StatusBadRequest}
+	}
+	seen := map[string]bool{}
+	clean := make([]string, 0, len(scopes))
+	for _, sc := range scopes {
+		sc = strings.TrimSpace(sc)
+		if !utils.IsValidAPIKeyScope(sc) {
+			return nil, "", &utils.AppError{
+				Message:    "Invalid scope: " + sc,
+				StatusCode: http.StatusBadRequest,
+				Details:    map[string]interface{}{"resources": utils.APIKeyResources, "actions": []string{"read", "write"}},
+			}
+		}
+		if !seen[sc] {
+			seen[sc] = true
+			clean = append(clean, sc)
+		}
+	}
+	sort.Strings(clean)
+
+	if expiresAt != nil && expiresAt.Before(time.Now()) {
+		return nil, "", &utils.AppError{Message: "expires_at must be in the future", StatusCode: http.StatusBadRequest}
+	}
+
+	random, err := utils.RandomHex(24)
+	if err != nil {
+		return nil, "", err
+	}
+	raw := utils.APIKeyPrefix + random
+
+	key := models.APIKey{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    raw[:len(utils.APIKeyPrefix)+8],
+		KeyHash:   utils.SHA256Hex(raw),
+		Scopes:    strings.Join(clean, ","),
+		ExpiresAt: expiresAt,
+	}
+	if err := s.db.Create(&key).Error; err != nil {
+		return nil, "", err
+	}
+	key.ScopeList = key.ScopeSlice()
+	return &key, raw, nil
+}
+
+// List mengembalikan semua API key user, termasuk yang sudah dicabut.
+func (s *APIKeyService) List(userID uint) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	if err := s.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	for i := range keys {
+		keys[i].ScopeList = keys[i].ScopeSlice()
+	}
+	return keys, nil
+}
+
+// Revoke mencabut API key; request berikutnya dengan key ini akan ditolak.
+func (s *APIKeyService) Revoke(userID, id uint) error {
+	var key models.APIKey
+	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&key).Error; err != nil {
+		return err
+	}
+	if key.RevokedAt != nil {
+		return nil
+	}
+	return s.db.Model(&key).Update("revoked_at", time.Now()).Error
+}
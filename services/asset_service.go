@@ -0,0 +1,185 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"finance-app/models"
+	"finance-app/utils"
+
+	"gorm.io/gorm"
+)
+
+type AssetService struct {
+	db *gorm.DB
+}
+
+func NewAssetService(db *gorm.DB) *AssetService {
+	return &AssetService{db: db}
+}
+
+func isAssetCategory(kind, category string) bool {
+	for _, c := range models.AssetCategories[kind] {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate memastikan jenis, kategori, tanggal dan member pemilik valid.
+func (s *AssetService) Validate(a *models.Asset) error {
+	if _, ok := models.AssetCategories[a.Kind]; !ok {
+		return utils.NewAppError("Kind must be asset or liability", http.StatusBadRequest)
+	}
+	if !isAssetCategory(a.Kind, a.Category) {
+		return &utils.AppError{
+			Message:    "Invalid category for " + a.Kind,
+			StatusCode: http.StatusBadRequest,
+			Details:    map[string]interface{}{"allowed": models.AssetCategories[a.Kind]},
+		}
+	}
+	if a.Name == "" {
+		return utils.NewAppError("Name is required", http.StatusBadRequest)
+	}
+	if a.Quantity < 0 {
+		return utils.NewAppError("Quantity cannot be negative", http.StatusBadRequest)
+	}
+	if a.ClosedAt != nil {
+		if _, err := time.Parse("2006-01-02", *a.ClosedAt); err != nil {
+			return utils.NewAppError("Invalid closed_at format", http.StatusBadRequest)
+		}
+	}
+	if a.MemberID != nil {
+		var cnt int64
+		s.db.Model(&models.Member{}).Where("user_id = ? AND id = ?", a.UserID, *a.MemberID).Count(&cnt)
+		if cnt == 0 {
+			return utils.NewAppError("Member not found", http.StatusNotFound)
+		}
+	}
+	return nil
+}
+
+// Create menyimpan aset baru beserta valuasi awalnya.
+func (s *AssetService) Create(a *models.Asset, value float64, date string) error {
+	if err := s.Validate(a); err != nil {
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(a).Error; err != nil {
+			return err
+		}
+		if _, err := s.addValuation(tx, a, date, value, "Initial value"); err != nil {
+			return err
+		}
+		return tx.First(a, a.ID).Error
+	})
+}
+
+/* ===========================
+   Valuations
+=========================== */
+
+func validateValuation(date string, value float64) error {
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return utils.NewAppError("Invalid date format, use YYYY-MM-DD", http.StatusBadRequest)
+	}
+	if value < 0 {
+		return utils.NewAppError("Value cannot be negative", http.StatusBadRequest)
+	}
+	return nil
+}
+
+// EnsureUnmanaged menolak perubahan manual pada aset yang riwayat nilainya
+// ditulis otomatis oleh pinjaman atau portofolio akun investasi.
+func (s *AssetService) EnsureUnmanaged(asset *models.Asset) error {
+	deps, err := countDependents([]dependentCounter{
+		{"loans", s.db.Model(&models.Loan{}).Where("asset_id = ?", asset.ID)},
+		{"investment_accounts", s.db.Model(&models.Account{}).Where("portfolio_asset_id = ?", asset.ID)},
+	})
+	if err != nil {
+		return err
+	}
+	if len(deps) > 0 {
+		return &utils.AppError{
+			Message:    "Asset is managed by a loan or investment account; change it there instead",
+			StatusCode: http.StatusConflict,
+			Code:       "MANAGED_ASSET",
+			Details:    deps,
+		}
+	}
+	return nil
+}
+
+// AddValuation mencatat nilai aset pada suatu tanggal. Valuasi di tanggal yang
+// sama ditimpa.
+func (s *AssetService) AddValuation(asset *models.Asset, date string, value float64, note string) (*models.AssetValuation, error) {
+	if err := s.EnsureUnmanaged(asset); err != nil {
+		return nil, err
+	}
+	var v *models.AssetValuation
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		v, err = s.addValuation(tx, asset, date, value, note)
+		return err
+	})
+	return v, err
+}
+
+func (s *AssetService) addValuation(tx *gorm.DB, asset *models.Asset, date string, value float64, note string) (*models.AssetValuation, error) {
+	if err := validateValuation(date, value); err != nil {
+		return nil, err
+	}
+
+	var v models.AssetValuation
+	err := tx.Where("asset_id = ? AND date = ?", asset.ID, date).First(&v).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		v = models.AssetValuation{UserID: asset.UserID, AssetID: asset.ID, Date: date, Value: value, Note: note}
+		if err := tx.Create(&v).Error; err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	default:
+		if err := tx.Model(&v).Updates(map[string]interface{}{"value": value, "note": note}).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	if err := refreshAssetValue(tx, asset.ID); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// DeleteValuation menghapus satu valuasi lalu menghitung ulang nilai terakhir aset.
+func (s *AssetService) DeleteValuation(asset *models.Asset, valuationID uint) error {
+	if err := s.EnsureUnmanaged(asset); err != nil {
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("asset_id = ? AND id = ?", asset.ID, valuationID).Delete(&models.AssetValuation{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return refreshAssetValue(tx, asset.ID)
+	})
+}
+
+// refreshAssetValue menyalin valuasi dengan tanggal terbaru ke CurrentValue.
+func refreshAssetValue(tx *gorm.DB, assetID uint) error {
+	var latest models.AssetValuation
+	err := tx.Where("asset_id = ?", assetID).Order("date DESC, id DESC").First(&latest).Error
+	updates := map[string]interface{}{"current_value": 0, "valued_at": ""}
+	if err == nil {
+		updates = map[string]interface{}{"current_value": latest.Value, "valued_at": latest.Date}
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return tx.Model(&models.Asset{}).Where("id = ?", assetID).Updates(updates).Error
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"net/http"
+
+	"finance-app/database"
+	"finance-app/models"
+	"finance-app/utils"
+
+	"gorm.io/gorm"
+)
+
+type AuditQuery struct {
+	Entity      string `form:"entity"`
+	EntityID    uint   `form:"entity_id"`
+	Action      string `form:"action"`
+	ActorUserID uint   `form:"actor_user_id"`
+	StartDate   string `form:"start_date"`
+	EndDate     string `form:"end_date"`
+	Limit       int    `form:"limit,default=50"`
+	Page        int    `form:"page,default=1"`
+}
+
+type AuditService struct {
+	db *gorm.DB
+}
+
+func NewAuditService(db *gorm.DB) *AuditService {
+	return &AuditService{db: db}
+}
+
+// List mengembalikan audit log ledger, terbaru lebih dulu.
+func (s *AuditService) List(ledgerUserID uint, q *AuditQuery) ([]models.AuditLog, int64, error) {
+	query := s.db.Model(&models.AuditLog{}).Where("ledger_user_id = ?", ledgerUserID)
+
+	if q.Entity != "" {
+		valid := false
+		for _, e := range database.AuditEntities() {
+			if e == q.Entity {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, 0, &utils.AppError{
+				Message:    "Unknown entity",
+				StatusCode: http.StatusBadRequest,
+				Details:    map[string]interface{}{"entities": database.AuditEntities()},
+			}
+		}
+		query = query.Where("entity = ?", q.Entity)
+	}
+	if q.EntityID != 0 {
+		query = query.Where("entity_id = ?", q.EntityID)
+	}
+	if q.Action != "" {
+		query = query.Where("action = ?", q.Action)
+	}
+	if q.ActorUserID != 0 {
+		query = query.Where("actor_user_id = ?", q.ActorUserID)
+	}
+	if q.StartDate != "" && q.EndDate != "" {
+		query = query.Where("DATE(created_at) BETWEEN ? AND ?", q.StartDate, q.EndDate)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if q.Limit <= 0 || q.Limit > 200 {
+		q.Limit = 50
+	}
+	if q.Page <= 0 {
+		q.Page = 1
+	}
+
+	var logs []models.AuditLog
+	err := query.Order("id DESC").
+		Limit(q.Limit).
+		Offset((q.Page - 1) * q.Limit).
+		Find(&logs).Error
+	return logs, total, err
+}
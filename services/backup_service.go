@@ -0,0 +1,1167 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"finance-app/models"
+	"finance-app/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	BackupFormat  = "finance-app-backup"
+	BackupVersion = 1
+)
+
+// BackupArchive adalah isi file backup. ID di dalamnya adalah ID dari instance
+// asal dan hanya dipakai untuk menghubungkan relasi antar data saat import.
+type BackupArchive struct {
+	Format     string    `json:"format"`
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	User       struct {
+		Username string `json:"username"`
+		Email    string `json:"email"`
+	} `json:"user"`
+
+	Members               []BackupMember               `json:"members"`
+	Accounts              []BackupAccount              `json:"accounts"`
+	Categories            []BackupCategory             `json:"categories"`
+	Transactions          []BackupTransaction          `json:"transactions"`
+	Transfers             []BackupTransfer             `json:"transfers"`
+	Budgets               []BackupBudget               `json:"budgets"`
+	RecurringTransactions []BackupRecurringTransaction `json:"recurring_transactions"`
+	SavingTargets         []BackupSavingTarget         `json:"saving_targets"`
+	SavingRules           []BackupSavingRule           `json:"saving_rules"`
+	SavingContributions   []BackupSavingContribution   `json:"saving_contributions"`
+	Allowances            []BackupAllowance            `json:"allowances"`
+	Assets                []BackupAsset                `json:"assets"`
+	AssetValuations       []BackupAssetValuation       `json:"asset_valuations"`
+	Loans                 []BackupLoan                 `json:"loans"`
+	LoanInstallments      []BackupLoanInstallment      `json:"loan_installments"`
+	Securities            []BackupSecurity             `json:"securities"`
+	SecurityPrices        []BackupSecurityPrice        `json:"security_prices"`
+	InvestmentLots        []BackupInvestmentLot        `json:"investment_lots"`
+	InvestmentDividends   []BackupInvestmentDividend   `json:"investment_dividends"`
+}
+
+type BackupMember struct {
+	ID                uint      `json:"id"`
+	CreatedAt         time.Time `json:"created_at"`
+	Name              string    `json:"name"`
+	SpendingCap       float64   `json:"spending_cap"`
+	SpendingCapPeriod string    `json:"spending_cap_period"`
+}
+
+type BackupAccount struct {
+	ID               uint       `json:"id"`
+	CreatedAt        time.Time  `json:"created_at"`
+	MemberID         uint       `json:"member_id"`
+	Name             string     `json:"name"`
+	Type             string     `json:"type"`
+	Balance          float64    `json:"balance"`
+	Currency         string     `json:"currency"`
+	ArchivedAt       *time.Time `json:"archived_at,omitempty"`
+	PortfolioAssetID *uint      `json:"portfolio_asset_id,omitempty"`
+}
+
+type BackupCategory struct {
+	ID         uint       `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ParentID   *uint      `json:"parent_id,omitempty"`
+	Name       string     `json:"name"`
+	Type       string     `json:"type"`
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+}
+
+type BackupTransaction struct {
+	ID          uint      `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	MemberID    uint      `json:"member_id"`
+	AccountID   uint      `json:"account_id"`
+	CategoryID  uint      `json:"category_id"`
+	Amount      float64   `json:"amount"`
+	Date        string    `json:"date"`
+	Description string    `json:"description"`
+	Type        string    `json:"type"`
+}
+
+type BackupTransfer struct {
+	ID            uint      `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	MemberID      uint      `json:"member_id"`
+	FromAccountID uint      `json:"from_account_id"`
+	ToAccountID   uint      `json:"to_account_id"`
+	Amount        float64   `json:"amount"`
+	Date          string    `json:"date"`
+	Description   string    `json:"description"`
+	Fee           float64   `json:"fee"`
+}
+
+type BackupBudget struct {
+	ID         uint      `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	CategoryID uint      `json:"category_id"`
+	Amount     float64   `json:"amount"`
+	Period     string    `json:"period"`
+}
+
+type BackupRecurringTransaction struct {
+	ID          uint      `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	MemberID    uint      `json:"member_id"`
+	AccountID   uint      `json:"account_id"`
+	CategoryID  uint      `json:"category_id"`
+	Amount      float64   `json:"amount"`
+	Description string    `json:"description"`
+	Type        string    `json:"type"`
+	Frequency   string    `json:"frequency"`
+	StartDate   string    `json:"start_date"`
+	EndDate     string    `json:"end_date"`
+	IsActive    bool      `json:"is_active"`
+}
+
+type BackupSavingTarget struct {
+	ID            uint      `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	MemberID      uint      `json:"member_id"`
+	AccountID     uint      `json:"account_id"`
+	Name          string    `json:"name"`
+	TargetAmount  float64   `json:"target_amount"`
+	CurrentAmount float64   `json:"current_amount"`
+	TargetDate    string    `json:"target_date"`
+	Description   string    `json:"description"`
+}
+
+type BackupSavingRule struct {
+	ID              uint      `json:"id"`
+	CreatedAt       time.Time `json:"created_at"`
+	SavingTargetID  uint      `json:"saving_target_id"`
+	Type            string    `json:"type"`
+	TransactionType string    `json:"transaction_type"`
+	CategoryID      *uint     `json:"category_id,omitempty"`
+	RoundTo         float64   `json:"round_to"`
+	Percentage      float64   `json:"percentage"`
+	IsActive        bool      `json:"is_active"`
+}
+
+type BackupSavingContribution struct {
+	ID               uint      `json:"id"`
+	CreatedAt        time.Time `json:"created_at"`
+	SavingTargetID   uint      `json:"saving_target_id"`
+	Type             string    `json:"type"`
+	Amount           float64   `json:"amount"`
+	Date             string    `json:"date"`
+	Description      string    `json:"description"`
+	CounterAccountID *uint     `json:"counter_account_id,omitempty"`
+	TransferID       *uint     `json:"transfer_id,omitempty"`
+	SavingRuleID     *uint     `json:"saving_rule_id,omitempty"`
+	TransactionID    *uint     `json:"transaction_id,omitempty"`
+}
+
+type BackupAllowance struct {
+	ID            uint      `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	MemberID      uint      `json:"member_id"`
+	FromAccountID uint      `json:"from_account_id"`
+	ToAccountID   uint      `json:"to_account_id"`
+	Amount        float64   `json:"amount"`
+	Frequency     string    `json:"frequency"`
+	StartDate     string    `json:"start_date,omitempty"`
+	NextRunDate   string    `json:"next_run_date"`
+	LastRunDate   string    `json:"last_run_date"`
+	IsActive      bool      `json:"is_active"`
+}
+
+type BackupAsset struct {
+	ID        uint      `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	MemberID  *uint     `json:"member_id,omitempty"`
+	Kind      string    `json:"kind"`
+	Category  string    `json:"category"`
+	Name      string    `json:"name"`
+	Currency  string    `json:"currency"`
+	Quantity  float64   `json:"quantity"`
+	Unit      string    `json:"unit"`
+	Notes     string    `json:"notes"`
+	ClosedAt  *string   `json:"closed_at,omitempty"`
+}
+
+type BackupAssetValuation struct {
+	ID        uint      `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	AssetID   uint      `json:"asset_id"`
+	Date      string    `json:"date"`
+	Value     float64   `json:"value"`
+	Note      string    `json:"note"`
+}
+
+type BackupLoan struct {
+	ID          uint      `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	MemberID    *uint     `json:"member_id,omitempty"`
+	AccountID   *uint     `json:"account_id,omitempty"`
+	CategoryID  *uint     `json:"category_id,omitempty"`
+	AssetID     *uint     `json:"asset_id,omitempty"`
+	Name        string    `json:"name"`
+	Lender      string    `json:"lender"`
+	Category    string    `json:"category"`
+	Principal   float64   `json:"principal"`
+	AnnualRate  float64   `json:"annual_rate"`
+	TenorMonths int       `json:"tenor_months"`
+	StartDate   string    `json:"start_date"`
+	Method      string    `json:"method"`
+}
+
+type BackupLoanInstallment struct {
+	ID            uint      `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	LoanID        uint      `json:"loan_id"`
+	Number        int       `json:"number"`
+	DueDate       string    `json:"due_date"`
+	Principal     float64   `json:"principal"`
+	Interest      float64   `json:"interest"`
+	Total         float64   `json:"total"`
+	BalanceAfter  float64   `json:"balance_after"`
+	PaidDate      *string   `json:"paid_date,omitempty"`
+	PaidAmount    float64   `json:"paid_amount"`
+	TransactionID *uint     `json:"transaction_id,omitempty"`
+	TransferID    *uint     `json:"transfer_id,omitempty"`
+}
+
+type BackupSecurity struct {
+	ID        uint      `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Symbol    string    `json:"symbol"`
+	Name      string    `json:"name"`
+	Kind      string    `json:"kind"`
+	Currency  string    `json:"currency"`
+}
+
+type BackupSecurityPrice struct {
+	SecurityID uint    `json:"security_id"`
+	Date       string  `json:"date"`
+	Price      float64 `json:"price"`
+}
+
+type BackupInvestmentLot struct {
+	ID         uint      `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	AccountID  uint      `json:"account_id"`
+	SecurityID uint      `json:"security_id"`
+	Side       string    `json:"side"`
+	Date       string    `json:"date"`
+	Quantity   float64   `json:"quantity"`
+	Price      float64   `json:"price"`
+	Fee        float64   `json:"fee"`
+	Notes      string    `json:"notes"`
+}
+
+type BackupInvestmentDividend struct {
+	ID            uint      `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	AccountID     uint      `json:"account_id"`
+	SecurityID    uint      `json:"security_id"`
+	TransactionID uint      `json:"transaction_id"`
+	Date          string    `json:"date"`
+	Amount        float64   `json:"amount"`
+}
+
+// ImportResult merangkum jumlah data yang dibuat per jenis.
+type ImportResult struct {
+	Created map[string]int `json:"created"`
+	// Kategori arsip yang cocok dengan kategori yang sudah ada (mis. kategori bawaan)
+	CategoriesMatched int `json:"categories_matched"`
+}
+
+type BackupService struct {
+	db *gorm.DB
+}
+
+func NewBackupService(db *gorm.DB) *BackupService {
+	return &BackupService{db: db}
+}
+
+func errInvalidBackup(format string, args ...interface{}) error {
+	return &utils.AppError{
+		Message:    fmt.Sprintf(format, args...),
+		StatusCode: http.StatusBadRequest,
+		Code:       "INVALID_BACKUP",
+	}
+}
+
+/* ===========================
+   Export
+=========================== */
+
+// Export mengumpulkan seluruh data ledger user. Data di trash tidak ikut.
+func (s *BackupService) Export(userID uint) (*BackupArchive, error) {
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	a := &BackupArchive{Format: BackupFormat, Version: BackupVersion, ExportedAt: time.Now().UTC()}
+	a.User.Username = user.Username
+	a.User.Email = user.Email
+
+	var members []models.Member
+	if err := s.db.Where("user_id = ?", userID).Order("id").Find(&members).Error; err != nil {
+		return nil, err
+	}
+	memberIDs := make([]uint, 0, len(members))
+	for _, m := range members {
+		memberIDs = append(memberIDs, m.ID)
+		a.Members = append(a.Members, BackupMember{
+			ID: m.ID, CreatedAt: m.CreatedAt, Name: m.Name,
+			SpendingCap: m.SpendingCap, SpendingCapPeriod: m.SpendingCapPeriod,
+		})
+	}
+
+	var accounts []models.Account
+	if err := s.db.Where("member_id IN ?", memberIDs).Order("id").Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+	for _, acc := range accounts {
+		a.Accounts = append(a.Accounts, BackupAccount{
+			ID: acc.ID, CreatedAt: acc.CreatedAt, MemberID: acc.MemberID, Name: acc.Name,
+			Type: acc.Type, Balance: acc.Balance, Currency: acc.Currency, ArchivedAt: acc.ArchivedAt,
+			PortfolioAssetID: acc.PortfolioAssetID,
+		})
+	}
+
+	var categories []models.Category
+	if err := s.db.Where("user_id = ?", userID).Order("id").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	for _, c := range categories {
+		a.Categories = append(a.Categories, BackupCategory{
+			ID: c.ID, CreatedAt: c.CreatedAt, ParentID: c.ParentID, Name: c.Name,
+			Type: c.Type, ArchivedAt: c.ArchivedAt,
+		})
+	}
+
+	var trxs []models.Transaction
+	if err := s.db.Where("user_id = ?", userID).Order("id").Find(&trxs).Error; err != nil {
+		return nil, err
+	}
+	for _, t := range trxs {
+		a.Transactions = append(a.Transactions, BackupTransaction{
+			ID: t.ID, CreatedAt: t.CreatedAt, MemberID: t.MemberID, AccountID: t.AccountID,
+			CategoryID: t.CategoryID, Amount: t.Amount, Date: t.Date, Description: t.Description, Type: t.Type,
+		})
+	}
+
+	var transfers []models.Transfer
+	if err := s.db.Where("user_id = ?", userID).Order("id").Find(&transfers).Error; err != nil {
+		return nil, err
+	}
+	for _, t := range transfers {
+		a.Transfers = append(a.Transfers, BackupTransfer{
+			ID: t.ID, CreatedAt: t.CreatedAt, MemberID: t.MemberID, FromAccountID: t.FromAccountID,
+			ToAccountID: t.ToAccountID, Amount: t.Amount, Date: t.Date, Description: t.Description, Fee: t.Fee,
+		})
+	}
+
+	var budgets []models.BudgetCategory
+	if err := s.db.Where("user_id = ?", userID).Order("id").Find(&budgets).Error; err != nil {
+		return nil, err
+	}
+	for _, b := range budgets {
+		a.Budgets = append(a.Budgets, BackupBudget{
+			ID: b.ID, CreatedAt: b.CreatedAt, CategoryID: b.CategoryID, Amount: b.Amount, Period: b.Period,
+		})
+	}
+
+	var recurring []models.RecurringTransaction
+	if err := s.db.Where("user_id = ?", userID).Order("id").Find(&recurring).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range recurring {
+		a.RecurringTransactions = append(a.RecurringTransactions, BackupRecurringTransaction{
+			ID: r.ID, CreatedAt: r.CreatedAt, MemberID: r.MemberID, AccountID: r.AccountID,
+			CategoryID: r.CategoryID, Amount: r.Amount, Description: r.Description, Type: r.Type,
+			Frequency: r.Frequency, StartDate: r.StartDate, EndDate: r.EndDate, IsActive: r.IsActive,
+		})
+	}
+
+	var targets []models.SavingTarget
+	if err := s.db.Where("user_id = ?", userID).Order("id").Find(&targets).Error; err != nil {
+		return nil, err
+	}
+	for _, t := range targets {
+		a.SavingTargets = append(a.SavingTargets, BackupSavingTarget{
+			ID: t.ID, CreatedAt: t.CreatedAt, MemberID: t.MemberID, AccountID: t.AccountID, Name: t.Name,
+			TargetAmount: t.TargetAmount, CurrentAmount: t.CurrentAmount, TargetDate: t.TargetDate,
+			Description: t.Description,
+		})
+	}
+
+	var rules []models.SavingRule
+	if err := s.db.Where("user_id = ?", userID).Order("id").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range rules {
+		a.SavingRules = append(a.SavingRules, BackupSavingRule{
+			ID: r.ID, CreatedAt: r.CreatedAt, SavingTargetID: r.SavingTargetID, Type: r.Type,
+			TransactionType: r.TransactionType, CategoryID: r.CategoryID, RoundTo: r.RoundTo,
+			Percentage: r.Percentage, IsActive: r.IsActive,
+		})
+	}
+
+	var contributions []models.SavingContribution
+	if err := s.db.Where("user_id = ?", userID).Order("id").Find(&contributions).Error; err != nil {
+		return nil, err
+	}
+	for _, c := range contributions {
+		a.SavingContributions = append(a.SavingContributions, BackupSavingContribution{
+			ID: c.ID, CreatedAt: c.CreatedAt, SavingTargetID: c.SavingTargetID, Type: c.Type,
+			Amount: c.Amount, Date: c.Date, Description: c.Description, CounterAccountID: c.CounterAccountID,
+			TransferID: c.TransferID, SavingRuleID: c.SavingRuleID, TransactionID: c.TransactionID,
+		})
+	}
+
+	var allowances []models.Allowance
+	if err := s.db.Where("user_id = ?", userID).Order("id").Find(&allowances).Error; err != nil {
+		return nil, err
+	}
+	for _, al := range allowances {
+		a.Allowances = append(a.Allowances, BackupAllowance{
+			ID: al.ID, CreatedAt: al.CreatedAt, MemberID: al.MemberID, FromAccountID: al.FromAccountID,
+			ToAccountID: al.ToAccountID, Amount: al.Amount, Frequency: al.Frequency,
+			StartDate: al.StartDate, NextRunDate: al.NextRunDate, LastRunDate: al.LastRunDate, IsActive: al.IsActive,
+		})
+	}
+
+	var assets []models.Asset
+	if err := s.db.Where("user_id = ?", userID).Order("id").Find(&assets).Error; err != nil {
+		return nil, err
+	}
+	for _, as := range assets {
+		a.Assets = append(a.Assets, BackupAsset{
+			ID: as.ID, CreatedAt: as.CreatedAt, MemberID: as.MemberID, Kind: as.Kind, Category: as.Category,
+			Name: as.Name, Currency: as.Currency, Quantity: as.Quantity, Unit: as.Unit, Notes: as.Notes,
+			ClosedAt: as.ClosedAt,
+		})
+	}
+
+	var valuations []models.AssetValuation
+	if err := s.db.Where("user_id = ?", userID).Order("id").Find(&valuations).Error; err != nil {
+		return nil, err
+	}
+	for _, v := range valuations {
+		a.AssetValuations = append(a.AssetValuations, BackupAssetValuation{
+			ID: v.ID, CreatedAt: v.CreatedAt, AssetID: v.AssetID, Date: v.Date, Value: v.Value, Note: v.Note,
+		})
+	}
+
+	var loans []models.Loan
+	if err := s.db.Where("user_id = ?", userID).Order("id").Find(&loans).Error; err != nil {
+		return nil, err
+	}
+	for _, l := range loans {
+		a.Loans = append(a.Loans, BackupLoan{
+			ID: l.ID, CreatedAt: l.CreatedAt, MemberID: l.MemberID, AccountID: l.AccountID,
+			CategoryID: l.CategoryID, AssetID: l.AssetID, Name: l.Name, Lender: l.Lender,
+			Category: l.Category, Principal: l.Principal, AnnualRate: l.AnnualRate,
+			TenorMonths: l.TenorMonths, StartDate: l.StartDate, Method: l.Method,
+		})
+	}
+
+	var installments []models.LoanInstallment
+	if err := s.db.Where("user_id = ?", userID).Order("loan_id, number").Find(&installments).Error; err != nil {
+		return nil, err
+	}
+	for _, in := range installments {
+		a.LoanInstallments = append(a.LoanInstallments, BackupLoanInstallment{
+			ID: in.ID, CreatedAt: in.CreatedAt, LoanID: in.LoanID, Number: in.Number, DueDate: in.DueDate,
+			Principal: in.Principal, Interest: in.Interest, Total: in.Total, BalanceAfter: in.BalanceAfter,
+			PaidDate: in.PaidDate, PaidAmount: in.PaidAmount, TransactionID: in.TransactionID,
+			TransferID: in.TransferID,
+		})
+	}
+
+	var securities []models.Security
+	if err := s.db.Where("user_id = ?", userID).Order("id").Find(&securities).Error; err != nil {
+		return nil, err
+	}
+	for _, sec := range securities {
+		a.Securities = append(a.Securities, BackupSecurity{
+			ID: sec.ID, CreatedAt: sec.CreatedAt, Symbol: sec.Symbol, Name: sec.Name, Kind: sec.Kind, Currency: sec.Currency,
+		})
+	}
+
+	var prices []models.SecurityPrice
+	if err := s.db.Where("user_id = ?", userID).Order("security_id, date").Find(&prices).Error; err != nil {
+		return nil, err
+	}
+	for _, p := range prices {
+		a.SecurityPrices = append(a.SecurityPrices, BackupSecurityPrice{SecurityID: p.SecurityID, Date: p.Date, Price: p.Price})
+	}
+
+	var lots []models.InvestmentLot
+	if err := s.db.Where("user_id = ?", userID).Order("id").Find(&lots).Error; err != nil {
+		return nil, err
+	}
+	for _, l := range lots {
+		a.InvestmentLots = append(a.InvestmentLots, BackupInvestmentLot{
+			ID: l.ID, CreatedAt: l.CreatedAt, AccountID: l.AccountID, SecurityID: l.SecurityID, Side: l.Side,
+			Date: l.Date, Quantity: l.Quantity, Price: l.Price, Fee: l.Fee, Notes: l.Notes,
+		})
+	}
+
+	var dividends []models.InvestmentDividend
+	if err := s.db.Where("user_id = ?", userID).Order("id").Find(&dividends).Error; err != nil {
+		return nil, err
+	}
+	for _, d := range dividends {
+		a.InvestmentDividends = append(a.InvestmentDividends, BackupInvestmentDividend{
+			ID: d.ID, CreatedAt: d.CreatedAt, AccountID: d.AccountID, SecurityID: d.SecurityID,
+			TransactionID: d.TransactionID, Date: d.Date, Amount: d.Amount,
+		})
+	}
+
+	return a, nil
+}
+
+/* ===========================
+   Import
+=========================== */
+
+// idMap memetakan ID arsip ke ID baru di database ini.
+type idMap map[uint]uint
+
+func (m idMap) resolve(kind string, oldID uint) (uint, error) {
+	newID, ok := m[oldID]
+	if !ok {
+		return 0, errInvalidBackup("Backup references unknown %s #%d", kind, oldID)
+	}
+	return newID, nil
+}
+
+// resolveOptional memetakan referensi opsional; referensi yang tidak dikenal dikosongkan.
+func (m idMap) resolveOptional(oldID *uint) *uint {
+	if oldID == nil {
+		return nil
+	}
+	newID, ok := m[*oldID]
+	if !ok {
+		return nil
+	}
+	return &newID
+}
+
+// Import memulihkan arsip ke ledger user yang masih kosong. Semua ID dibuat
+// baru; saldo akun diambil dari arsip apa adanya (riwayat tidak di-apply ulang).
+// Kategori yang sudah ada dengan nama, tipe dan induk yang sama dipakai ulang,
+// jadi kategori bawaan dari registrasi tidak menghalangi import.
+func (s *BackupService) Import(userID uint, a *BackupArchive) (*ImportResult, error) {
+	if a.Format != BackupFormat {
+		return nil, errInvalidBackup("Unrecognised backup format")
+	}
+	if a.Version < 1 || a.Version > BackupVersion {
+		return nil, errInvalidBackup("Unsupported backup version %d (max %d)", a.Version, BackupVersion)
+	}
+	if err := validateArchive(a); err != nil {
+		return nil, err
+	}
+	if err := s.ensureEmpty(userID); err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{Created: map[string]int{}}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		members, accounts, categories := idMap{}, idMap{}, idMap{}
+		transactions, transfers, targets, rules := idMap{}, idMap{}, idMap{}, idMap{}
+
+		for _, m := range a.Members {
+			row := models.Member{UserID: userID, Name: m.Name, SpendingCap: m.SpendingCap, SpendingCapPeriod: m.SpendingCapPeriod}
+			row.CreatedAt = m.CreatedAt
+			if row.SpendingCapPeriod == "" {
+				row.SpendingCapPeriod = "monthly"
+			}
+			if err := tx.Create(&row).Error; err != nil {
+				return err
+			}
+			members[m.ID] = row.ID
+		}
+		result.Created["members"] = len(a.Members)
+
+		for _, acc := range a.Accounts {
+			memberID, err := members.resolve("member", acc.MemberID)
+			if err != nil {
+				return err
+			}
+			row := models.Account{
+				MemberID: memberID, Name: acc.Name, Type: acc.Type, Balance: acc.Balance,
+				Currency: acc.Currency, ArchivedAt: acc.ArchivedAt,
+			}
+			row.CreatedAt = acc.CreatedAt
+			if row.Currency == "" {
+				row.Currency = "IDR"
+			}
+			if err := tx.Create(&row).Error; err != nil {
+				return err
+			}
+			accounts[acc.ID] = row.ID
+		}
+		result.Created["accounts"] = len(a.Accounts)
+
+		created, matched, err := s.importCategories(tx, userID, a.Categories, categories)
+		if err != nil {
+			return err
+		}
+		result.Created["categories"] = created
+		result.CategoriesMatched = matched
+
+		for _, t := range a.Transactions {
+			row := models.Transaction{
+				UserID: userID, Amount: t.Amount, Date: t.Date, Description: t.Description, Type: t.Type,
+			}
+			row.CreatedAt = t.CreatedAt
+			if row.MemberID, err = members.resolve("member", t.MemberID); err != nil {
+				return err
+			}
+			if row.AccountID, err = accounts.resolve("account", t.AccountID); err != nil {
+				return err
+			}
+			if row.CategoryID, err = categories.resolve("category", t.CategoryID); err != nil {
+				return err
+			}
+			if err := tx.Omit("Member", "Account", "Category").Create(&row).Error; err != nil {
+				return err
+			}
+			transactions[t.ID] = row.ID
+		}
+		result.Created["transactions"] = len(a.Transactions)
+
+		for _, t := range a.Transfers {
+			row := models.Transfer{
+				UserID: userID, Amount: t.Amount, Date: t.Date, Description: t.Description, Fee: t.Fee,
+			}
+			row.CreatedAt = t.CreatedAt
+			if row.MemberID, err = members.resolve("member", t.MemberID); err != nil {
+				return err
+			}
+			if row.FromAccountID, err = accounts.resolve("account", t.FromAccountID); err != nil {
+				return err
+			}
+			if row.ToAccountID, err = accounts.resolve("account", t.ToAccountID); err != nil {
+				return err
+			}
+			if err := tx.Omit("Member", "FromAccount", "ToAccount").Create(&row).Error; err != nil {
+				return err
+			}
+			transfers[t.ID] = row.ID
+		}
+		result.Created["transfers"] = len(a.Transfers)
+
+		for _, b := range a.Budgets {
+			row := models.BudgetCategory{UserID: userID, Amount: b.Amount, Period: b.Period}
+			row.CreatedAt = b.CreatedAt
+			if row.CategoryID, err = categories.resolve("category", b.CategoryID); err != nil {
+				return err
+			}
+			if err := tx.Create(&row).Error; err != nil {
+				return err
+			}
+		}
+		result.Created["budgets"] = len(a.Budgets)
+
+		for _, r := range a.RecurringTransactions {
+			row := models.RecurringTransaction{
+				UserID: userID, Amount: r.Amount, Description: r.Description, Type: r.Type,
+				Frequency: r.Frequency, StartDate: r.StartDate, EndDate: r.EndDate, IsActive: r.IsActive,
+			}
+			row.CreatedAt = r.CreatedAt
+			if row.MemberID, err = members.resolve("member", r.MemberID); err != nil {
+				return err
+			}
+			if row.AccountID, err = accounts.resolve("account", r.AccountID); err != nil {
+				return err
+			}
+			if row.CategoryID, err = categories.resolve("category", r.CategoryID); err != nil {
+				return err
+			}
+			if err := createWithActive(tx, &row, r.IsActive); err != nil {
+				return err
+			}
+		}
+		result.Created["recurring_transactions"] = len(a.RecurringTransactions)
+
+		for _, t := range a.SavingTargets {
+			row := models.SavingTarget{
+				UserID: userID, Name: t.Name, TargetAmount: t.TargetAmount, CurrentAmount: t.CurrentAmount,
+				TargetDate: t.TargetDate, Description: t.Description,
+			}
+			row.CreatedAt = t.CreatedAt
+			if row.MemberID, err = members.resolve("member", t.MemberID); err != nil {
+				return err
+			}
+			if row.AccountID, err = accounts.resolve("account", t.AccountID); err != nil {
+				return err
+			}
+			if err := tx.Create(&row).Error; err != nil {
+				return err
+			}
+			targets[t.ID] = row.ID
+		}
+		result.Created["saving_targets"] = len(a.SavingTargets)
+
+		for _, r := range a.SavingRules {
+			row := models.SavingRule{
+				UserID: userID, Type: r.Type, TransactionType: r.TransactionType,
+				CategoryID: categories.resolveOptional(r.CategoryID), RoundTo: r.RoundTo,
+				Percentage: r.Percentage, IsActive: r.IsActive,
+			}
+			row.CreatedAt = r.CreatedAt
+			if row.SavingTargetID, err = targets.resolve("saving target", r.SavingTargetID); err != nil {
+				return err
+			}
+			if err := createWithActive(tx.Omit("SavingTarget"), &row, r.IsActive); err != nil {
+				return err
+			}
+			rules[r.ID] = row.ID
+		}
+		result.Created["saving_rules"] = len(a.SavingRules)
+
+		for _, c := range a.SavingContributions {
+			row := models.SavingContribution{
+				UserID: userID, Type: c.Type, Amount: c.Amount, Date: c.Date, Description: c.Description,
+				CounterAccountID: accounts.resolveOptional(c.CounterAccountID),
+				TransferID:       transfers.resolveOptional(c.TransferID),
+				SavingRuleID:     rules.resolveOptional(c.SavingRuleID),
+				TransactionID:    transactions.resolveOptional(c.TransactionID),
+			}
+			row.CreatedAt = c.CreatedAt
+			if row.SavingTargetID, err = targets.resolve("saving target", c.SavingTargetID); err != nil {
+				return err
+			}
+			if err := tx.Omit("SavingTarget").Create(&row).Error; err != nil {
+				return err
+			}
+		}
+		result.Created["saving_contributions"] = len(a.SavingContributions)
+
+		for _, al := range a.Allowances {
+			row := models.Allowance{
+				UserID: userID, Amount: al.Amount, Frequency: al.Frequency,
+				StartDate: al.StartDate, NextRunDate: al.NextRunDate, LastRunDate: al.LastRunDate, IsActive: al.IsActive,
+			}
+			row.CreatedAt = al.CreatedAt
+			if row.StartDate == "" {
+				row.StartDate = row.NextRunDate
+			}
+			if row.MemberID, err = members.resolve("member", al.MemberID); err != nil {
+				return err
+			}
+			if row.FromAccountID, err = accounts.resolve("account", al.FromAccountID); err != nil {
+				return err
+			}
+			if row.ToAccountID, err = accounts.resolve("account", al.ToAccountID); err != nil {
+				return err
+			}
+			if err := createWithActive(tx.Omit("Member", "FromAccount", "ToAccount"), &row, al.IsActive); err != nil {
+				return err
+			}
+		}
+		result.Created["allowances"] = len(a.Allowances)
+
+		assets := idMap{}
+		for _, as := range a.Assets {
+			row := models.Asset{
+				UserID: userID, MemberID: members.resolveOptional(as.MemberID), Kind: as.Kind,
+				Category: as.Category, Name: as.Name, Currency: as.Currency, Quantity: as.Quantity,
+				Unit: as.Unit, Notes: as.Notes, ClosedAt: as.ClosedAt,
+			}
+			row.CreatedAt = as.CreatedAt
+			if row.Currency == "" {
+				row.Currency = "IDR"
+			}
+			if err := tx.Create(&row).Error; err != nil {
+				return err
+			}
+			assets[as.ID] = row.ID
+		}
+		result.Created["assets"] = len(a.Assets)
+
+		for _, v := range a.AssetValuations {
+			row := models.AssetValuation{UserID: userID, Date: v.Date, Value: v.Value, Note: v.Note}
+			row.CreatedAt = v.CreatedAt
+			if row.AssetID, err = assets.resolve("asset", v.AssetID); err != nil {
+				return err
+			}
+			if err := tx.Omit("Asset").Create(&row).Error; err != nil {
+				return err
+			}
+		}
+		for _, newID := range assets {
+			if err := refreshAssetValue(tx, newID); err != nil {
+				return err
+			}
+		}
+		result.Created["asset_valuations"] = len(a.AssetValuations)
+
+		// akun investasi menunjuk ke Asset portofolionya
+		for _, acc := range a.Accounts {
+			if acc.PortfolioAssetID == nil {
+				continue
+			}
+			if assetID := assets.resolveOptional(acc.PortfolioAssetID); assetID != nil {
+				if err := tx.Model(&models.Account{}).Where("id = ?", accounts[acc.ID]).
+					Update("portfolio_asset_id", *assetID).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		loans := idMap{}
+		for _, l := range a.Loans {
+			row := models.Loan{
+				UserID: userID, MemberID: members.resolveOptional(l.MemberID),
+				AccountID: accounts.resolveOptional(l.AccountID), CategoryID: categories.resolveOptional(l.CategoryID),
+				AssetID: assets.resolveOptional(l.AssetID), Name: l.Name, Lender: l.Lender, Category: l.Category,
+				Principal: l.Principal, AnnualRate: l.AnnualRate, TenorMonths: l.TenorMonths,
+				StartDate: l.StartDate, Method: l.Method, OutstandingPrincipal: l.Principal,
+			}
+			row.CreatedAt = l.CreatedAt
+			if err := tx.Omit("Installments").Create(&row).Error; err != nil {
+				return err
+			}
+			loans[l.ID] = row.ID
+		}
+		result.Created["loans"] = len(a.Loans)
+
+		for _, in := range a.LoanInstallments {
+			row := models.LoanInstallment{
+				UserID: userID, Number: in.Number, DueDate: in.DueDate, Principal: in.Principal,
+				Interest: in.Interest, Total: in.Total, BalanceAfter: in.BalanceAfter,
+				PaidDate: in.PaidDate, PaidAmount: in.PaidAmount,
+				TransactionID: transactions.resolveOptional(in.TransactionID),
+				TransferID:    transfers.resolveOptional(in.TransferID),
+			}
+			row.CreatedAt = in.CreatedAt
+			if row.LoanID, err = loans.resolve("loan", in.LoanID); err != nil {
+				return err
+			}
+			if err := tx.Create(&row).Error; err != nil {
+				return err
+			}
+		}
+		// sisa pokok, status dan riwayat kewajiban dihitung ulang dari angsuran
+		for _, newID := range loans {
+			if err := syncLoan(tx, newID); err != nil {
+				return err
+			}
+		}
+		result.Created["loan_installments"] = len(a.LoanInstallments)
+
+		invested, err := s.importInvestments(tx, userID, a, accounts, transactions)
+		if err != nil {
+			return err
+		}
+		for k, v := range invested {
+			result.Created[k] = v
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// importInvestments memulihkan instrumen, harga, lot dan dividen. Instrumen
+// dengan simbol yang sudah ada dipakai ulang. Lot tidak memindahkan kas lagi
+// karena saldo akun di arsip sudah termasuk efeknya.
+func (s *BackupService) importInvestments(tx *gorm.DB, userID uint, a *BackupArchive, accounts, transactions idMap) (map[string]int, error) {
+	created := map[string]int{}
+
+	securities := idMap{}
+	for _, sec := range a.Securities {
+		var row models.Security
+		err := tx.Where("user_id = ? AND symbol = ?", userID, sec.Symbol).First(&row).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			row = models.Security{UserID: userID, Symbol: sec.Symbol, Name: sec.Name, Kind: sec.Kind, Currency: sec.Currency}
+			row.CreatedAt = sec.CreatedAt
+			if row.Currency == "" {
+				row.Currency = "IDR"
+			}
+			if err := tx.Create(&row).Error; err != nil {
+				return nil, err
+			}
+			created["securities"]++
+		} else if err != nil {
+			return nil, err
+		}
+		securities[sec.ID] = row.ID
+	}
+
+	prices := make([]models.SecurityPrice, 0, len(a.SecurityPrices))
+	for _, p := range a.SecurityPrices {
+		securityID, err := securities.resolve("security", p.SecurityID)
+		if err != nil {
+			return nil, err
+		}
+		prices = append(prices, models.SecurityPrice{UserID: userID, SecurityID: securityID, Date: p.Date, Price: p.Price})
+	}
+	if len(prices) > 0 {
+		if err := upsertPrices(tx, prices); err != nil {
+			return nil, err
+		}
+	}
+	created["security_prices"] = len(prices)
+
+	lotAccounts := map[uint]bool{}
+	for _, l := range a.InvestmentLots {
+		row := models.InvestmentLot{
+			UserID: userID, Side: l.Side, Date: l.Date, Quantity: l.Quantity,
+			Price: l.Price, Fee: l.Fee, Notes: l.Notes,
+		}
+		row.CreatedAt = l.CreatedAt
+		var err error
+		if row.AccountID, err = accounts.resolve("account", l.AccountID); err != nil {
+			return nil, err
+		}
+		if row.SecurityID, err = securities.resolve("security", l.SecurityID); err != nil {
+			return nil, err
+		}
+		if err := tx.Omit("Security").Create(&row).Error; err != nil {
+			return nil, err
+		}
+		lotAccounts[row.AccountID] = true
+	}
+	created["investment_lots"] = len(a.InvestmentLots)
+
+	for _, d := range a.InvestmentDividends {
+		row := models.InvestmentDividend{UserID: userID, Date: d.Date, Amount: d.Amount}
+		row.CreatedAt = d.CreatedAt
+		var err error
+		if row.AccountID, err = accounts.resolve("account", d.AccountID); err != nil {
+			return nil, err
+		}
+		if row.SecurityID, err = securities.resolve("security", d.SecurityID); err != nil {
+			return nil, err
+		}
+		if row.TransactionID, err = transactions.resolve("transaction", d.TransactionID); err != nil {
+			return nil, err
+		}
+		if err := tx.Omit("Security").Create(&row).Error; err != nil {
+			return nil, err
+		}
+	}
+	created["investment_dividends"] = len(a.InvestmentDividends)
+
+	ids := make([]uint, 0, len(securities))
+	for _, id := range securities {
+		ids = append(ids, id)
+	}
+	if len(ids) > 0 {
+		// harga terakhir instrumen + nilai portofolio akun yang punya lot
+		if err := afterPriceChange(tx, ids); err != nil {
+			return nil, err
+		}
+	}
+	for id := range lotAccounts {
+		if err := syncPortfolio(tx, id); err != nil {
+			return nil, err
+		}
+	}
+	return created, nil
+}
+
+// createWithActive membuat baris lalu menyimpan is_active=false secara eksplisit,
+// karena GORM mengganti nilai false dengan default:true saat Create.
+func createWithActive(tx *gorm.DB, row interface{}, active bool) error {
+	if err := tx.Create(row).Error; err != nil {
+		return err
+	}
+	if active {
+		return nil
+	}
+	return tx.Session(&gorm.Session{NewDB: true}).Model(row).Update("is_active", false).Error
+}
+
+// validateArchive menolak nilai enum yang tidak diterima controller, supaya
+// import tidak menyimpan data yang tidak bisa dibuat lewat API.
+func validateArchive(a *BackupArchive) error {
+	transactionTypes := map[string]bool{"income": true, "expense": true}
+	frequencies := map[string]bool{"daily": true, "weekly": true, "monthly": true, "yearly": true}
+	allowanceFrequencies := map[string]bool{"weekly": true, "monthly": true}
+	ruleTypes := map[string]bool{models.SavingRuleRoundUp: true, models.SavingRulePercentage: true}
+	contributionTypes := map[string]bool{models.SavingContributionDeposit: true, models.SavingContributionWithdrawal: true}
+	loanMethods := map[string]bool{models.LoanMethodAnnuity: true, models.LoanMethodFlat: true}
+	lotSides := map[string]bool{models.LotSideBuy: true, models.LotSideSell: true}
+
+	for _, acc := range a.Accounts {
+		if !models.ValidAccountTypes[acc.Type] {
+			return errInvalidBackup("Account #%d has invalid type %q", acc.ID, acc.Type)
+		}
+	}
+	for _, c := range a.Categories {
+		if !transactionTypes[c.Type] {
+			return errInvalidBackup("Category #%d has invalid type %q", c.ID, c.Type)
+		}
+	}
+	for _, t := range a.Transactions {
+		if !transactionTypes[t.Type] {
+			return errInvalidBackup("Transaction #%d has invalid type %q", t.ID, t.Type)
+		}
+	}
+	for _, r := range a.RecurringTransactions {
+		if !transactionTypes[r.Type] {
+			return errInvalidBackup("Recurring transaction #%d has invalid type %q", r.ID, r.Type)
+		}
+		if !frequencies[r.Frequency] {
+			return errInvalidBackup("Recurring transaction #%d has invalid frequency %q", r.ID, r.Frequency)
+		}
+	}
+	for _, r := range a.SavingRules {
+		if !ruleTypes[r.Type] {
+			return errInvalidBackup("Saving rule #%d has invalid type %q", r.ID, r.Type)
+		}
+		if !transactionTypes[r.TransactionType] {
+			return errInvalidBackup("Saving rule #%d has invalid transaction type %q", r.ID, r.TransactionType)
+		}
+	}
+	for _, c := range a.SavingContributions {
+		if !contributionTypes[c.Type] {
+			return errInvalidBackup("Saving contribution #%d has invalid type %q", c.ID, c.Type)
+		}
+	}
+	for _, al := range a.Allowances {
+		if !allowanceFrequencies[al.Frequency] {
+			return errInvalidBackup("Allowance #%d has invalid frequency %q", al.ID, al.Frequency)
+		}
+	}
+	for _, as := range a.Assets {
+		if !isAssetCategory(as.Kind, as.Category) {
+			return errInvalidBackup("Asset #%d has invalid kind or category %q/%q", as.ID, as.Kind, as.Category)
+		}
+	}
+	for _, l := range a.Loans {
+		if !loanMethods[l.Method] {
+			return errInvalidBackup("Loan #%d has invalid method %q", l.ID, l.Method)
+		}
+		if !isAssetCategory(models.AssetKindLiability, l.Category) {
+			return errInvalidBackup("Loan #%d has invalid category %q", l.ID, l.Category)
+		}
+	}
+	for _, sec := range a.Securities {
+		if !isSecurityKind(sec.Kind) {
+			return errInvalidBackup("Security #%d has invalid kind %q", sec.ID, sec.Kind)
+		}
+	}
+	for _, l := range a.InvestmentLots {
+		if !lotSides[l.Side] {
+			return errInvalidBackup("Investment lot #%d has invalid side %q", l.ID, l.Side)
+		}
+	}
+	return nil
+}
+
+// ensureEmpty menolak import kalau ledger sudah berisi data selain kategori.
+func (s *BackupService) ensureEmpty(userID uint) error {
+	checks := []struct {
+		name  string
+		model interface{}
+	}{
+		{"members", &models.Member{}},
+		{"transactions", &models.Transaction{}},
+		{"transfers", &models.Transfer{}},
+		{"budgets", &models.BudgetCategory{}},
+		{"recurring transactions", &models.RecurringTransaction{}},
+		{"saving targets", &models.SavingTarget{}},
+		{"allowances", &models.Allowance{}},
+		{"assets", &models.Asset{}},
+		{"loans", &models.Loan{}},
+		{"investment lots", &models.InvestmentLot{}},
+	}
+	for _, c := range checks {
+		var count int64
+		if err := s.db.Model(c.model).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return &utils.AppError{
+				Message:    "Import is only allowed into an empty account",
+				StatusCode: http.StatusConflict,
+				Code:       "ACCOUNT_NOT_EMPTY",
+				Details:    map[string]interface{}{c.name: count},
+			}
+		}
+	}
+	return nil
+}
+
+// importCategories membuat kategori dengan urutan induk dulu. Kategori yang
+// sudah ada (nama, tipe dan induk sama) dipakai ulang.
+func (s *BackupService) importCategories(tx *gorm.DB, userID uint, cats []BackupCategory, ids idMap) (created, matched int, err error) {
+	var existing []models.Category
+	if err := tx.Where("user_id = ?", userID).Find(&existing).Error; err != nil {
+		return 0, 0, err
+	}
+	type categoryKey struct {
+		name, typ string
+		parent    uint
+	}
+	byKey := map[categoryKey]uint{}
+	for _, c := range existing {
+		var parent uint
+		if c.ParentID != nil {
+			parent = *c.ParentID
+		}
+		byKey[categoryKey{c.Name, c.Type, parent}] = c.ID
+	}
+
+	// urutkan supaya induk selalu diproses sebelum anaknya
+	pending := append([]BackupCategory(nil), cats...)
+	sort.SliceStable(pending, func(i, j int) bool {
+		return pending[i].ParentID == nil && pending[j].ParentID != nil
+	})
+	for len(pending) > 0 {
+		var next []BackupCategory
+		for _, c := range pending {
+			var parentID *uint
+			if c.ParentID != nil {
+				pid, ok := ids[*c.ParentID]
+				if !ok {
+					next = append(next, c)
+					continue
+				}
+				parentID = &pid
+			}
+
+			var parentKey uint
+			if parentID != nil {
+				parentKey = *parentID
+			}
+			key := categoryKey{c.Name, c.Type, parentKey}
+			if id, ok := byKey[key]; ok {
+				ids[c.ID] = id
+				matched++
+				continue
+			}
+
+			row := models.Category{UserID: userID, ParentID: parentID, Name: c.Name, Type: c.Type, ArchivedAt: c.ArchivedAt}
+			row.CreatedAt = c.CreatedAt
+			if err := tx.Create(&row).Error; err != nil {
+				return 0, 0, err
+			}
+			ids[c.ID] = row.ID
+			byKey[key] = row.ID
+			created++
+		}
+		if len(next) == len(pending) {
+			return 0, 0, errInvalidBackup("Backup references unknown parent category #%d", *next[0].ParentID)
+		}
+		pending = next
+	}
+	return created, matched, nil
+}
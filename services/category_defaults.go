@@ -0,0 +1,55 @@
+package services
+
+type categoryTemplate struct {
+	Name     string
+	Type     string
+	Children []string
+}
+
+const DefaultCategoryLocale = "id"
+
+// defaultCategories adalah kategori awal yang dibuat saat user mendaftar.
+var defaultCategories = map[string][]categoryTemplate{
+	"id": {
+		{Name: "Gaji", Type: "income"},
+		{Name: "Bonus", Type: "income"},
+		{Name: "Usaha", Type: "income"},
+		{Name: "Investasi", Type: "income", Children: []string{"Dividen", "Bunga"}},
+		{Name: "Pemasukan Lain", Type: "income"},
+		{Name: "Makan & Minum", Type: "expense", Children: []string{"Belanja Dapur", "Makan di Luar"}},
+		{Name: "Transportasi", Type: "expense", Children: []string{"Bensin", "Transportasi Umum", "Parkir & Tol"}},
+		{Name: "Tagihan", Type: "expense", Children: []string{"Listrik", "Air", "Internet", "Pulsa"}},
+		{Name: "Rumah Tangga", Type: "expense"},
+		{Name: "Kesehatan", Type: "expense"},
+		{Name: "Pendidikan", Type: "expense"},
+		{Name: "Hiburan", Type: "expense"},
+		{Name: "Belanja", Type: "expense"},
+		{Name: "Zakat & Sedekah", Type: "expense"},
+		{Name: "Cicilan", Type: "expense"},
+		{Name: "Pengeluaran Lain", Type: "expense"},
+	},
+	"en": {
+		{Name: "Salary", Type: "income"},
+		{Name: "Bonus", Type: "income"},
+		{Name: "Business", Type: "income"},
+		{Name: "Investments", Type: "income", Children: []string{"Dividends", "Interest"}},
+		{Name: "Other Income", Type: "income"},
+		{Name: "Food & Drinks", Type: "expense", Children: []string{"Groceries", "Dining Out"}},
+		{Name: "Transportation", Type: "expense", Children: []string{"Fuel", "Public Transport", "Parking & Tolls"}},
+		{Name: "Bills", Type: "expense", Children: []string{"Electricity", "Water", "Internet", "Phone"}},
+		{Name: "Household", Type: "expense"},
+		{Name: "Health", Type: "expense"},
+		{Name: "Education", Type: "expense"},
+		{Name: "Entertainment", Type: "expense"},
+		{Name: "Shopping", Type: "expense"},
+		{Name: "Charity", Type: "expense"},
+		{Name: "Installments", Type: "expense"},
+		{Name: "Other Expenses", Type: "expense"},
+	},
+}
+
+// IsSupportedCategoryLocale memeriksa apakah ada kategori bawaan untuk locale ini.
+func IsSupportedCategoryLocale(locale string) bool {
+	_, ok := defaultCategories[locale]
+	return ok
+}
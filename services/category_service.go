@@ -0,0 +1,130 @@
+package services
+
+import (
+	"net/http"
+
+	"finance-app/models"
+	"finance-app/utils"
+
+	"gorm.io/gorm"
+)
+
+type CategoryService struct {
+	db *gorm.DB
+}
+
+func NewCategoryService(db *gorm.DB) *CategoryService {
+	return &CategoryService{db: db}
+}
+
+// SeedDefaults membuat kategori bawaan untuk user baru sesuai locale.
+func (s *CategoryService) SeedDefaults(userID uint, locale string) error {
+	templates, ok := defaultCategories[locale]
+	if !ok {
+		templates = defaultCategories[DefaultCategoryLocale]
+	}
+
+	for _, t := range templates {
+		parent := models.Category{UserID: userID, Name: t.Name, Type: t.Type}
+		if err := s.db.Create(&parent).Error; err != nil {
+			return err
+		}
+		for _, name := range t.Children {
+			child := models.Category{UserID: userID, ParentID: &parent.ID, Name: name, Type: t.Type}
+			if err := s.db.Create(&child).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Merge memindahkan semua transaksi, recurring, budget, saving rule, kategori
+// default pinjaman dan sub-kategori dari sourceID ke targetID, lalu menghapus
+// kategori sumber.
+func (s *CategoryService) Merge(userID, sourceID, targetID uint) (*models.Category, error) {
+	if sourceID == targetID {
+		return nil, utils.NewAppError("Cannot merge a category into itself", http.StatusBadRequest)
+	}
+
+	tree, err := LoadCategoryTree(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	source, ok := tree.Get(sourceID)
+	if !ok {
+		return nil, utils.NewAppError("Category not found", http.StatusNotFound)
+	}
+	target, ok := tree.Get(targetID)
+	if !ok {
+		return nil, utils.NewAppError("Target category not found", http.StatusNotFound)
+	}
+	if source.Type != target.Type {
+		return nil, utils.NewAppError("Categories must have the same type to be merged", http.StatusBadRequest)
+	}
+	for _, id := range tree.DescendantIDs(sourceID) {
+		if id == targetID {
+			return nil, utils.NewAppError("Cannot merge a category into its own sub-category", http.StatusBadRequest)
+		}
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Transaction{}).
+			Where("user_id = ? AND category_id = ?", userID, sourceID).
+			Update("category_id", targetID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.RecurringTransaction{}).
+			Where("user_id = ? AND category_id = ?", userID, sourceID).
+			Update("category_id", targetID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.SavingRule{}).
+			Where("user_id = ? AND category_id = ?", userID, sourceID).
+			Update("category_id", targetID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Loan{}).
+			Where("user_id = ? AND category_id = ?", userID, sourceID).
+			Update("category_id", targetID).Error; err != nil {
+			return err
+		}
+
+		// Budget: kalau target sudah punya budget di periode yang sama, nominalnya dijumlahkan
+		var budgets []models.BudgetCategory
+		if err := tx.Where("user_id = ? AND category_id = ?", userID, sourceID).Find(&budgets).Error; err != nil {
+			return err
+		}
+		for _, b := range budgets {
+			var existing models.BudgetCategory
+			err := tx.Where("user_id = ? AND category_id = ? AND period = ?", userID, targetID, b.Period).
+				First(&existing).Error
+			if err == nil {
+				existing.Amount += b.Amount
+				if err := tx.Save(&existing).Error; err != nil {
+					return err
+				}
+				if err := tx.Delete(&b).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			if err := tx.Model(&b).Update("category_id", targetID).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&models.Category{}).
+			Where("user_id = ? AND parent_id = ?", userID, sourceID).
+			Update("parent_id", targetID).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&models.Category{}, sourceID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &target, nil
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"net/http"
+	"sort"
+
+	"finance-app/models"
+	"finance-app/utils"
+
+	"gorm.io/gorm"
+)
+
+type CategoryNode struct {
+	ID       uint           `json:"id"`
+	ParentID *uint          `json:"parent_id"`
+	Name     string         `json:"name"`
+	Type     string         `json:"type"`
+	Children []CategoryNode `json:"children"`
+}
+
+// CategoryTree adalah indeks in-memory dari kategori milik satu user,
+// dipakai untuk validasi hirarki dan rollup total sub-kategori ke parent.
+type CategoryTree struct {
+	byID     map[uint]models.Category
+	children map[uint][]uint
+	roots    []uint
+}
+
+func LoadCategoryTree(db *gorm.DB, userID uint) (*CategoryTree, error) {
+	var categories []models.Category
+	if err := db.Where("user_id = ?", userID).Order("name ASC").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	return NewCategoryTree(categories), nil
+}
+
+func NewCategoryTree(categories []models.Category) *CategoryTree {
+	t := &CategoryTree{
+		byID:     make(map[uint]models.Category, len(categories)),
+		children: map[uint][]uint{},
+	}
+	for _, c := range categories {
+		t.byID[c.ID] = c
+	}
+	for _, c := range categories {
+		// parent yang sudah terhapus diperlakukan seperti kategori utama
+		if c.ParentID != nil {
+			if _, ok := t.byID[*c.ParentID]; ok {
+				t.children[*c.ParentID] = append(t.children[*c.ParentID], c.ID)
+				continue
+			}
+		}
+		t.roots = append(t.roots, c.ID)
+	}
+	return t
+}
+
+func (t *CategoryTree) Get(id uint) (models.Category, bool) {
+	c, ok := t.byID[id]
+	return c, ok
+}
+
+// RootOf mengembalikan ID kategori paling atas dari id.
+func (t *CategoryTree) RootOf(id uint) uint {
+	current := id
+	for i := 0; i <= len(t.byID); i++ {
+		c, ok := t.byID[current]
+		if !ok || c.ParentID == nil {
+			return current
+		}
+		if _, ok := t.byID[*c.ParentID]; !ok {
+			return current
+		}
+		current = *c.ParentID
+	}
+	return current
+}
+
+// DescendantIDs mengembalikan id beserta seluruh sub-kategorinya.
+func (t *CategoryTree) DescendantIDs(id uint) []uint {
+	ids := []uint{id}
+	seen := map[uint]bool{id: true}
+	for i := 0; i < len(ids); i++ {
+		for _, child := range t.children[ids[i]] {
+			if !seen[child] {
+				seen[child] = true
+				ids = append(ids, child)
+			}
+		}
+	}
+	return ids
+}
+
+func (t *CategoryTree) Nodes() []CategoryNode {
+	return t.buildNodes(t.roots)
+}
+
+func (t *CategoryTree) buildNodes(ids []uint) []CategoryNode {
+	nodes := make([]CategoryNode, 0, len(ids))
+	for _, id := range ids {
+		c := t.byID[id]
+		nodes = append(nodes, CategoryNode{
+			ID:       c.ID,
+			ParentID: c.ParentID,
+			Name:     c.Name,
+			Type:     c.Type,
+			Children: t.buildNodes(t.children[id]),
+		})
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		if nodes[i].Type != nodes[j].Type {
+			return nodes[i].Type > nodes[j].Type
+		}
+		return nodes[i].Name < nodes[j].Name
+	})
+	return nodes
+}
+
+// ValidateParent memastikan parent milik user yang sama, bertipe sama dan
+// tidak membentuk siklus. categoryID = 0 untuk kategori baru.
+func (t *CategoryTree) ValidateParent(categoryID, parentID uint, categoryType string) error {
+	parent, ok := t.byID[parentID]
+	if !ok {
+		return utils.NewAppError("Parent category not found", http.StatusNotFound)
+	}
+	if parent.ArchivedAt != nil {
+		return utils.NewAppError("Parent category is archived", http.StatusBadRequest)
+	}
+	if parent.Type != categoryType {
+		return utils.NewAppError("Sub-category must have the same type as its parent", http.StatusBadRequest)
+	}
+	if categoryID == 0 {
+		return nil
+	}
+	for _, id := range t.DescendantIDs(categoryID) {
+		if id == parentID {
+			return utils.NewAppError("Category cannot be moved under itself or its sub-category", http.StatusBadRequest)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,463 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"finance-app/models"
+	"finance-app/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	forecastDefaultDays        = 90
+	forecastMaxDays            = 365
+	forecastDefaultHistoryDays = 90
+
+	ForecastSourceRecurring = "recurring"
+	ForecastSourceLoan      = "loan"
+	ForecastSourceAllowance = "allowance"
+)
+
+type ForecastService struct {
+	db *gorm.DB
+}
+
+func NewForecastService(db *gorm.DB) *ForecastService {
+	return &ForecastService{db: db}
+}
+
+// ForecastQuery adalah parameter GET /reports/forecast.
+type ForecastQuery struct {
+	Days      int   `form:"days"` // default 90, maks 365
+	AccountID *uint `form:"account_id"`
+
+	// Tambahkan rata-rata pengeluaran harian tak terjadwal dari riwayat
+	IncludeDiscretionary bool `form:"include_discretionary"`
+	HistoryDays          int  `form:"history_days"` // default 90
+
+	// Diisi controller untuk role member: hanya akun milik member ini
+	MemberID *uint `form:"-"`
+}
+
+// ForecastEvent adalah satu arus kas terjadwal. Amount positif = masuk, negatif = keluar.
+type ForecastEvent struct {
+	Date        string  `json:"date"`
+	AccountID   uint    `json:"account_id"`
+	Source      string  `json:"source"` // recurring, loan, allowance
+	SourceID    uint    `json:"source_id"`
+	Description string  `json:"description"`
+	Amount      float64 `json:"amount"`
+	Overdue     bool    `json:"overdue,omitempty"` // angsuran lewat jatuh tempo, diasumsikan dibayar di hari pertama
+}
+
+type ForecastDay struct {
+	Date     string  `json:"date"`
+	Inflow   float64 `json:"inflow"`
+	Outflow  float64 `json:"outflow"`
+	Balance  float64 `json:"balance"`
+	Negative bool    `json:"negative"`
+}
+
+type ForecastAccount struct {
+	AccountID          uint          `json:"account_id"`
+	Name               string        `json:"name"`
+	Type               string        `json:"type"`
+	MemberID           uint          `json:"member_id"`
+	StartBalance       float64       `json:"start_balance"`
+	EndBalance         float64       `json:"end_balance"`
+	LowestBalance      float64       `json:"lowest_balance"`
+	LowestDate         string        `json:"lowest_date"`
+	FirstNegativeDate  string        `json:"first_negative_date,omitempty"`
+	NegativeDays       int           `json:"negative_days"`
+	DiscretionaryDaily float64       `json:"discretionary_daily"`
+	Days               []ForecastDay `json:"days"`
+}
+
+// ForecastAlert menandai akun yang saldonya diproyeksikan negatif.
+type ForecastAlert struct {
+	AccountID         uint    `json:"account_id"`
+	Name              string  `json:"name"`
+	FirstNegativeDate string  `json:"first_negative_date"`
+	NegativeDays      int     `json:"negative_days"`
+	LowestBalance     float64 `json:"lowest_balance"`
+	LowestDate        string  `json:"lowest_date"`
+}
+
+type Forecast struct {
+	From                 string            `json:"from"` // hari pertama proyeksi (besok)
+	To                   string            `json:"to"`
+	Days                 int               `json:"days"`
+	IncludeDiscretionary bool              `json:"include_discretionary"`
+	HistoryDays          int               `json:"history_days,omitempty"`
+	Accounts             []ForecastAccount `json:"accounts"`
+	Total                []ForecastDay     `json:"total"`
+	Events               []ForecastEvent   `json:"events"`
+	Alerts               []ForecastAlert   `json:"alerts"`
+}
+
+// recurringDates mengembalikan tanggal kejadian aturan berulang dalam rentang from..to (inklusif).
+func recurringDates(r *models.RecurringTransaction, from, to time.Time) []time.Time {
+	start, err := time.Parse("2006-01-02", r.StartDate)
+	if err != nil {
+		return nil
+	}
+	if r.EndDate != "" {
+		if end, err := time.Parse("2006-01-02", r.EndDate); err == nil && end.Before(to) {
+			to = end
+		}
+	}
+	if to.Before(from) || to.Before(start) {
+		return nil
+	}
+
+	var dates []time.Time
+	switch r.Frequency {
+	case "daily", "weekly":
+		step := 1
+		if r.Frequency == "weekly" {
+			step = 7
+		}
+		// lompat langsung ke kejadian pertama >= from
+		k := 0
+		if from.After(start) {
+			gap := int(from.Sub(start).Hours() / 24)
+			k = (gap + step - 1) / step
+		}
+		for d := start.AddDate(0, 0, k*step); !d.After(to); d = d.AddDate(0, 0, step) {
+			dates = append(dates, d)
+		}
+	case "monthly", "yearly":
+		step := 1
+		if r.Frequency == "yearly" {
+			step = 12
+		}
+		k := 0
+		if from.After(start) {
+			months := (from.Year()-start.Year())*12 + int(from.Month()-start.Month())
+			k = months/step - 1
+			if k < 0 {
+				k = 0
+			}
+		}
+		// selalu dihitung dari StartDate supaya tanggal 31 tidak bergeser ke 28
+		for d := addMonths(start, k*step); !d.After(to); k++ {
+			if !d.Before(from) {
+				dates = append(dates, d)
+			}
+			d = addMonths(start, (k+1)*step)
+		}
+	}
+	return dates
+}
+
+// Project memproyeksikan saldo harian tiap akun dari hari ini sampai days hari ke depan
+// memakai aturan transaksi berulang aktif, angsuran pinjaman yang belum dibayar,
+// uang saku terjadwal dan (opsional) rata-rata pengeluaran tak terjadwal.
+func (s *ForecastService) Project(userID uint, q ForecastQuery) (*Forecast, error) {
+	if q.Days == 0 {
+		q.Days = forecastDefaultDays
+	}
+	if q.Days < 1 || q.Days > forecastMaxDays {
+		return nil, utils.NewAppError("days must be between 1 and 365", http.StatusBadRequest)
+	}
+	if q.HistoryDays == 0 {
+		q.HistoryDays = forecastDefaultHistoryDays
+	}
+	if q.HistoryDays < 1 || q.HistoryDays > forecastMaxDays {
+		return nil, utils.NewAppError("history_days must be between 1 and 365", http.StatusBadRequest)
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	from := today.AddDate(0, 0, 1)
+	to := today.AddDate(0, 0, q.Days)
+	fromStr, toStr := from.Format("2006-01-02"), to.Format("2006-01-02")
+
+	query := s.db.Model(&models.Account{}).
+		Joins("JOIN members ON members.id = accounts.member_id").
+		Where("members.user_id = ? AND accounts.archived_at IS NULL", userID)
+	if q.MemberID != nil {
+		query = query.Where("accounts.member_id = ?", *q.MemberID)
+	}
+	if q.AccountID != nil {
+		query = query.Where("accounts.id = ?", *q.AccountID)
+	}
+	var accounts []models.Account
+	if err := query.Order("accounts.id").Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+	if q.AccountID != nil && len(accounts) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	accountIDs := make([]uint, 0, len(accounts))
+	tracked := map[uint]bool{}
+	for _, a := range accounts {
+		accountIDs = append(accountIDs, a.ID)
+		tracked[a.ID] = true
+	}
+
+	result := &Forecast{
+		From: fromStr, To: toStr, Days: q.Days,
+		IncludeDiscretionary: q.IncludeDiscretionary,
+		Accounts:             []ForecastAccount{},
+		Total:                []ForecastDay{},
+		Events:               []ForecastEvent{},
+		Alerts:               []ForecastAlert{},
+	}
+	if q.IncludeDiscretionary {
+		result.HistoryDays = q.HistoryDays
+	}
+	if len(accounts) == 0 {
+		return result, nil
+	}
+
+	events, err := s.scheduledEvents(userID, accountIDs, tracked, from, to)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Date < events[j].Date })
+	result.Events = events
+
+	discretionary := map[uint]float64{}
+	if q.IncludeDiscretionary {
+		if discretionary, err = s.discretionaryDaily(userID, accountIDs, today, q.HistoryDays); err != nil {
+			return nil, err
+		}
+	}
+
+	// arus kas per akun per tanggal
+	flows := map[uint]map[string][2]float64{}
+	for _, e := range events {
+		if flows[e.AccountID] == nil {
+			flows[e.AccountID] = map[string][2]float64{}
+		}
+		f := flows[e.AccountID][e.Date]
+		if e.Amount >= 0 {
+			f[0] += e.Amount
+		} else {
+			f[1] -= e.Amount
+		}
+		flows[e.AccountID][e.Date] = f
+	}
+
+	totals := make([]ForecastDay, q.Days)
+	for _, a := range accounts {
+		fa := ForecastAccount{
+			AccountID: a.ID, Name: a.Name, Type: a.Type, MemberID: a.MemberID,
+			StartBalance:       round2(a.Balance),
+			LowestBalance:      round2(a.Balance),
+			LowestDate:         today.Format("2006-01-02"),
+			DiscretionaryDaily: round2(discretionary[a.ID]),
+			Days:               make([]ForecastDay, 0, q.Days),
+		}
+		balance := a.Balance
+		for i := 0; i < q.Days; i++ {
+			date := from.AddDate(0, 0, i).Format("2006-01-02")
+			f := flows[a.ID][date]
+			inflow, outflow := f[0], f[1]+discretionary[a.ID]
+			balance += inflow - outflow
+
+			day := ForecastDay{
+				Date: date, Inflow: round2(inflow), Outflow: round2(outflow),
+				Balance: round2(balance), Negative: round2(balance) < 0,
+			}
+			fa.Days = append(fa.Days, day)
+			if day.Balance < fa.LowestBalance {
+				fa.LowestBalance, fa.LowestDate = day.Balance, date
+			}
+			if day.Negative {
+				fa.NegativeDays++
+				if fa.FirstNegativeDate == "" {
+					fa.FirstNegativeDate = date
+				}
+			}
+
+			totals[i].Date = date
+			totals[i].Inflow += inflow
+			totals[i].Outflow += outflow
+			totals[i].Balance += balance
+		}
+		fa.EndBalance = round2(balance)
+		result.Accounts = append(result.Accounts, fa)
+
+		if fa.NegativeDays > 0 {
+			result.Alerts = append(result.Alerts, ForecastAlert{
+				AccountID: a.ID, Name: a.Name,
+				FirstNegativeDate: fa.FirstNegativeDate, NegativeDays: fa.NegativeDays,
+				LowestBalance: fa.LowestBalance, LowestDate: fa.LowestDate,
+			})
+		}
+	}
+	for i := range totals {
+		totals[i].Inflow = round2(totals[i].Inflow)
+		totals[i].Outflow = round2(totals[i].Outflow)
+		totals[i].Balance = round2(totals[i].Balance)
+		totals[i].Negative = totals[i].Balance < 0
+	}
+	result.Total = totals
+	return result, nil
+}
+
+// scheduledEvents mengumpulkan arus kas terjadwal ke akun-akun yang diproyeksikan.
+func (s *ForecastService) scheduledEvents(userID uint, accountIDs []uint, tracked map[uint]bool, from, to time.Time) ([]ForecastEvent, error) {
+	fromStr, toStr := from.Format("2006-01-02"), to.Format("2006-01-02")
+	events := []ForecastEvent{}
+
+	var rules []models.RecurringTransaction
+	if err := s.db.Where("user_id = ? AND is_active = ? AND account_id IN ?", userID, true, accountIDs).
+		Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	for i := range rules {
+		r := &rules[i]
+		amount := r.Amount
+		if r.Type == "expense" {
+			amount = -amount
+		}
+		for _, d := range recurringDates(r, from, to) {
+			events = append(events, ForecastEvent{
+				Date: d.Format("2006-01-02"), AccountID: r.AccountID,
+				Source: ForecastSourceRecurring, SourceID: r.ID,
+				Description: r.Description, Amount: amount,
+			})
+		}
+	}
+
+	// angsuran yang belum dibayar; yang sudah lewat jatuh tempo dianggap dibayar di hari pertama
+	var installments []struct {
+		LoanID    uint
+		Number    int
+		DueDate   string
+		Total     float64
+		AccountID uint
+		Name      string
+	}
+	if err := s.db.Table("loan_installments").
+		Select("loan_installments.loan_id, loan_installments.number, loan_installments.due_date, loan_installments.total, loans.account_id, loans.name").
+		Joins("JOIN loans ON loans.id = loan_installments.loan_id AND loans.deleted_at IS NULL").
+		Where("loans.user_id = ? AND loans.status = ? AND loans.account_id IN ?", userID, models.LoanStatusActive, accountIDs).
+		Where("loan_installments.deleted_at IS NULL AND loan_installments.paid_date IS NULL AND loan_installments.due_date <= ?", toStr).
+		Order("loan_installments.due_date").
+		Scan(&installments).Error; err != nil {
+		return nil, err
+	}
+	for _, inst := range installments {
+		e := ForecastEvent{
+			Date: inst.DueDate, AccountID: inst.AccountID,
+			Source: ForecastSourceLoan, SourceID: inst.LoanID,
+			Description: fmt.Sprintf("%s #%d", inst.Name, inst.Number), Amount: -inst.Total,
+		}
+		if inst.DueDate < fromStr {
+			e.Date, e.Overdue = fromStr, true
+		}
+		events = append(events, e)
+	}
+
+	// uang saku memindahkan saldo antar akun; sisi yang tidak diproyeksikan diabaikan
+	var allowances []models.Allowance
+	if err := s.db.Where("user_id = ? AND is_active = ?", userID, true).
+		Where("from_account_id IN ? OR to_account_id IN ?", accountIDs, accountIDs).
+		Find(&allowances).Error; err != nil {
+		return nil, err
+	}
+	for _, a := range allowances {
+		next, err := time.Parse("2006-01-02", a.NextRunDate)
+		if err != nil {
+			continue
+		}
+		// jadwal yang tertinggal dikejar scheduler, jadi ikut dihitung di hari pertama
+		for d := next; !d.After(to); d = nextAllowanceDate(&a, d) {
+			date := d.Format("2006-01-02")
+			if date < fromStr {
+				date = fromStr
+			}
+			if tracked[a.FromAccountID] {
+				events = append(events, ForecastEvent{
+					Date: date, AccountID: a.FromAccountID,
+					Source: ForecastSourceAllowance, SourceID: a.ID,
+					Description: "Allowance", Amount: -a.Amount,
+				})
+			}
+			if tracked[a.ToAccountID] {
+				events = append(events, ForecastEvent{
+					Date: date, AccountID: a.ToAccountID,
+					Source: ForecastSourceAllowance, SourceID: a.ID,
+					Description: "Allowance", Amount: a.Amount,
+				})
+			}
+		}
+	}
+
+	for i := range events {
+		events[i].Amount = round2(events[i].Amount)
+	}
+	return events, nil
+}
+
+// discretionaryDaily menghitung rata-rata pengeluaran harian per akun selama historyDays
+// terakhir, tanpa pengeluaran yang sudah diproyeksikan terpisah: transaksi hasil aturan
+// berulang aktif (akun, kategori dan nominal sama) dan pembayaran angsuran pinjaman.
+// Akun yang lebih muda dari historyDays dirata-rata sejak transaksi pertamanya.
+func (s *ForecastService) discretionaryDaily(userID uint, accountIDs []uint, today time.Time, historyDays int) (map[uint]float64, error) {
+	since := today.AddDate(0, 0, -historyDays+1).Format("2006-01-02")
+
+	recurringGenerated := s.db.Model(&models.RecurringTransaction{}).
+		Select("1").
+		Where("recurring_transactions.user_id = ? AND recurring_transactions.is_active = ? AND recurring_transactions.type = ?", userID, true, "expense").
+		Where("recurring_transactions.account_id = transactions.account_id").
+		Where("recurring_transactions.category_id = transactions.category_id").
+		Where("recurring_transactions.amount = transactions.amount")
+	loanPayments := s.db.Model(&models.LoanInstallment{}).
+		Select("transaction_id").
+		Where("transaction_id IS NOT NULL")
+
+	var rows []struct {
+		AccountID uint
+		Total     float64
+	}
+	if err := s.db.Model(&models.Transaction{}).
+		Select("account_id, SUM(amount) AS total").
+		Where("user_id = ? AND type = ? AND account_id IN ?", userID, "expense", accountIDs).
+		Where("date BETWEEN ? AND ?", since, today.Format("2006-01-02")).
+		Where("NOT EXISTS (?)", recurringGenerated).
+		Where("id NOT IN (?)", loanPayments).
+		Group("account_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	var firsts []struct {
+		AccountID uint
+		First     string
+	}
+	if err := s.db.Model(&models.Transaction{}).
+		Select("account_id, MIN(date) AS first").
+		Where("user_id = ? AND account_id IN ?", userID, accountIDs).
+		Group("account_id").
+		Scan(&firsts).Error; err != nil {
+		return nil, err
+	}
+	days := make(map[uint]int, len(firsts))
+	for _, f := range firsts {
+		days[f.AccountID] = historyDays
+		if first, err := time.Parse("2006-01-02", f.First); err == nil {
+			if n := int(today.Sub(first).Hours()/24) + 1; n > 0 && n < historyDays {
+				days[f.AccountID] = n
+			}
+		}
+	}
+
+	daily := make(map[uint]float64, len(rows))
+	for _, r := range rows {
+		n := days[r.AccountID]
+		if n == 0 {
+			n = historyDays
+		}
+		daily[r.AccountID] = r.Total / float64(n)
+	}
+	return daily, nil
+}
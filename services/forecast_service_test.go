@@ -0,0 +1,73 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"finance-app/models"
+)
+
+func TestRecurringDates(t *testing.T) {
+	day := func(s string) time.Time {
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+
+	tests := []struct {
+		name     string
+		rule     models.RecurringTransaction
+		from, to string
+		want     []string
+	}{
+		{
+			name: "bulanan tanggal 31 mengikuti akhir bulan",
+			rule: models.RecurringTransaction{Frequency: "monthly", StartDate: "2024-01-31"},
+			from: "2026-02-01", to: "2026-05-31",
+			want: []string{"2026-02-28", "2026-03-31", "2026-04-30", "2026-05-31"},
+		},
+		{
+			name: "tahunan 29 Februari",
+			rule: models.RecurringTransaction{Frequency: "yearly", StartDate: "2024-02-29"},
+			from: "2025-01-01", to: "2028-12-31",
+			want: []string{"2025-02-28", "2026-02-28", "2027-02-28", "2028-02-29"},
+		},
+		{
+			name: "mingguan berhenti di end_date",
+			rule: models.RecurringTransaction{Frequency: "weekly", StartDate: "2026-10-01", EndDate: "2026-10-20"},
+			from: "2026-10-05", to: "2026-12-31",
+			want: []string{"2026-10-08", "2026-10-15"},
+		},
+		{
+			name: "harian mulai dari from",
+			rule: models.RecurringTransaction{Frequency: "daily", StartDate: "2026-10-10"},
+			from: "2026-10-19", to: "2026-10-21",
+			want: []string{"2026-10-19", "2026-10-20", "2026-10-21"},
+		},
+		{
+			name: "start_date setelah rentang",
+			rule: models.RecurringTransaction{Frequency: "monthly", StartDate: "2027-01-01"},
+			from: "2026-10-01", to: "2026-12-31",
+		},
+		{
+			name: "end_date sebelum rentang",
+			rule: models.RecurringTransaction{Frequency: "monthly", StartDate: "2026-01-15", EndDate: "2026-06-30"},
+			from: "2026-10-01", to: "2026-12-31",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, d := range recurringDates(&tt.rule, day(tt.from), day(tt.to)) {
+				got = append(got, d.Format("2006-01-02"))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"finance-app/models"
+
+	"gorm.io/gorm"
+)
+
+// moveFunds memindahkan saldo antar akun dan mencatatnya sebagai Transfer.
+func moveFunds(tx *gorm.DB, userID, memberID uint, from, to *models.Account, amount float64, date, desc string) (*models.Transfer, error) {
+	if from.Balance < amount {
+		return nil, ErrInsufficientBalance
+	}
+
+	from.Balance -= amount
+	to.Balance += amount
+	if err := tx.Save(from).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Save(to).Error; err != nil {
+		return nil, err
+	}
+
+	transfer := models.Transfer{
+		UserID:        userID,
+		MemberID:      memberID,
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		Amount:        amount,
+		Date:          date,
+		Description:   desc,
+	}
+	if err := tx.Create(&transfer).Error; err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
@@ -0,0 +1,566 @@
+package services
+
+import (
+	"net/http"
+
+	"finance-app/models"
+	"finance-app/utils"
+
+	"gorm.io/gorm"
+)
+
+// DeleteOptions mengatur apa yang dilakukan terhadap data turunan saat menghapus.
+// Tanpa opsi, delete ditolak dengan 409 kalau masih ada data yang bergantung.
+type DeleteOptions struct {
+	ReassignTo uint `form:"reassign_to"`
+	Cascade    bool `form:"cascade"`
+}
+
+type IntegrityService struct {
+	db *gorm.DB
+}
+
+func NewIntegrityService(db *gorm.DB) *IntegrityService {
+	return &IntegrityService{db: db}
+}
+
+/* ===========================
+   Helpers
+=========================== */
+
+type dependentCounter struct {
+	name  string
+	query *gorm.DB
+}
+
+func countDependents(counters []dependentCounter) (map[string]int64, error) {
+	deps := map[string]int64{}
+	for _, ct := range counters {
+		var n int64
+		if err := ct.query.Count(&n).Error; err != nil {
+			return nil, err
+		}
+		if n > 0 {
+			deps[ct.name] = n
+		}
+	}
+	return deps, nil
+}
+
+func dependentsError(entity string, deps map[string]int64) error {
+	return &utils.AppError{
+		Message:    entity + " still has dependent records; use reassign_to or cascade=true",
+		StatusCode: http.StatusConflict,
+		Code:       "HAS_DEPENDENTS",
+		Details:    deps,
+	}
+}
+
+// deleteTransactions menghapus transaksi sambil mengembalikan efek saldonya.
+// batch diisi saat hapus akun cascade (lihat Account.DeletionBatch).
+func (s *IntegrityService) deleteTransactions(tx *gorm.DB, query *gorm.DB, restoreBalance bool, batch string) error {
+	var trxs []models.Transaction
+	if err := query.Find(&trxs).Error; err != nil {
+		return err
+	}
+	trxService := NewTransactionService(tx)
+	for i := range trxs {
+		if restoreBalance {
+			if err := trxService.adjustAccountBalance(tx, trxs[i].AccountID, trxs[i].Type, trxs[i].Amount, false); err != nil {
+				return err
+			}
+		}
+		if err := releaseLoanPayments(tx, "transaction_id", trxs[i].ID, batch); err != nil {
+			return err
+		}
+		if err := releaseInvestmentDividends(tx, trxs[i].ID, batch); err != nil {
+			return err
+		}
+		if err := releaseSavingContributions(tx, trxs[i].ID, batch); err != nil {
+			return err
+		}
+		if err := stampBatch(tx.Model(&trxs[i]), batch); err != nil {
+			return err
+		}
+		if err := tx.Delete(&trxs[i]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteTransfers menghapus transfer dan mengembalikan saldo akun yang tidak ikut dihapus.
+// Kontribusi tabungan yang dananya lewat transfer tersebut ikut dihapus.
+func (s *IntegrityService) deleteTransfers(tx *gorm.DB, query *gorm.DB, skipAccountID uint, batch string) error {
+	var transfers []models.Transfer
+	if err := query.Find(&transfers).Error; err != nil {
+		return err
+	}
+	for i := range transfers {
+		t := transfers[i]
+		if t.FromAccountID != skipAccountID {
+			if err := tx.Model(&models.Account{}).Where("id = ?", t.FromAccountID).
+				Update("balance", gorm.Expr("balance + ?", t.Amount)).Error; err != nil {
+				return err
+			}
+		}
+		if t.ToAccountID != skipAccountID {
+			if err := tx.Model(&models.Account{}).Where("id = ?", t.ToAccountID).
+				Update("balance", gorm.Expr("balance - ?", t.Amount)).Error; err != nil {
+				return err
+			}
+		}
+		if err := releaseLoanPayments(tx, "transfer_id", t.ID, batch); err != nil {
+			return err
+		}
+		var contributions []models.SavingContribution
+		if err := tx.Where("transfer_id = ?", t.ID).Find(&contributions).Error; err != nil {
+			return err
+		}
+		for i := range contributions {
+			if err := stampBatch(tx.Model(&contributions[i]), batch); err != nil {
+				return err
+			}
+			if err := tx.Delete(&contributions[i]).Error; err != nil {
+				return err
+			}
+			if err := NewSavingService(tx).RecalculateCurrentAmount(tx, contributions[i].SavingTargetID); err != nil {
+				return err
+			}
+		}
+		if err := stampBatch(tx.Model(&t), batch); err != nil {
+			return err
+		}
+		if err := tx.Delete(&t).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *IntegrityService) deleteSavingTargets(tx *gorm.DB, query *gorm.DB, batch string) error {
+	var targets []models.SavingTarget
+	if err := query.Find(&targets).Error; err != nil {
+		return err
+	}
+	for i := range targets {
+		for _, model := range []interface{}{&models.SavingRule{}, &models.SavingContribution{}} {
+			if err := stampBatch(tx.Model(model).Where("saving_target_id = ?", targets[i].ID), batch); err != nil {
+				return err
+			}
+			if err := tx.Where("saving_target_id = ?", targets[i].ID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		if err := stampBatch(tx.Model(&targets[i]), batch); err != nil {
+			return err
+		}
+		if err := tx.Delete(&targets[i]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *IntegrityService) findAccount(tx *gorm.DB, userID, accountID uint) (*models.Account, error) {
+	var acc models.Account
+	if err := tx.Joins("JOIN members ON members.id = accounts.member_id").
+		Where("members.user_id = ? AND accounts.id = ?", userID, accountID).
+		First(&acc).Error; err != nil {
+		return nil, err
+	}
+	return &acc, nil
+}
+
+/* ===========================
+   Category
+=========================== */
+
+func (s *IntegrityService) CategoryDependents(userID, categoryID uint) (map[string]int64, error) {
+	return countDependents([]dependentCounter{
+		{"transactions", s.db.Model(&models.Transaction{}).Where("user_id = ? AND category_id = ?", userID, categoryID)},
+		{"recurring_transactions", s.db.Model(&models.RecurringTransaction{}).Where("user_id = ? AND category_id = ?", userID, categoryID)},
+		{"budgets", s.db.Model(&models.BudgetCategory{}).Where("user_id = ? AND category_id = ?", userID, categoryID)},
+		{"saving_rules", s.db.Model(&models.SavingRule{}).Where("user_id = ? AND category_id = ?", userID, categoryID)},
+		{"loans", s.db.Model(&models.Loan{}).Where("user_id = ? AND category_id = ?", userID, categoryID)},
+		{"sub_categories", s.db.Model(&models.Category{}).Where("user_id = ? AND parent_id = ?", userID, categoryID)},
+	})
+}
+
+func (s *IntegrityService) DeleteCategory(userID, categoryID uint, opts DeleteOptions) error {
+	var category models.Category
+	if err := s.db.Where("user_id = ? AND id = ?", userID, categoryID).First(&category).Error; err != nil {
+		return utils.NewAppError("Category not found", http.StatusNotFound)
+	}
+
+	if opts.ReassignTo != 0 {
+		_, err := NewCategoryService(s.db).Merge(userID, categoryID, opts.ReassignTo)
+		return err
+	}
+
+	deps, err := s.CategoryDependents(userID, categoryID)
+	if err != nil {
+		return err
+	}
+	if len(deps) > 0 && !opts.Cascade {
+		return dependentsError("Category", deps)
+	}
+
+	tree, err := LoadCategoryTree(s.db, userID)
+	if err != nil {
+		return err
+	}
+	ids := tree.DescendantIDs(categoryID)
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.deleteTransactions(tx, tx.Where("user_id = ? AND category_id IN ?", userID, ids), true, ""); err != nil {
+			return err
+		}
+		for _, model := range []interface{}{&models.RecurringTransaction{}, &models.BudgetCategory{}, &models.SavingRule{}} {
+			if err := tx.Where("user_id = ? AND category_id IN ?", userID, ids).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		// pinjaman tetap ada, hanya kategori default cicilannya dikosongkan
+		if err := tx.Model(&models.Loan{}).Where("user_id = ? AND category_id IN ?", userID, ids).
+			Update("category_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ? AND id IN ?", userID, ids).Delete(&models.Category{}).Error
+	})
+}
+
+/* ===========================
+   Account
+=========================== */
+
+func (s *IntegrityService) AccountDependents(accountID uint) (map[string]int64, error) {
+	return countDependents([]dependentCounter{
+		{"transactions", s.db.Model(&models.Transaction{}).Where("account_id = ?", accountID)},
+		{"recurring_transactions", s.db.Model(&models.RecurringTransaction{}).Where("account_id = ?", accountID)},
+		{"saving_targets", s.db.Model(&models.SavingTarget{}).Where("account_id = ?", accountID)},
+		{"transfers", s.db.Model(&models.Transfer{}).Where("from_account_id = ? OR to_account_id = ?", accountID, accountID)},
+		{"investment_lots", s.db.Model(&models.InvestmentLot{}).Where("account_id = ?", accountID)},
+		{"allowances", s.db.Model(&models.Allowance{}).Where("from_account_id = ? OR to_account_id = ?", accountID, accountID)},
+		{"loans", s.db.Model(&models.Loan{}).Where("account_id = ?", accountID)},
+	})
+}
+
+func (s *IntegrityService) DeleteAccount(userID, accountID uint, opts DeleteOptions) error {
+	account, err := s.findAccount(s.db, userID, accountID)
+	if err != nil {
+		return utils.NewAppError("Account not found", http.StatusNotFound)
+	}
+
+	deps, err := s.AccountDependents(accountID)
+	if err != nil {
+		return err
+	}
+	if len(deps) > 0 && opts.ReassignTo == 0 && !opts.Cascade {
+		return dependentsError("Account", deps)
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if opts.ReassignTo != 0 {
+			if err := s.reassignAccount(tx, userID, account, opts.ReassignTo); err != nil {
+				return err
+			}
+		} else if err := s.cascadeAccount(tx, account); err != nil {
+			return err
+		}
+		return tx.Delete(account).Error
+	})
+}
+
+// reassignAccount memindahkan seluruh riwayat akun ke akun lain. Efek saldo
+// dari riwayat yang dipindah ikut dibawa ke akun tujuan.
+func (s *IntegrityService) reassignAccount(tx *gorm.DB, userID uint, account *models.Account, targetID uint) error {
+	if targetID == account.ID {
+		return utils.NewAppError("Cannot reassign an account to itself", http.StatusBadRequest)
+	}
+	target, err := s.findAccount(tx, userID, targetID)
+	if err != nil {
+		return utils.NewAppError("Target account not found", http.StatusNotFound)
+	}
+
+	var net struct {
+		Income  float64
+		Expense float64
+	}
+	if err := tx.Model(&models.Transaction{}).
+		Select("COALESCE(SUM(CASE WHEN type='income' THEN amount ELSE 0 END),0) AS income, COALESCE(SUM(CASE WHEN type='expense' THEN amount ELSE 0 END),0) AS expense").
+		Where("account_id = ?", account.ID).Scan(&net).Error; err != nil {
+		return err
+	}
+	var transferIn, transferOut float64
+	if err := tx.Model(&models.Transfer{}).Where("to_account_id = ?", account.ID).
+		Select("COALESCE(SUM(amount),0)").Scan(&transferIn).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.Transfer{}).Where("from_account_id = ?", account.ID).
+		Select("COALESCE(SUM(amount),0)").Scan(&transferOut).Error; err != nil {
+		return err
+	}
+
+	moves := []struct {
+		model  interface{}
+		column string
+	}{
+		{&models.Transaction{}, "account_id"},
+		{&models.RecurringTransaction{}, "account_id"},
+		{&models.SavingTarget{}, "account_id"},
+	}
+	for _, m := range moves {
+		if err := tx.Model(m.model).Where(m.column+" = ?", account.ID).
+			Updates(map[string]interface{}{m.column: target.ID, "member_id": target.MemberID}).Error; err != nil {
+			return err
+		}
+	}
+	if err := tx.Model(&models.Transfer{}).Where("from_account_id = ?", account.ID).
+		Update("from_account_id", target.ID).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.Transfer{}).Where("to_account_id = ?", account.ID).
+		Update("to_account_id", target.ID).Error; err != nil {
+		return err
+	}
+	// transfer antara kedua akun sekarang jadi transfer ke diri sendiri; efeknya sudah nol
+	if err := tx.Where("from_account_id = ? AND to_account_id = ?", target.ID, target.ID).
+		Delete(&models.Transfer{}).Error; err != nil {
+		return err
+	}
+
+	// uang saku ikut pindah; penerimanya mengikuti pemilik akun tujuan
+	if err := tx.Model(&models.Allowance{}).Where("from_account_id = ?", account.ID).
+		Update("from_account_id", target.ID).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.Allowance{}).Where("to_account_id = ?", account.ID).
+		Updates(map[string]interface{}{"to_account_id": target.ID, "member_id": target.MemberID}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("from_account_id = ? AND to_account_id = ?", target.ID, target.ID).
+		Delete(&models.Allowance{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.Loan{}).Where("account_id = ?", account.ID).
+		Update("account_id", target.ID).Error; err != nil {
+		return err
+	}
+
+	// lot & dividen hanya bisa pindah ke akun investasi lain
+	var lots []models.InvestmentLot
+	if err := tx.Where("account_id = ?", account.ID).Find(&lots).Error; err != nil {
+		return err
+	}
+	var lotNet float64
+	for i := range lots {
+		lotNet += lotCash(&lots[i])
+	}
+	if len(lots) > 0 {
+		if target.Type != models.AccountTypeInvestment {
+			return utils.NewAppError("Investment lots can only be reassigned to another investment account", http.StatusBadRequest)
+		}
+		for _, model := range []interface{}{&models.InvestmentLot{}, &models.InvestmentDividend{}} {
+			if err := tx.Model(model).Where("account_id = ?", account.ID).
+				Update("account_id", target.ID).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	delta := net.Income - net.Expense + transferIn - transferOut + lotNet
+	if err := tx.Model(target).Update("balance", gorm.Expr("balance + ?", delta)).Error; err != nil {
+		return err
+	}
+	if len(lots) == 0 {
+		return nil
+	}
+	if err := deleteInvestments(tx, account, ""); err != nil {
+		return err
+	}
+	return syncPortfolio(tx, target.ID)
+}
+
+// cascadeAccount menghapus semua riwayat akun. Akun dan baris yang ikut terhapus
+// ditandai dengan satu DeletionBatch supaya TrashService bisa memulihkannya bersama.
+func (s *IntegrityService) cascadeAccount(tx *gorm.DB, account *models.Account) error {
+	batch, err := utils.RandomHex(16)
+	if err != nil {
+		return err
+	}
+	account.DeletionBatch = batch
+	if err := tx.Model(account).Update("deletion_batch", batch).Error; err != nil {
+		return err
+	}
+
+	// transfer dulu: transfer tabungan otomatis dari transaksi akun ini ikut di sini,
+	// jadi releaseSavingContributions tidak membalik saldonya dua kali
+	if err := s.deleteTransfers(tx, tx.Where("from_account_id = ? OR to_account_id = ?", account.ID, account.ID), account.ID, batch); err != nil {
+		return err
+	}
+	// saldo akun ini ikut hilang, jadi transaksinya tidak perlu di-rollback
+	if err := s.deleteTransactions(tx, tx.Where("account_id = ?", account.ID), false, batch); err != nil {
+		return err
+	}
+	if err := stampBatch(tx.Model(&models.RecurringTransaction{}).Where("account_id = ?", account.ID), batch); err != nil {
+		return err
+	}
+	if err := tx.Where("account_id = ?", account.ID).Delete(&models.RecurringTransaction{}).Error; err != nil {
+		return err
+	}
+	if err := s.deleteSavingTargets(tx, tx.Where("account_id = ?", account.ID), batch); err != nil {
+		return err
+	}
+	if err := stampBatch(tx.Model(&models.Allowance{}).Where("from_account_id = ? OR to_account_id = ?", account.ID, account.ID), batch); err != nil {
+		return err
+	}
+	if err := tx.Where("from_account_id = ? OR to_account_id = ?", account.ID, account.ID).Delete(&models.Allowance{}).Error; err != nil {
+		return err
+	}
+	// pinjaman tetap ada, hanya akun default cicilannya dikosongkan
+	if err := tx.Model(&models.Loan{}).Where("account_id = ?", account.ID).
+		Update("account_id", nil).Error; err != nil {
+		return err
+	}
+	return deleteInvestments(tx, account, batch)
+}
+
+/* ===========================
+   Saving target
+=========================== */
+
+func (s *IntegrityService) SavingTargetDependents(targetID uint) (map[string]int64, error) {
+	return countDependents([]dependentCounter{
+		{"saving_contributions", s.db.Model(&models.SavingContribution{}).Where("saving_target_id = ?", targetID)},
+		{"saving_rules", s.db.Model(&models.SavingRule{}).Where("saving_target_id = ?", targetID)},
+	})
+}
+
+// DeleteSavingTarget menghapus target tabungan. Dengan cascade, aturan dan
+// kontribusinya ikut dihapus dan transfer kontribusinya dibatalkan.
+func (s *IntegrityService) DeleteSavingTarget(userID, targetID uint, opts DeleteOptions) error {
+	var target models.SavingTarget
+	if err := s.db.Joins("JOIN members ON members.id = saving_targets.member_id").
+		Where("members.user_id = ? AND saving_targets.id = ?", userID, targetID).
+		First(&target).Error; err != nil {
+		return utils.NewAppError("Saving target not found", http.StatusNotFound)
+	}
+	if opts.ReassignTo != 0 {
+		return utils.NewAppError("Saving targets cannot be reassigned; use cascade=true", http.StatusBadRequest)
+	}
+
+	deps, err := s.SavingTargetDependents(targetID)
+	if err != nil {
+		return err
+	}
+	if len(deps) > 0 && !opts.Cascade {
+		return &utils.AppError{
+			Message:    "Saving target still has contributions or rules; use cascade=true",
+			StatusCode: http.StatusConflict,
+			Code:       "HAS_DEPENDENTS",
+			Details:    deps,
+		}
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		transferIDs := tx.Model(&models.SavingContribution{}).
+			Select("transfer_id").
+			Where("saving_target_id = ? AND transfer_id IS NOT NULL", target.ID)
+		if err := s.deleteTransfers(tx, tx.Where("id IN (?)", transferIDs), 0, ""); err != nil {
+			return err
+		}
+		return s.deleteSavingTargets(tx, tx.Where("id = ?", target.ID), "")
+	})
+}
+
+/* ===========================
+   Member
+=========================== */
+
+func (s *IntegrityService) MemberDependents(memberID uint) (map[string]int64, error) {
+	return countDependents([]dependentCounter{
+		{"accounts", s.db.Model(&models.Account{}).Where("member_id = ?", memberID)},
+		{"transactions", s.db.Model(&models.Transaction{}).Where("member_id = ?", memberID)},
+		{"recurring_transactions", s.db.Model(&models.RecurringTransaction{}).Where("member_id = ?", memberID)},
+		{"saving_targets", s.db.Model(&models.SavingTarget{}).Where("member_id = ?", memberID)},
+		{"transfers", s.db.Model(&models.Transfer{}).Where("member_id = ?", memberID)},
+		{"allowances", s.db.Model(&models.Allowance{}).Where("member_id = ?", memberID)},
+		{"loans", s.db.Model(&models.Loan{}).Where("member_id = ?", memberID)},
+		{"assets", s.db.Model(&models.Asset{}).Where("member_id = ?", memberID)},
+	})
+}
+
+func (s *IntegrityService) DeleteMember(userID, memberID uint, opts DeleteOptions) error {
+	var member models.Member
+	if err := s.db.Where("user_id = ? AND id = ?", userID, memberID).First(&member).Error; err != nil {
+		return utils.NewAppError("Member not found", http.StatusNotFound)
+	}
+
+	deps, err := s.MemberDependents(memberID)
+	if err != nil {
+		return err
+	}
+	if len(deps) > 0 && opts.ReassignTo == 0 && !opts.Cascade {
+		return dependentsError("Member", deps)
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if opts.ReassignTo != 0 {
+			if opts.ReassignTo == memberID {
+				return utils.NewAppError("Cannot reassign a member to itself", http.StatusBadRequest)
+			}
+			var target models.Member
+			if err := tx.Where("user_id = ? AND id = ?", userID, opts.ReassignTo).First(&target).Error; err != nil {
+				return utils.NewAppError("Target member not found", http.StatusNotFound)
+			}
+			for _, model := range []interface{}{
+				&models.Account{}, &models.Transaction{}, &models.RecurringTransaction{},
+				&models.SavingTarget{}, &models.Transfer{}, &models.Allowance{},
+				&models.Loan{}, &models.Asset{},
+			} {
+				if err := tx.Model(model).Where("member_id = ?", memberID).
+					Update("member_id", target.ID).Error; err != nil {
+					return err
+				}
+			}
+		} else {
+			var accounts []models.Account
+			if err := tx.Where("member_id = ?", memberID).Find(&accounts).Error; err != nil {
+				return err
+			}
+			for i := range accounts {
+				if err := s.cascadeAccount(tx, &accounts[i]); err != nil {
+					return err
+				}
+				if err := tx.Delete(&accounts[i]).Error; err != nil {
+					return err
+				}
+			}
+			// sisa data member di akun milik member lain
+			if err := s.deleteTransactions(tx, tx.Where("member_id = ?", memberID), true, ""); err != nil {
+				return err
+			}
+			if err := tx.Where("member_id = ?", memberID).Delete(&models.RecurringTransaction{}).Error; err != nil {
+				return err
+			}
+			if err := s.deleteSavingTargets(tx, tx.Where("member_id = ?", memberID), ""); err != nil {
+				return err
+			}
+			if err := s.deleteTransfers(tx, tx.Where("member_id = ?", memberID), 0, ""); err != nil {
+				return err
+			}
+			if err := tx.Where("member_id = ?", memberID).Delete(&models.Allowance{}).Error; err != nil {
+				return err
+			}
+			// pinjaman dan aset member menjadi milik bersama
+			for _, model := range []interface{}{&models.Loan{}, &models.Asset{}} {
+				if err := tx.Model(model).Where("member_id = ?", memberID).
+					Update("member_id", nil).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return tx.Delete(&member).Error
+	})
+}
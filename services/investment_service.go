@@ -0,0 +1,791 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"finance-app/models"
+	"finance-app/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type InvestmentService struct {
+	db *gorm.DB
+}
+
+func NewInvestmentService(db *gorm.DB) *InvestmentService {
+	return &InvestmentService{db: db}
+}
+
+/* ===========================
+   Securities
+=========================== */
+
+func isSecurityKind(kind string) bool {
+	for _, k := range models.SecurityKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateSecurity menormalkan simbol dan memastikan simbol unik di ledger.
+func (s *InvestmentService) ValidateSecurity(sec *models.Security) error {
+	sec.Symbol = strings.ToUpper(strings.TrimSpace(sec.Symbol))
+	if sec.Symbol == "" {
+		return utils.NewAppError("Symbol is required", http.StatusBadRequest)
+	}
+	if sec.Kind == "" {
+		sec.Kind = "other"
+	}
+	if !isSecurityKind(sec.Kind) {
+		return &utils.AppError{
+			Message:    "Invalid security kind",
+			StatusCode: http.StatusBadRequest,
+			Details:    map[string]interface{}{"allowed": models.SecurityKinds},
+		}
+	}
+	// unscoped: unique index (user_id, symbol) juga berlaku untuk baris yang soft-deleted
+	var cnt int64
+	if err := s.db.Unscoped().Model(&models.Security{}).
+		Where("user_id = ? AND symbol = ? AND id <> ?", sec.UserID, sec.Symbol, sec.ID).
+		Count(&cnt).Error; err != nil {
+		return err
+	}
+	if cnt > 0 {
+		return &utils.AppError{
+			Message:    "Symbol already exists",
+			StatusCode: http.StatusConflict,
+			Code:       "DUPLICATE_SYMBOL",
+		}
+	}
+	return nil
+}
+
+// DeleteSecurity menghapus permanen instrumen beserta riwayat harganya, supaya
+// simbolnya bisa dipakai lagi. Instrumen yang masih punya lot atau dividen tidak bisa dihapus.
+func (s *InvestmentService) DeleteSecurity(sec *models.Security) error {
+	deps, err := countDependents([]dependentCounter{
+		{"investment_lots", s.db.Model(&models.InvestmentLot{}).Where("security_id = ?", sec.ID)},
+		{"investment_dividends", s.db.Model(&models.InvestmentDividend{}).Where("security_id = ?", sec.ID)},
+	})
+	if err != nil {
+		return err
+	}
+	if len(deps) > 0 {
+		return &utils.AppError{
+			Message:    "Security still has lots or dividends",
+			StatusCode: http.StatusConflict,
+			Code:       "HAS_DEPENDENTS",
+			Details:    deps,
+		}
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("security_id = ?", sec.ID).Delete(&models.SecurityPrice{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(sec).Error
+	})
+}
+
+/* ===========================
+   Prices
+=========================== */
+
+// AddPrice mencatat harga instrumen pada suatu tanggal; harga di tanggal yang sama ditimpa.
+func (s *InvestmentService) AddPrice(sec *models.Security, date string, price float64) (*models.SecurityPrice, error) {
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return nil, utils.NewAppError("Invalid date format, use YYYY-MM-DD", http.StatusBadRequest)
+	}
+	if price < 0 {
+		return nil, utils.NewAppError("Price cannot be negative", http.StatusBadRequest)
+	}
+
+	row := models.SecurityPrice{UserID: sec.UserID, SecurityID: sec.ID, Date: date, Price: price}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := upsertPrices(tx, []models.SecurityPrice{row}); err != nil {
+			return err
+		}
+		if err := tx.Where("security_id = ? AND date = ?", sec.ID, date).First(&row).Error; err != nil {
+			return err
+		}
+		return afterPriceChange(tx, []uint{sec.ID})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
+
+// DeletePrice menghapus satu harga lalu menghitung ulang nilai portofolio.
+func (s *InvestmentService) DeletePrice(sec *models.Security, priceID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("security_id = ? AND id = ?", sec.ID, priceID).Delete(&models.SecurityPrice{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return afterPriceChange(tx, []uint{sec.ID})
+	})
+}
+
+func upsertPrices(tx *gorm.DB, rows []models.SecurityPrice) error {
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "security_id"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"price"}),
+	}).CreateInBatches(rows, 500).Error
+}
+
+// afterPriceChange memperbarui harga terakhir instrumen dan nilai portofolio
+// setiap akun yang pernah memegangnya.
+func afterPriceChange(tx *gorm.DB, securityIDs []uint) error {
+	for _, id := range securityIDs {
+		var latest models.SecurityPrice
+		err := tx.Where("security_id = ?", id).Order("date DESC").First(&latest).Error
+		updates := map[string]interface{}{"last_price": 0, "last_price_date": ""}
+		if err == nil {
+			updates = map[string]interface{}{"last_price": latest.Price, "last_price_date": latest.Date}
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err := tx.Model(&models.Security{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+
+	var accountIDs []uint
+	if err := tx.Model(&models.InvestmentLot{}).Distinct("account_id").
+		Where("security_id IN ?", securityIDs).Pluck("account_id", &accountIDs).Error; err != nil {
+		return err
+	}
+	for _, id := range accountIDs {
+		if err := syncPortfolio(tx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PriceImportError menjelaskan baris CSV yang dilewati.
+type PriceImportError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+type PriceImportResult struct {
+	Imported int                `json:"imported"`
+	Skipped  int                `json:"skipped"`
+	Errors   []PriceImportError `json:"errors,omitempty"`
+}
+
+// maxPriceImportErrors membatasi jumlah error baris yang dikembalikan.
+const maxPriceImportErrors = 100
+
+// ImportPrices membaca CSV harga dengan kolom symbol,date,price. Header opsional;
+// kalau ada, urutan kolom boleh bebas. Baris yang tidak valid atau simbolnya
+// tidak dikenal dilewati dan dilaporkan, baris lainnya tetap diimpor.
+func (s *InvestmentService) ImportPrices(userID uint, r io.Reader) (*PriceImportResult, error) {
+	var securities []models.Security
+	if err := s.db.Where("user_id = ?", userID).Find(&securities).Error; err != nil {
+		return nil, err
+	}
+	bySymbol := map[string]uint{}
+	for _, sec := range securities {
+		bySymbol[sec.Symbol] = sec.ID
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	result := &PriceImportResult{}
+	skip := func(line int, msg string) {
+		result.Skipped++
+		if len(result.Errors) < maxPriceImportErrors {
+			result.Errors = append(result.Errors, PriceImportError{Line: line, Message: msg})
+		}
+	}
+
+	cols := map[string]int{"symbol": 0, "date": 1, "price": 2}
+	rows := map[string]models.SecurityPrice{} // baris terakhir menang untuk simbol+tanggal yang sama
+	touched := map[uint]bool{}
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, utils.NewAppError("Invalid CSV: "+err.Error(), http.StatusBadRequest)
+		}
+
+		if line == 1 {
+			header := map[string]int{}
+			for i, name := range record {
+				header[strings.ToLower(strings.TrimSpace(name))] = i
+			}
+			if _, ok := header["symbol"]; ok {
+				for _, name := range []string{"date", "price"} {
+					if _, ok := header[name]; !ok {
+						return nil, utils.NewAppError("CSV header must contain symbol, date and price", http.StatusBadRequest)
+					}
+				}
+				cols = header
+				continue
+			}
+		}
+
+		field := func(name string) string {
+			if i := cols[name]; i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		symbol := strings.ToUpper(field("symbol"))
+		securityID, ok := bySymbol[symbol]
+		if !ok {
+			skip(line, fmt.Sprintf("unknown symbol %q", symbol))
+			continue
+		}
+		date := field("date")
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			skip(line, fmt.Sprintf("invalid date %q, use YYYY-MM-DD", date))
+			continue
+		}
+		price, err := strconv.ParseFloat(strings.ReplaceAll(field("price"), ",", ""), 64)
+		if err != nil || price < 0 {
+			skip(line, fmt.Sprintf("invalid price %q", field("price")))
+			continue
+		}
+
+		rows[fmt.Sprintf("%d|%s", securityID, date)] = models.SecurityPrice{
+			UserID: userID, SecurityID: securityID, Date: date, Price: price,
+		}
+		touched[securityID] = true
+	}
+
+	if len(rows) == 0 {
+		return result, nil
+	}
+	prices := make([]models.SecurityPrice, 0, len(rows))
+	for _, p := range rows {
+		prices = append(prices, p)
+	}
+	ids := make([]uint, 0, len(touched))
+	for id := range touched {
+		ids = append(ids, id)
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := upsertPrices(tx, prices); err != nil {
+			return err
+		}
+		return afterPriceChange(tx, ids)
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Imported = len(prices)
+	return result, nil
+}
+
+/* ===========================
+   Lots
+=========================== */
+
+// findInvestmentAccount memastikan akun milik ledger dan bertipe Investment.
+func findInvestmentAccount(db *gorm.DB, userID, accountID uint) (*models.Account, error) {
+	var acc models.Account
+	err := db.Joins("JOIN members ON members.id = accounts.member_id").
+		Where("accounts.id = ? AND members.user_id = ?", accountID, userID).
+		First(&acc).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, utils.NewAppError("Account not found", http.StatusNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if acc.Type != models.AccountTypeInvestment {
+		return nil, utils.NewAppError("Account is not an investment account", http.StatusBadRequest)
+	}
+	return &acc, nil
+}
+
+func (s *InvestmentService) findSecurity(userID, securityID uint) (*models.Security, error) {
+	var sec models.Security
+	err := s.db.Where("user_id = ? AND id = ?", userID, securityID).First(&sec).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, utils.NewAppError("Security not found", http.StatusNotFound)
+	}
+	return &sec, err
+}
+
+// CreateLot mencatat pembelian / penjualan dan memindahkan kas akun investasi.
+func (s *InvestmentService) CreateLot(lot *models.InvestmentLot) error {
+	if lot.Side != models.LotSideBuy && lot.Side != models.LotSideSell {
+		return utils.NewAppError("Side must be buy or sell", http.StatusBadRequest)
+	}
+	if _, err := time.Parse("2006-01-02", lot.Date); err != nil {
+		return utils.NewAppError("Invalid date format, use YYYY-MM-DD", http.StatusBadRequest)
+	}
+	if lot.Quantity <= 0 {
+		return utils.NewAppError("Quantity must be greater than zero", http.StatusBadRequest)
+	}
+	if lot.Price < 0 || lot.Fee < 0 {
+		return utils.NewAppError("Price and fee cannot be negative", http.StatusBadRequest)
+	}
+	acc, err := findInvestmentAccount(s.db, lot.UserID, lot.AccountID)
+	if err != nil {
+		return err
+	}
+	if err := EnsureAccountActive(s.db, acc.ID); err != nil {
+		return err
+	}
+	if _, err := s.findSecurity(lot.UserID, lot.SecurityID); err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Security").Create(lot).Error; err != nil {
+			return err
+		}
+		if err := applyLotCash(tx, lot, true); err != nil {
+			return err
+		}
+		if err := checkAccountHoldings(tx, lot.AccountID, lot.SecurityID); err != nil {
+			return err
+		}
+		return syncPortfolio(tx, lot.AccountID)
+	})
+}
+
+// DeleteLot menghapus lot dan membatalkan efek kasnya.
+func (s *InvestmentService) DeleteLot(lot *models.InvestmentLot) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := applyLotCash(tx, lot, false); err != nil {
+			return err
+		}
+		if err := tx.Delete(lot).Error; err != nil {
+			return err
+		}
+		if err := checkAccountHoldings(tx, lot.AccountID, lot.SecurityID); err != nil {
+			return err
+		}
+		return syncPortfolio(tx, lot.AccountID)
+	})
+}
+
+// applyLotCash memakai aturan saldo transaksi: pembelian seperti pengeluaran
+// (ditolak kalau kas kurang), penjualan seperti pemasukan.
+func applyLotCash(tx *gorm.DB, lot *models.InvestmentLot, apply bool) error {
+	amount := lotCash(lot)
+	tType := "income"
+	if amount < 0 {
+		tType, amount = "expense", -amount
+	}
+	return NewTransactionService(tx).adjustAccountBalance(tx, lot.AccountID, tType, amount, apply)
+}
+
+func checkAccountHoldings(tx *gorm.DB, accountID, securityID uint) error {
+	var lots []models.InvestmentLot
+	if err := tx.Where("account_id = ? AND security_id = ?", accountID, securityID).Find(&lots).Error; err != nil {
+		return err
+	}
+	return checkHoldings(lots)
+}
+
+/* ===========================
+   Dividends
+=========================== */
+
+type DividendInput struct {
+	AccountID   uint
+	SecurityID  uint
+	CategoryID  uint
+	Date        string
+	Amount      float64
+	Description string
+}
+
+// RecordDividend mencatat dividen / kupon sebagai transaksi pemasukan di akun
+// investasi dan menautkannya ke instrumennya.
+func (s *InvestmentService) RecordDividend(userID uint, in DividendInput) (*models.InvestmentDividend, error) {
+	if in.Amount <= 0 {
+		return nil, utils.NewAppError("Amount must be greater than zero", http.StatusBadRequest)
+	}
+	acc, err := findInvestmentAccount(s.db, userID, in.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	sec, err := s.findSecurity(userID, in.SecurityID)
+	if err != nil {
+		return nil, err
+	}
+	if in.Date == "" {
+		in.Date = time.Now().Format("2006-01-02")
+	}
+	if in.Description == "" {
+		in.Description = "Dividen " + sec.Symbol
+	}
+
+	var dividend models.InvestmentDividend
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		trx, err := NewTransactionService(tx).Create(userID, map[string]interface{}{
+			"date":        in.Date,
+			"member_id":   float64(acc.MemberID),
+			"account_id":  float64(acc.ID),
+			"category_id": float64(in.CategoryID),
+			"amount":      in.Amount,
+			"description": in.Description,
+			"type":        "income",
+		})
+		if err != nil {
+			return err
+		}
+		dividend = models.InvestmentDividend{
+			UserID: userID, AccountID: acc.ID, SecurityID: sec.ID,
+			TransactionID: trx.ID, Date: trx.Date, Amount: trx.Amount,
+		}
+		return tx.Omit("Security").Create(&dividend).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	dividend.Security = *sec
+	return &dividend, nil
+}
+
+// releaseInvestmentDividends menghapus catatan dividen saat transaksinya dihapus.
+func releaseInvestmentDividends(tx *gorm.DB, transactionID uint, batch string) error {
+	if err := stampBatch(tx.Model(&models.InvestmentDividend{}).Where("transaction_id = ?", transactionID), batch); err != nil {
+		return err
+	}
+	return tx.Where("transaction_id = ?", transactionID).Delete(&models.InvestmentDividend{}).Error
+}
+
+// refreshInvestmentDividends menyalin tanggal dan nominal transaksi yang diedit
+// ke catatan dividennya.
+func refreshInvestmentDividends(tx *gorm.DB, trx *models.Transaction) error {
+	return tx.Model(&models.InvestmentDividend{}).Where("transaction_id = ?", trx.ID).
+		Updates(map[string]interface{}{"date": trx.Date, "amount": trx.Amount}).Error
+}
+
+/* ===========================
+   Net worth
+=========================== */
+
+// syncPortfolio menulis ulang riwayat nilai pasar portofolio akun investasi ke
+// Asset-nya (dibuat otomatis saat pertama kali ada lot), supaya ikut di net worth.
+func syncPortfolio(tx *gorm.DB, accountID uint) error {
+	var acc models.Account
+	if err := tx.First(&acc, accountID).Error; err != nil {
+		return err
+	}
+
+	var lots []models.InvestmentLot
+	if err := tx.Where("account_id = ?", accountID).Find(&lots).Error; err != nil {
+		return err
+	}
+	if acc.PortfolioAssetID == nil && len(lots) == 0 {
+		return nil
+	}
+
+	var asset models.Asset
+	if acc.PortfolioAssetID != nil {
+		err := tx.First(&asset, *acc.PortfolioAssetID).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+	}
+	if asset.ID == 0 {
+		// belum ada, atau Asset-nya terhapus manual lewat /assets
+		var member models.Member
+		if err := tx.First(&member, acc.MemberID).Error; err != nil {
+			return err
+		}
+		asset = models.Asset{
+			UserID:   member.UserID,
+			MemberID: &acc.MemberID,
+			Kind:     models.AssetKindAsset,
+			Category: "investment",
+			Name:     "Portofolio " + acc.Name,
+			Currency: acc.Currency,
+			Notes:    "Dikelola otomatis dari akun investasi",
+		}
+		if err := tx.Create(&asset).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&acc).Update("portfolio_asset_id", asset.ID).Error; err != nil {
+			return err
+		}
+	}
+
+	valuations := []models.AssetValuation{}
+	if len(lots) > 0 {
+		sortLots(lots)
+		securityIDs := []uint{}
+		seen := map[uint]bool{}
+		for _, l := range lots {
+			if !seen[l.SecurityID] {
+				seen[l.SecurityID] = true
+				securityIDs = append(securityIDs, l.SecurityID)
+			}
+		}
+		var prices []models.SecurityPrice
+		if err := tx.Where("security_id IN ? AND date >= ?", securityIDs, lots[0].Date).
+			Find(&prices).Error; err != nil {
+			return err
+		}
+		book := newPriceBook(prices, lots)
+
+		dateSet := map[string]bool{}
+		for _, l := range lots {
+			dateSet[l.Date] = true
+		}
+		for _, p := range prices {
+			dateSet[p.Date] = true
+		}
+		dates := make([]string, 0, len(dateSet))
+		for d := range dateSet {
+			dates = append(dates, d)
+		}
+		sort.Strings(dates)
+
+		quantities := map[uint]float64{}
+		next := 0
+		last := -1.0
+		for _, date := range dates {
+			for next < len(lots) && lots[next].Date <= date {
+				if lots[next].Side == models.LotSideBuy {
+					quantities[lots[next].SecurityID] += lots[next].Quantity
+				} else {
+					quantities[lots[next].SecurityID] -= lots[next].Quantity
+				}
+				next++
+			}
+			value := holdingsValue(quantities, book, date)
+			if value == last {
+				continue
+			}
+			last = value
+			valuations = append(valuations, models.AssetValuation{
+				UserID: asset.UserID, AssetID: asset.ID, Date: date, Value: value, Note: "Nilai pasar portofolio",
+			})
+		}
+	}
+
+	if err := tx.Unscoped().Where("asset_id = ?", asset.ID).Delete(&models.AssetValuation{}).Error; err != nil {
+		return err
+	}
+	if len(valuations) > 0 {
+		if err := tx.CreateInBatches(valuations, 500).Error; err != nil {
+			return err
+		}
+	}
+	if err := tx.Model(&asset).Updates(map[string]interface{}{
+		"member_id": acc.MemberID,
+		"name":      "Portofolio " + acc.Name,
+	}).Error; err != nil {
+		return err
+	}
+	return refreshAssetValue(tx, asset.ID)
+}
+
+// deleteInvestments menghapus lot, dividen dan Asset portofolio milik akun.
+func deleteInvestments(tx *gorm.DB, account *models.Account, batch string) error {
+	for _, model := range []interface{}{&models.InvestmentLot{}, &models.InvestmentDividend{}} {
+		if err := stampBatch(tx.Model(model).Where("account_id = ?", account.ID), batch); err != nil {
+			return err
+		}
+		if err := tx.Where("account_id = ?", account.ID).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	if account.PortfolioAssetID == nil {
+		return nil
+	}
+	// valuasi tidak ditandai: ditulis ulang oleh syncPortfolio saat akun dipulihkan
+	if err := tx.Where("asset_id = ?", *account.PortfolioAssetID).Delete(&models.AssetValuation{}).Error; err != nil {
+		return err
+	}
+	if err := stampBatch(tx.Model(&models.Asset{}).Where("id = ?", *account.PortfolioAssetID), batch); err != nil {
+		return err
+	}
+	return tx.Delete(&models.Asset{}, *account.PortfolioAssetID).Error
+}
+
+/* ===========================
+   Portfolio
+=========================== */
+
+// Portfolio menghitung posisi per akun dan instrumen pada tanggal AsOf: unit,
+// harga perolehan (FIFO atau rata-rata), nilai pasar, unrealized gain, serta
+// realized gain dan dividen dalam periode From..AsOf.
+func (s *InvestmentService) Portfolio(userID uint, q PortfolioQuery) (*Portfolio, error) {
+	if q.AsOf == "" {
+		q.AsOf = time.Now().Format("2006-01-02")
+	}
+	if _, err := time.Parse("2006-01-02", q.AsOf); err != nil {
+		return nil, utils.NewAppError("Invalid as_of format, use YYYY-MM-DD", http.StatusBadRequest)
+	}
+	if q.From != "" {
+		if _, err := time.Parse("2006-01-02", q.From); err != nil {
+			return nil, utils.NewAppError("Invalid from format, use YYYY-MM-DD", http.StatusBadRequest)
+		}
+	}
+	if q.Method == "" {
+		q.Method = models.CostMethodFIFO
+	}
+	if q.Method != models.CostMethodFIFO && q.Method != models.CostMethodAverage {
+		return nil, utils.NewAppError("Method must be fifo or average", http.StatusBadRequest)
+	}
+
+	accQuery := s.db.Joins("JOIN members ON members.id = accounts.member_id").
+		Where("members.user_id = ? AND accounts.type = ?", userID, models.AccountTypeInvestment)
+	if q.AccountID != nil {
+		accQuery = accQuery.Where("accounts.id = ?", *q.AccountID)
+	}
+	if q.MemberID != nil {
+		accQuery = accQuery.Where("accounts.member_id = ?", *q.MemberID)
+	}
+	var accounts []models.Account
+	if err := accQuery.Order("accounts.name").Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+
+	p := &Portfolio{AsOf: q.AsOf, From: q.From, Method: q.Method, Positions: []Position{}, Accounts: []PortfolioAccount{}}
+	if len(accounts) == 0 {
+		return p, nil
+	}
+	accountIDs := make([]uint, 0, len(accounts))
+	for _, a := range accounts {
+		accountIDs = append(accountIDs, a.ID)
+	}
+
+	var lots []models.InvestmentLot
+	if err := s.db.Where("account_id IN ? AND date <= ?", accountIDs, q.AsOf).Find(&lots).Error; err != nil {
+		return nil, err
+	}
+	sortLots(lots)
+	divQuery := s.db.Where("account_id IN ? AND date <= ?", accountIDs, q.AsOf)
+	if q.From != "" {
+		divQuery = divQuery.Where("date >= ?", q.From)
+	}
+	var dividends []models.InvestmentDividend
+	if err := divQuery.Find(&dividends).Error; err != nil {
+		return nil, err
+	}
+
+	var securities []models.Security
+	if err := s.db.Where("user_id = ?", userID).Find(&securities).Error; err != nil {
+		return nil, err
+	}
+	securityByID := map[uint]models.Security{}
+	securityIDs := make([]uint, 0, len(securities))
+	for _, sec := range securities {
+		securityByID[sec.ID] = sec
+		securityIDs = append(securityIDs, sec.ID)
+	}
+	var prices []models.SecurityPrice
+	if err := s.db.Where("security_id IN ? AND date <= ?", securityIDs, q.AsOf).Find(&prices).Error; err != nil {
+		return nil, err
+	}
+	book := newPriceBook(prices, lots)
+
+	type key struct{ account, security uint }
+	trackers := map[key]*costTracker{}
+	positions := map[key]*Position{}
+	order := []key{}
+	position := func(k key) *Position {
+		pos, ok := positions[k]
+		if !ok {
+			sec := securityByID[k.security]
+			pos = &Position{AccountID: k.account, SecurityID: k.security, Symbol: sec.Symbol, Name: sec.Name, Kind: sec.Kind}
+			positions[k] = pos
+			trackers[k] = newCostTracker(q.Method)
+			order = append(order, k)
+		}
+		return pos
+	}
+
+	for i := range lots {
+		k := key{lots[i].AccountID, lots[i].SecurityID}
+		pos := position(k)
+		gain, err := trackers[k].apply(&lots[i])
+		if err != nil {
+			return nil, err
+		}
+		if lots[i].Side == models.LotSideSell && (q.From == "" || lots[i].Date >= q.From) {
+			pos.RealizedGain += gain
+		}
+	}
+	for _, d := range dividends {
+		position(key{d.AccountID, d.SecurityID}).Dividends += d.Amount
+	}
+
+	marketByAccount := map[uint]float64{}
+	for _, k := range order {
+		pos := positions[k]
+		t := trackers[k]
+		pos.Quantity = t.quantity
+		pos.CostBasis = round2(t.cost)
+		if t.quantity > 0 {
+			pos.AverageCost = t.cost / t.quantity
+		}
+		if pp, ok := book.at(k.security, q.AsOf); ok {
+			pos.Price, pos.PriceDate = pp.price, pp.date
+		}
+		pos.MarketValue = round2(pos.Quantity * pos.Price)
+		pos.UnrealizedGain = round2(pos.MarketValue - pos.CostBasis)
+		if pos.CostBasis > 0 {
+			pos.UnrealizedPct = round2(pos.UnrealizedGain / pos.CostBasis * 100)
+		}
+		pos.RealizedGain = round2(pos.RealizedGain)
+		pos.Dividends = round2(pos.Dividends)
+		if pos.Quantity == 0 && pos.RealizedGain == 0 && pos.Dividends == 0 {
+			continue
+		}
+
+		p.Positions = append(p.Positions, *pos)
+		marketByAccount[k.account] += pos.MarketValue
+		p.Totals.MarketValue += pos.MarketValue
+		p.Totals.CostBasis += pos.CostBasis
+		p.Totals.UnrealizedGain += pos.UnrealizedGain
+		p.Totals.RealizedGain += pos.RealizedGain
+		p.Totals.Dividends += pos.Dividends
+	}
+	sort.SliceStable(p.Positions, func(i, j int) bool {
+		if p.Positions[i].AccountID != p.Positions[j].AccountID {
+			return p.Positions[i].AccountID < p.Positions[j].AccountID
+		}
+		return p.Positions[i].Symbol < p.Positions[j].Symbol
+	})
+
+	// kas memakai saldo akun saat ini
+	for _, a := range accounts {
+		market := round2(marketByAccount[a.ID])
+		p.Accounts = append(p.Accounts, PortfolioAccount{
+			AccountID: a.ID, Name: a.Name, Cash: a.Balance, MarketValue: market, Total: round2(a.Balance + market),
+		})
+		p.Totals.Cash += a.Balance
+	}
+	p.Totals.MarketValue = round2(p.Totals.MarketValue)
+	p.Totals.CostBasis = round2(p.Totals.CostBasis)
+	p.Totals.UnrealizedGain = round2(p.Totals.UnrealizedGain)
+	p.Totals.RealizedGain = round2(p.Totals.RealizedGain)
+	p.Totals.Dividends = round2(p.Totals.Dividends)
+	p.Totals.Cash = round2(p.Totals.Cash)
+	p.Totals.Total = round2(p.Totals.Cash + p.Totals.MarketValue)
+	return p, nil
+}
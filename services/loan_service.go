@@ -0,0 +1,697 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"finance-app/models"
+	"finance-app/utils"
+
+	"gorm.io/gorm"
+)
+
+type LoanService struct {
+	db *gorm.DB
+}
+
+func NewLoanService(db *gorm.DB) *LoanService {
+	return &LoanService{db: db}
+}
+
+// addMonths menambah n bulan ke tanggal; tanggal yang tidak ada di bulan
+// tujuan (contoh 31 Februari) dibulatkan ke akhir bulan.
+func addMonths(date time.Time, n int) time.Time {
+	first := time.Date(date.Year(), date.Month()+time.Month(n), 1, 0, 0, 0, 0, time.UTC)
+	lastDay := first.AddDate(0, 1, -1).Day()
+	day := date.Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return time.Date(first.Year(), first.Month(), day, 0, 0, 0, 0, time.UTC)
+}
+
+// BuildSchedule menghitung jadwal angsuran. Annuity memakai cicilan tetap dengan
+// bunga dari sisa pokok; flat memakai bunga tetap dari pokok awal. Selisih
+// pembulatan diserap angsuran terakhir.
+func BuildSchedule(loan *models.Loan) ([]models.LoanInstallment, error) {
+	start, err := time.Parse("2006-01-02", loan.StartDate)
+	if err != nil {
+		return nil, utils.NewAppError("Invalid start_date format, use YYYY-MM-DD", http.StatusBadRequest)
+	}
+
+	n := loan.TenorMonths
+	rate := loan.AnnualRate / 100 / 12
+	balance := loan.Principal
+
+	var payment, flatInterest, flatPrincipal float64
+	switch loan.Method {
+	case models.LoanMethodAnnuity:
+		if rate == 0 {
+			payment = round2(loan.Principal / float64(n))
+		} else {
+			payment = round2(loan.Principal * rate / (1 - math.Pow(1+rate, -float64(n))))
+		}
+	case models.LoanMethodFlat:
+		flatInterest = round2(loan.Principal * rate)
+		flatPrincipal = round2(loan.Principal / float64(n))
+	default:
+		return nil, utils.NewAppError("Method must be annuity or flat", http.StatusBadRequest)
+	}
+
+	rows := make([]models.LoanInstallment, 0, n)
+	for i := 1; i <= n; i++ {
+		var principal, interest float64
+		if loan.Method == models.LoanMethodAnnuity {
+			interest = round2(balance * rate)
+			principal = round2(payment - interest)
+		} else {
+			interest = flatInterest
+			principal = flatPrincipal
+		}
+		if i == n || principal > balance {
+			principal = balance
+		}
+		balance = round2(balance - principal)
+
+		rows = append(rows, models.LoanInstallment{
+			UserID:       loan.UserID,
+			LoanID:       loan.ID,
+			Number:       i,
+			DueDate:      addMonths(start, i).Format("2006-01-02"),
+			Principal:    principal,
+			Interest:     interest,
+			Total:        round2(principal + interest),
+			BalanceAfter: balance,
+		})
+	}
+	return rows, nil
+}
+
+// Validate memastikan angka pinjaman masuk akal dan relasinya milik ledger.
+func (s *LoanService) Validate(loan *models.Loan) error {
+	if loan.Name == "" {
+		return utils.NewAppError("Name is required", http.StatusBadRequest)
+	}
+	if loan.Principal <= 0 {
+		return utils.NewAppError("Principal must be greater than zero", http.StatusBadRequest)
+	}
+	if loan.AnnualRate < 0 {
+		return utils.NewAppError("Interest rate cannot be negative", http.StatusBadRequest)
+	}
+	if loan.TenorMonths <= 0 || loan.TenorMonths > 600 {
+		return utils.NewAppError("Tenor must be between 1 and 600 months", http.StatusBadRequest)
+	}
+	if loan.Method == "" {
+		loan.Method = models.LoanMethodAnnuity
+	}
+	if loan.Method != models.LoanMethodAnnuity && loan.Method != models.LoanMethodFlat {
+		return utils.NewAppError("Method must be annuity or flat", http.StatusBadRequest)
+	}
+	if loan.Category == "" {
+		loan.Category = "loan"
+	}
+	if !isAssetCategory(models.AssetKindLiability, loan.Category) {
+		return &utils.AppError{
+			Message:    "Invalid liability category",
+			StatusCode: http.StatusBadRequest,
+			Details:    map[string]interface{}{"allowed": models.AssetCategories[models.AssetKindLiability]},
+		}
+	}
+	if _, err := time.Parse("2006-01-02", loan.StartDate); err != nil {
+		return utils.NewAppError("Invalid start_date format, use YYYY-MM-DD", http.StatusBadRequest)
+	}
+
+	var cnt int64
+	if loan.MemberID != nil {
+		s.db.Model(&models.Member{}).Where("user_id = ? AND id = ?", loan.UserID, *loan.MemberID).Count(&cnt)
+		if cnt == 0 {
+			return utils.NewAppError("Member not found", http.StatusNotFound)
+		}
+	}
+	if loan.AccountID != nil {
+		if _, err := s.findAccount(loan.UserID, *loan.AccountID); err != nil {
+			return err
+		}
+	}
+	if loan.CategoryID != nil {
+		cnt = 0
+		s.db.Model(&models.Category{}).
+			Where("user_id = ? AND id = ? AND type = ?", loan.UserID, *loan.CategoryID, "expense").
+			Count(&cnt)
+		if cnt == 0 {
+			return utils.NewAppError("Expense category not found", http.StatusNotFound)
+		}
+	}
+	return nil
+}
+
+func (s *LoanService) findAccount(userID, accountID uint) (*models.Account, error) {
+	var acc models.Account
+	err := s.db.Joins("JOIN members ON members.id = accounts.member_id").
+		Where("accounts.id = ? AND members.user_id = ?", accountID, userID).
+		First(&acc).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, utils.NewAppError("Account not found", http.StatusNotFound)
+	}
+	return &acc, err
+}
+
+// Create menyimpan pinjaman, jadwal angsurannya dan kewajiban di net worth.
+func (s *LoanService) Create(loan *models.Loan) error {
+	if err := s.Validate(loan); err != nil {
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		loan.OutstandingPrincipal = loan.Principal
+		loan.Status = models.LoanStatusActive
+		if err := tx.Omit("Installments").Create(loan).Error; err != nil {
+			return err
+		}
+
+		asset := models.Asset{
+			UserID:   loan.UserID,
+			MemberID: loan.MemberID,
+			Kind:     models.AssetKindLiability,
+			Category: loan.Category,
+			Name:     loan.Name,
+			Notes:    "Dikelola otomatis dari pinjaman",
+		}
+		if err := tx.Create(&asset).Error; err != nil {
+			return err
+		}
+		loan.AssetID = &asset.ID
+		if err := tx.Model(loan).Update("asset_id", asset.ID).Error; err != nil {
+			return err
+		}
+
+		if err := s.createSchedule(tx, loan); err != nil {
+			return err
+		}
+		return syncLoan(tx, loan.ID)
+	})
+}
+
+func (s *LoanService) createSchedule(tx *gorm.DB, loan *models.Loan) error {
+	rows, err := BuildSchedule(loan)
+	if err != nil {
+		return err
+	}
+	return tx.CreateInBatches(rows, 100).Error
+}
+
+// Update mengubah detail pinjaman. Pokok, bunga, tenor, tanggal mulai dan metode
+// hanya boleh diubah selama belum ada angsuran yang dibayar; jadwal lalu dibuat ulang.
+func (s *LoanService) Update(loan *models.Loan, scheduleChanged bool) error {
+	if err := s.Validate(loan); err != nil {
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if scheduleChanged {
+			var paid int64
+			if err := tx.Model(&models.LoanInstallment{}).
+				Where("loan_id = ? AND paid_date IS NOT NULL", loan.ID).Count(&paid).Error; err != nil {
+				return err
+			}
+			if paid > 0 {
+				return &utils.AppError{
+					Message:    "Loan terms cannot change after an installment has been paid",
+					StatusCode: http.StatusConflict,
+					Code:       "LOAN_HAS_PAYMENTS",
+				}
+			}
+			if err := tx.Where("loan_id = ?", loan.ID).Delete(&models.LoanInstallment{}).Error; err != nil {
+				return err
+			}
+			if err := s.createSchedule(tx, loan); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Omit("Installments").Save(loan).Error; err != nil {
+			return err
+		}
+		if loan.AssetID != nil {
+			if err := tx.Model(&models.Asset{}).Where("id = ?", *loan.AssetID).Updates(map[string]interface{}{
+				"member_id": loan.MemberID,
+				"category":  loan.Category,
+				"name":      loan.Name,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return syncLoan(tx, loan.ID)
+	})
+}
+
+// Delete menghapus pinjaman, jadwalnya dan kewajibannya. Transaksi pembayaran
+// tetap ada di ledger.
+func (s *LoanService) Delete(loan *models.Loan) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("loan_id = ?", loan.ID).Delete(&models.LoanInstallment{}).Error; err != nil {
+			return err
+		}
+		if loan.AssetID != nil {
+			if err := tx.Where("asset_id = ?", *loan.AssetID).Delete(&models.AssetValuation{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&models.Asset{}, *loan.AssetID).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(loan).Error
+	})
+}
+
+/* ===========================
+   Status
+=========================== */
+
+// InstallmentStatus mengembalikan paid, overdue, due atau upcoming relatif ke today.
+func InstallmentStatus(inst *models.LoanInstallment, today string) string {
+	switch {
+	case inst.PaidDate != nil:
+		return models.InstallmentStatusPaid
+	case inst.DueDate < today:
+		return models.InstallmentStatusOverdue
+	case inst.DueDate == today:
+		return models.InstallmentStatusDue
+	default:
+		return models.InstallmentStatusUpcoming
+	}
+}
+
+// Summarize mengisi status tiap angsuran serta ringkasan tunggakan dan
+// jatuh tempo berikutnya. Installments harus urut berdasarkan nomor.
+func Summarize(loan *models.Loan, installments []models.LoanInstallment, today string) {
+	loan.OverdueCount = 0
+	loan.NextDueDate = ""
+	loan.NextDueTotal = 0
+	for i := range installments {
+		inst := &installments[i]
+		inst.Status = InstallmentStatus(inst, today)
+		if inst.Status == models.InstallmentStatusOverdue {
+			loan.OverdueCount++
+		}
+		if inst.PaidDate == nil && loan.NextDueDate == "" {
+			loan.NextDueDate = inst.DueDate
+			loan.NextDueTotal = inst.Total
+		}
+	}
+}
+
+// Schedule mengambil jadwal angsuran lengkap dengan statusnya.
+func (s *LoanService) Schedule(loan *models.Loan, today string) ([]models.LoanInstallment, error) {
+	var installments []models.LoanInstallment
+	if err := s.db.Where("loan_id = ?", loan.ID).Order("number").Find(&installments).Error; err != nil {
+		return nil, err
+	}
+	Summarize(loan, installments, today)
+	return installments, nil
+}
+
+// SummarizeAll mengisi ringkasan status untuk banyak pinjaman sekaligus.
+func (s *LoanService) SummarizeAll(loans []models.Loan, today string) error {
+	if len(loans) == 0 {
+		return nil
+	}
+	ids := make([]uint, 0, len(loans))
+	for _, l := range loans {
+		ids = append(ids, l.ID)
+	}
+	var installments []models.LoanInstallment
+	if err := s.db.Where("loan_id IN ?", ids).Order("loan_id, number").Find(&installments).Error; err != nil {
+		return err
+	}
+	byLoan := map[uint][]models.LoanInstallment{}
+	for _, inst := range installments {
+		byLoan[inst.LoanID] = append(byLoan[inst.LoanID], inst)
+	}
+	for i := range loans {
+		Summarize(&loans[i], byLoan[loans[i].ID], today)
+	}
+	return nil
+}
+
+/* ===========================
+   Payments
+=========================== */
+
+// PaymentInput menjelaskan pembayaran satu angsuran: tautkan transaksi atau
+// transfer yang sudah ada, atau biarkan keduanya kosong untuk membuat transaksi
+// pengeluaran baru dari akun/kategori (default dari pinjaman).
+type PaymentInput struct {
+	TransactionID *uint
+	TransferID    *uint
+	AccountID     *uint
+	CategoryID    *uint
+	Date          string
+	Amount        float64
+	Description   string
+}
+
+func errPaymentLinked(msg string) error {
+	return &utils.AppError{Message: msg, StatusCode: http.StatusConflict, Code: "PAYMENT_ALREADY_LINKED"}
+}
+
+// PayInstallment menandai angsuran sebagai dibayar dan menautkannya ke transaksi
+// atau transfer pembayarannya.
+func (s *LoanService) PayInstallment(loan *models.Loan, number int, in PaymentInput) (*models.LoanInstallment, error) {
+	if in.TransactionID != nil && in.TransferID != nil {
+		return nil, utils.NewAppError("Provide either transaction_id or transfer_id, not both", http.StatusBadRequest)
+	}
+
+	var inst models.LoanInstallment
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("loan_id = ? AND number = ?", loan.ID, number).First(&inst).Error; err != nil {
+			return err
+		}
+		if inst.PaidDate != nil {
+			return &utils.AppError{
+				Message:    "Installment is already paid",
+				StatusCode: http.StatusConflict,
+				Code:       "INSTALLMENT_PAID",
+			}
+		}
+
+		var date string
+		var amount float64
+		switch {
+		case in.TransactionID != nil:
+			var trx models.Transaction
+			if err := tx.Where("user_id = ? AND id = ?", loan.UserID, *in.TransactionID).First(&trx).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return utils.NewAppError("Transaction not found", http.StatusNotFound)
+				}
+				return err
+			}
+			if trx.Type != "expense" {
+				return utils.NewAppError("Only expense transactions can pay an installment", http.StatusBadRequest)
+			}
+			if err := ensureUnlinked(tx, "transaction_id", trx.ID); err != nil {
+				return err
+			}
+			inst.TransactionID = &trx.ID
+			date, amount = trx.Date, trx.Amount
+
+		case in.TransferID != nil:
+			var transfer models.Transfer
+			if err := tx.Joins("JOIN members ON members.id = transfers.member_id").
+				Where("transfers.id = ? AND members.user_id = ?", *in.TransferID, loan.UserID).
+				First(&transfer).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return utils.NewAppError("Transfer not found", http.StatusNotFound)
+				}
+				return err
+			}
+			if err := ensureUnlinked(tx, "transfer_id", transfer.ID); err != nil {
+				return err
+			}
+			inst.TransferID = &transfer.ID
+			date, amount = transfer.Date, transfer.Amount
+
+		default:
+			trx, err := s.createPayment(tx, loan, &inst, in)
+			if err != nil {
+				return err
+			}
+			inst.TransactionID = &trx.ID
+			date, amount = trx.Date, trx.Amount
+		}
+
+		inst.PaidDate = &date
+		inst.PaidAmount = amount
+		if err := tx.Save(&inst).Error; err != nil {
+			return err
+		}
+		return syncLoan(tx, loan.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	inst.Status = models.InstallmentStatusPaid
+	return &inst, nil
+}
+
+// createPayment membuat transaksi pengeluaran untuk angsuran, lewat
+// TransactionService supaya saldo, batas belanja dan aturan tabungan tetap berlaku.
+func (s *LoanService) createPayment(tx *gorm.DB, loan *models.Loan, inst *models.LoanInstallment, in PaymentInput) (*models.Transaction, error) {
+	accountID := loan.AccountID
+	if in.AccountID != nil {
+		accountID = in.AccountID
+	}
+	categoryID := loan.CategoryID
+	if in.CategoryID != nil {
+		categoryID = in.CategoryID
+	}
+	if accountID == nil || categoryID == nil {
+		return nil, utils.NewAppError("account_id and category_id are required when the loan has no defaults", http.StatusBadRequest)
+	}
+
+	acc, err := NewLoanService(tx).findAccount(loan.UserID, *accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	date := in.Date
+	if date == "" {
+		date = time.Now().Format("2006-01-02")
+	}
+	amount := in.Amount
+	if amount <= 0 {
+		amount = inst.Total
+	}
+	desc := in.Description
+	if desc == "" {
+		desc = fmt.Sprintf("Cicilan %s %d/%d", loan.Name, inst.Number, loan.TenorMonths)
+	}
+
+	return NewTransactionService(tx).Create(loan.UserID, map[string]interface{}{
+		"date":        date,
+		"member_id":   float64(acc.MemberID),
+		"account_id":  float64(acc.ID),
+		"category_id": float64(*categoryID),
+		"amount":      amount,
+		"description": desc,
+		"type":        "expense",
+	})
+}
+
+func ensureUnlinked(tx *gorm.DB, column string, id uint) error {
+	var cnt int64
+	if err := tx.Model(&models.LoanInstallment{}).Where(column+" = ?", id).Count(&cnt).Error; err != nil {
+		return err
+	}
+	if cnt > 0 {
+		return errPaymentLinked("This payment is already linked to another installment")
+	}
+	return nil
+}
+
+// UnpayInstallment melepas tautan pembayaran angsuran. Transaksi/transfernya
+// tidak ikut dihapus.
+func (s *LoanService) UnpayInstallment(loan *models.Loan, number int) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var inst models.LoanInstallment
+		if err := tx.Where("loan_id = ? AND number = ?", loan.ID, number).First(&inst).Error; err != nil {
+			return err
+		}
+		if inst.PaidDate == nil {
+			return &utils.AppError{
+				Message:    "Installment is not paid",
+				StatusCode: http.StatusConflict,
+				Code:       "INSTALLMENT_NOT_PAID",
+			}
+		}
+		if err := clearPayment(tx, &inst); err != nil {
+			return err
+		}
+		return syncLoan(tx, loan.ID)
+	})
+}
+
+func clearPayment(tx *gorm.DB, inst *models.LoanInstallment) error {
+	return tx.Model(inst).Updates(map[string]interface{}{
+		"paid_date":      nil,
+		"paid_amount":    0,
+		"transaction_id": nil,
+		"transfer_id":    nil,
+	}).Error
+}
+
+// ReleaseLoanPayments dipanggil saat transaksi (column "transaction_id") atau
+// transfer ("transfer_id") dihapus: angsuran yang dibayar dengannya kembali belum lunas.
+func ReleaseLoanPayments(tx *gorm.DB, column string, id uint) error {
+	return releaseLoanPayments(tx, column, id, "")
+}
+
+// releaseLoanPayments seperti ReleaseLoanPayments; kalau batch diisi (hapus akun
+// cascade) tautan pembayarannya disimpan supaya bisa dipasang lagi oleh relinkLoanPayments.
+func releaseLoanPayments(tx *gorm.DB, column string, id uint, batch string) error {
+	var installments []models.LoanInstallment
+	if err := tx.Where(column+" = ?", id).Find(&installments).Error; err != nil {
+		return err
+	}
+	for i := range installments {
+		if batch != "" {
+			if err := tx.Model(&installments[i]).Updates(map[string]interface{}{
+				"deletion_batch":          batch,
+				"released_transaction_id": installments[i].TransactionID,
+				"released_transfer_id":    installments[i].TransferID,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		if err := clearPayment(tx, &installments[i]); err != nil {
+			return err
+		}
+		if err := syncLoan(tx, installments[i].LoanID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// relinkLoanPayments memasang lagi pembayaran angsuran yang dilepas oleh batch
+// hapus akun, kalau transaksi / transfernya sudah dipulihkan dan angsurannya
+// belum dibayar dengan cara lain.
+func relinkLoanPayments(tx *gorm.DB, batch string) error {
+	var installments []models.LoanInstallment
+	if err := tx.Where("deletion_batch = ?", batch).Find(&installments).Error; err != nil {
+		return err
+	}
+	for i := range installments {
+		inst := &installments[i]
+		updates := map[string]interface{}{
+			"deletion_batch":          "",
+			"released_transaction_id": nil,
+			"released_transfer_id":    nil,
+		}
+		if inst.PaidDate == nil {
+			switch {
+			case inst.ReleasedTransactionID != nil:
+				var trx models.Transaction
+				err := tx.First(&trx, *inst.ReleasedTransactionID).Error
+				if err == nil {
+					updates["transaction_id"], updates["paid_date"], updates["paid_amount"] = trx.ID, trx.Date, trx.Amount
+				} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+					return err
+				}
+			case inst.ReleasedTransferID != nil:
+				var transfer models.Transfer
+				err := tx.First(&transfer, *inst.ReleasedTransferID).Error
+				if err == nil {
+					updates["transfer_id"], updates["paid_date"], updates["paid_amount"] = transfer.ID, transfer.Date, transfer.Amount
+				} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+					return err
+				}
+			}
+		}
+		if err := tx.Model(inst).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := syncLoan(tx, inst.LoanID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refreshLoanPayments menyalin tanggal dan nominal transaksi yang diedit ke
+// angsuran yang dibayar dengannya. Transaksi yang tidak lagi berupa expense
+// dilepas dari angsurannya, sama seperti syarat di PayInstallment.
+func refreshLoanPayments(tx *gorm.DB, trx *models.Transaction) error {
+	if trx.Type != "expense" {
+		return ReleaseLoanPayments(tx, "transaction_id", trx.ID)
+	}
+	var installments []models.LoanInstallment
+	if err := tx.Where("transaction_id = ?", trx.ID).Find(&installments).Error; err != nil {
+		return err
+	}
+	for i := range installments {
+		if err := tx.Model(&installments[i]).Updates(map[string]interface{}{
+			"paid_date":   trx.Date,
+			"paid_amount": trx.Amount,
+		}).Error; err != nil {
+			return err
+		}
+		if err := syncLoan(tx, installments[i].LoanID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncLoan menghitung ulang sisa pokok dan status pinjaman dari angsuran yang
+// sudah dibayar, lalu menulis ulang riwayat nilai kewajibannya di net worth.
+func syncLoan(tx *gorm.DB, loanID uint) error {
+	var loan models.Loan
+	if err := tx.First(&loan, loanID).Error; err != nil {
+		return err
+	}
+	var paid []models.LoanInstallment
+	if err := tx.Where("loan_id = ? AND paid_date IS NOT NULL", loanID).
+		Order("paid_date, number").Find(&paid).Error; err != nil {
+		return err
+	}
+
+	outstanding := loan.Principal
+	type point struct {
+		date  string
+		value float64
+	}
+	history := []point{{loan.StartDate, loan.Principal}}
+	for _, inst := range paid {
+		outstanding = round2(outstanding - inst.Principal)
+		last := &history[len(history)-1]
+		if *inst.PaidDate <= last.date {
+			// dibayar sebelum / di hari yang sama dengan titik terakhir
+			last.value = outstanding
+			continue
+		}
+		history = append(history, point{*inst.PaidDate, outstanding})
+	}
+	if outstanding < 0 {
+		outstanding = 0
+	}
+
+	status := models.LoanStatusActive
+	var closedAt *string
+	if len(paid) >= loan.TenorMonths {
+		status = models.LoanStatusPaidOff
+		outstanding = 0
+		closedAt = &history[len(history)-1].date
+	}
+	if err := tx.Model(&loan).Updates(map[string]interface{}{
+		"outstanding_principal": outstanding,
+		"status":                status,
+	}).Error; err != nil {
+		return err
+	}
+
+	if loan.AssetID == nil {
+		return nil
+	}
+	if err := tx.Unscoped().Where("asset_id = ?", *loan.AssetID).Delete(&models.AssetValuation{}).Error; err != nil {
+		return err
+	}
+	valuations := make([]models.AssetValuation, 0, len(history))
+	for _, p := range history {
+		value := p.value
+		if value < 0 {
+			value = 0
+		}
+		valuations = append(valuations, models.AssetValuation{
+			UserID: loan.UserID, AssetID: *loan.AssetID, Date: p.date, Value: value, Note: "Sisa pokok pinjaman",
+		})
+	}
+	if err := tx.Create(&valuations).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.Asset{}).Where("id = ?", *loan.AssetID).Update("closed_at", closedAt).Error; err != nil {
+		return err
+	}
+	return refreshAssetValue(tx, *loan.AssetID)
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"math"
+	"testing"
+
+	"finance-app/models"
+)
+
+func TestBuildSchedule(t *testing.T) {
+	type row struct {
+		dueDate                    string
+		principal, interest, total float64
+		balanceAfter               float64
+	}
+	tests := []struct {
+		name  string
+		loan  models.Loan
+		rows  int
+		first row
+		last  row
+	}{
+		{
+			name:  "annuity, cicilan tetap dan selisih pembulatan di angsuran terakhir",
+			loan:  models.Loan{Principal: 12000, AnnualRate: 12, TenorMonths: 12, StartDate: "2026-01-15", Method: models.LoanMethodAnnuity},
+			rows:  12,
+			first: row{"2026-02-15", 946.19, 120, 1066.19, 11053.81},
+			last:  row{"2027-01-15", 1055.58, 10.56, 1066.14, 0},
+		},
+		{
+			name:  "flat, bunga tetap dari pokok awal",
+			loan:  models.Loan{Principal: 1000, AnnualRate: 12, TenorMonths: 3, StartDate: "2026-01-15", Method: models.LoanMethodFlat},
+			rows:  3,
+			first: row{"2026-02-15", 333.33, 10, 343.33, 666.67},
+			last:  row{"2026-04-15", 333.34, 10, 343.34, 0},
+		},
+		{
+			name:  "annuity bunga 0%",
+			loan:  models.Loan{Principal: 1000, AnnualRate: 0, TenorMonths: 3, StartDate: "2026-01-15", Method: models.LoanMethodAnnuity},
+			rows:  3,
+			first: row{"2026-02-15", 333.33, 0, 333.33, 666.67},
+			last:  row{"2026-04-15", 333.34, 0, 333.34, 0},
+		},
+		{
+			name:  "jatuh tempo akhir bulan tidak bergeser",
+			loan:  models.Loan{Principal: 300, AnnualRate: 0, TenorMonths: 3, StartDate: "2026-01-31", Method: models.LoanMethodFlat},
+			rows:  3,
+			first: row{"2026-02-28", 100, 0, 100, 200},
+			last:  row{"2026-04-30", 100, 0, 100, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := BuildSchedule(&tt.loan)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(rows) != tt.rows {
+				t.Fatalf("rows = %d, want %d", len(rows), tt.rows)
+			}
+
+			check := func(label string, got models.LoanInstallment, want row) {
+				if got.DueDate != want.dueDate || got.Principal != want.principal || got.Interest != want.interest ||
+					got.Total != want.total || got.BalanceAfter != want.balanceAfter {
+					t.Errorf("%s = {%s %v %v %v %v}, want %v", label, got.DueDate, got.Principal, got.Interest,
+						got.Total, got.BalanceAfter, want)
+				}
+			}
+			check("first", rows[0], tt.first)
+			check("last", rows[len(rows)-1], tt.last)
+
+			// total pokok harus pas sama dengan pokok pinjaman
+			var sum float64
+			for i, r := range rows {
+				if r.Number != i+1 {
+					t.Errorf("row %d: number = %d", i, r.Number)
+				}
+				sum += r.Principal
+			}
+			if math.Abs(sum-tt.loan.Principal) > 1e-6 {
+				t.Errorf("sum principal = %v, want %v", sum, tt.loan.Principal)
+			}
+		})
+	}
+}
+
+func TestBuildScheduleInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		loan models.Loan
+	}{
+		{"metode tidak dikenal", models.Loan{Principal: 1000, TenorMonths: 3, StartDate: "2026-01-15", Method: "balloon"}},
+		{"tanggal tidak valid", models.Loan{Principal: 1000, TenorMonths: 3, StartDate: "15-01-2026", Method: models.LoanMethodAnnuity}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := BuildSchedule(&tt.loan); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
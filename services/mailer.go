@@ -0,0 +1,125 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"finance-app/config"
+)
+
+// MailMessage adalah email teks sederhana.
+type MailMessage struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer mengirim email. Implementasi dipilih lewat MAIL_DRIVER.
+type Mailer interface {
+	Send(msg MailMessage) error
+}
+
+// LogMailer hanya menulis email ke log (untuk development).
+type LogMailer struct{}
+
+func (LogMailer) Send(msg MailMessage) error {
+	log.Printf("📧 mail to=%s subject=%q\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// FileMailer menyimpan setiap email sebagai file .eml di Dir.
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+func (m FileMailer) Send(msg MailMessage) error {
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), sanitizeFileName(msg.To))
+	return os.WriteFile(filepath.Join(m.Dir, name), buildMail(m.From, msg), 0o644)
+}
+
+// SMTPMailer mengirim email lewat server SMTP.
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	User     string
+	Password string
+	From     string
+}
+
+func (m SMTPMailer) Send(msg MailMessage) error {
+	var auth smtp.Auth
+	if m.User != "" {
+		auth = smtp.PlainAuth("", m.User, m.Password, m.Host)
+	}
+	return smtp.SendMail(m.Host+":"+m.Port, auth, m.From, []string{msg.To}, buildMail(m.From, msg))
+}
+
+var mailerOverride Mailer
+
+// SetMailer mengganti mailer global (mis. untuk integrasi lain).
+func SetMailer(m Mailer) {
+	mailerOverride = m
+}
+
+// MailerFromConfig mengembalikan mailer sesuai konfigurasi.
+func MailerFromConfig() Mailer {
+	if mailerOverride != nil {
+		return mailerOverride
+	}
+
+	cfg := config.Get()
+	switch cfg.MailDriver {
+	case "file":
+		return FileMailer{Dir: cfg.MailDir, From: cfg.MailFrom}
+	case "smtp":
+		return SMTPMailer{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			User:     cfg.SMTPUser,
+			Password: cfg.SMTPPassword,
+			From:     cfg.MailFrom,
+		}
+	}
+	return LogMailer{}
+}
+
+// MailConfigured bernilai false untuk LogMailer, yaitu saat email tidak
+// benar-benar terkirim ke penerima.
+func MailConfigured(m Mailer) bool {
+	if m == nil {
+		return false
+	}
+	_, isLog := m.(LogMailer)
+	return !isLog
+}
+
+func buildMail(from string, msg MailMessage) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + msg.To + "\r\n")
+	b.WriteString("Subject: " + msg.Subject + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(msg.Body)
+	return []byte(b.String())
+}
+
+func sanitizeFileName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, s)
+}
@@ -0,0 +1,368 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"finance-app/models"
+	"finance-app/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	NetWorthIntervalDay   = "day"
+	NetWorthIntervalWeek  = "week"
+	NetWorthIntervalMonth = "month"
+)
+
+type NetWorthService struct {
+	db *gorm.DB
+}
+
+func NewNetWorthService(db *gorm.DB) *NetWorthService {
+	return &NetWorthService{db: db}
+}
+
+/* ===========================
+   Snapshot job
+=========================== */
+
+// Snapshot mencatat saldo setiap akun dan total per ledger untuk tanggal now.
+// Aman dijalankan berkali-kali sehari: baris tanggal yang sama ditimpa.
+func (s *NetWorthService) Snapshot(now time.Time) error {
+	date := now.Format("2006-01-02")
+
+	var rows []struct {
+		UserID   uint
+		ID       uint
+		MemberID uint
+		Type     string
+		Currency string
+		Balance  float64
+	}
+	if err := s.db.Table("accounts").
+		Select("members.user_id, accounts.id, accounts.member_id, accounts.type, accounts.currency, accounts.balance").
+		Joins("JOIN members ON members.id = accounts.member_id AND members.deleted_at IS NULL").
+		Where("accounts.deleted_at IS NULL").
+		Scan(&rows).Error; err != nil {
+		return err
+	}
+
+	snapshots := make([]models.AccountBalanceSnapshot, 0, len(rows))
+	totals := map[uint]*models.NetWorthSnapshot{}
+	totalFor := func(userID uint) *models.NetWorthSnapshot {
+		t, ok := totals[userID]
+		if !ok {
+			t = &models.NetWorthSnapshot{UserID: userID, Date: date}
+			totals[userID] = t
+		}
+		return t
+	}
+	accountIDs := make([]uint, 0, len(rows))
+	for _, r := range rows {
+		accountIDs = append(accountIDs, r.ID)
+		snapshots = append(snapshots, models.AccountBalanceSnapshot{
+			UserID: r.UserID, AccountID: r.ID, MemberID: r.MemberID,
+			AccountType: r.Type, Currency: r.Currency, Date: date, Balance: r.Balance,
+		})
+		t := totalFor(r.UserID)
+		t.AccountsTotal += r.Balance
+		t.AccountCount++
+	}
+
+	// aset & kewajiban memakai valuasi terakhirnya dan dicatat per aset, supaya
+	// riwayat net worth tidak berubah kalau valuasi / asetnya diubah belakangan
+	var assets []models.Asset
+	if err := s.db.Where("valued_at <> '' AND valued_at <= ?", date).
+		Where("closed_at IS NULL OR closed_at > ?", date).
+		Find(&assets).Error; err != nil {
+		return err
+	}
+	assetSnapshots := make([]models.AssetValueSnapshot, 0, len(assets))
+	assetIDs := make([]uint, 0, len(assets))
+	for _, a := range assets {
+		assetSnapshots = append(assetSnapshots, models.AssetValueSnapshot{
+			UserID: a.UserID, AssetID: a.ID, MemberID: a.MemberID,
+			Kind: a.Kind, Category: a.Category, Date: date, Value: a.CurrentValue,
+		})
+		assetIDs = append(assetIDs, a.ID)
+		t := totalFor(a.UserID)
+		if a.Kind == models.AssetKindLiability {
+			t.LiabilitiesTotal += a.CurrentValue
+		} else {
+			t.AssetsTotal += a.CurrentValue
+		}
+	}
+	// ledger yang sudah punya snapshot hari ini tapi tidak punya akun / aset lagi
+	// ditulis ulang dengan total nol
+	var snapshotted []uint
+	if err := s.db.Model(&models.NetWorthSnapshot{}).Where("date = ?", date).
+		Pluck("user_id", &snapshotted).Error; err != nil {
+		return err
+	}
+	for _, userID := range snapshotted {
+		totalFor(userID)
+	}
+	for _, t := range totals {
+		t.Total = t.AccountsTotal + t.AssetsTotal - t.LiabilitiesTotal
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		// akun yang dihapus sejak run sebelumnya hari ini tidak ikut lagi
+		staleAccounts := tx.Where("date = ?", date)
+		if len(accountIDs) > 0 {
+			staleAccounts = staleAccounts.Where("account_id NOT IN ?", accountIDs)
+		}
+		if err := staleAccounts.Delete(&models.AccountBalanceSnapshot{}).Error; err != nil {
+			return err
+		}
+		if len(snapshots) > 0 {
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "account_id"}, {Name: "date"}},
+				DoUpdates: clause.AssignmentColumns([]string{"updated_at", "member_id", "account_type", "currency", "balance"}),
+			}).CreateInBatches(&snapshots, 500).Error; err != nil {
+				return err
+			}
+		}
+		// aset yang dihapus / ditutup sejak run sebelumnya hari ini tidak ikut lagi
+		stale := tx.Where("date = ?", date)
+		if len(assetIDs) > 0 {
+			stale = stale.Where("asset_id NOT IN ?", assetIDs)
+		}
+		if err := stale.Delete(&models.AssetValueSnapshot{}).Error; err != nil {
+			return err
+		}
+		if len(assetSnapshots) > 0 {
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "asset_id"}, {Name: "date"}},
+				DoUpdates: clause.AssignmentColumns([]string{"updated_at", "member_id", "kind", "category", "value"}),
+			}).CreateInBatches(&assetSnapshots, 500).Error; err != nil {
+				return err
+			}
+		}
+		for _, t := range totals {
+			if err := tx.Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "user_id"}, {Name: "date"}},
+				DoUpdates: clause.AssignmentColumns([]string{
+					"updated_at", "total", "account_count", "accounts_total", "assets_total", "liabilities_total",
+				}),
+			}).Create(t).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+/* ===========================
+   Time series
+=========================== */
+
+// NetWorthQuery adalah parameter GET /reports/net-worth.
+type NetWorthQuery struct {
+	From     string `form:"from"` // YYYY-MM-DD, default 12 bulan lalu
+	To       string `form:"to"`   // YYYY-MM-DD, default hari ini
+	Interval string `form:"interval"`
+
+	// Diisi controller untuk role member: hanya akun milik member ini
+	MemberID *uint `form:"-"`
+}
+
+type NetWorthMemberTotal struct {
+	MemberID   uint    `json:"member_id"`
+	MemberName string  `json:"member_name"`
+	Total      float64 `json:"total"`
+}
+
+// NetWorthPoint adalah nilai kekayaan bersih di akhir satu periode, diambil
+// dari snapshot terakhir di periode tersebut. Total = Accounts + Assets - Liabilities;
+// di ByType (key "asset:gold", "liability:loan", ...) dan ByMember kewajiban bernilai negatif.
+type NetWorthPoint struct {
+	Period      string                `json:"period"` // 2024-05, 2024-W18 atau 2024-05-03
+	Date        string                `json:"date"`   // tanggal snapshot yang dipakai
+	Total       float64               `json:"total"`
+	Accounts    float64               `json:"accounts"`
+	Assets      float64               `json:"assets"`
+	Liabilities float64               `json:"liabilities"`
+	ByType      map[string]float64    `json:"by_type"`
+	ByMember    []NetWorthMemberTotal `json:"by_member"`
+}
+
+type NetWorthSeries struct {
+	From     string          `json:"from"`
+	To       string          `json:"to"`
+	Interval string          `json:"interval"`
+	Points   []NetWorthPoint `json:"points"`
+}
+
+func netWorthPeriod(date time.Time, interval string) string {
+	switch interval {
+	case NetWorthIntervalDay:
+		return date.Format("2006-01-02")
+	case NetWorthIntervalWeek:
+		year, week := date.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	}
+	return date.Format("2006-01")
+}
+
+// Series mengembalikan deret waktu kekayaan bersih, dipecah per tipe akun dan member.
+func (s *NetWorthService) Series(userID uint, q NetWorthQuery) (*NetWorthSeries, error) {
+	now := time.Now()
+	if q.Interval == "" {
+		q.Interval = NetWorthIntervalMonth
+	}
+	if q.Interval != NetWorthIntervalDay && q.Interval != NetWorthIntervalWeek && q.Interval != NetWorthIntervalMonth {
+		return nil, utils.NewAppError("interval must be day, week or month", http.StatusBadRequest)
+	}
+	if q.To == "" {
+		q.To = now.Format("2006-01-02")
+	}
+	if q.From == "" {
+		q.From = now.AddDate(-1, 0, 0).Format("2006-01-02")
+	}
+	from, err := time.Parse("2006-01-02", q.From)
+	if err != nil {
+		return nil, utils.NewAppError("from must be YYYY-MM-DD", http.StatusBadRequest)
+	}
+	to, err := time.Parse("2006-01-02", q.To)
+	if err != nil {
+		return nil, utils.NewAppError("to must be YYYY-MM-DD", http.StatusBadRequest)
+	}
+	if to.Before(from) {
+		return nil, utils.NewAppError("from must be before to", http.StatusBadRequest)
+	}
+
+	query := s.db.Model(&models.AccountBalanceSnapshot{}).
+		Where("user_id = ? AND date BETWEEN ? AND ?", userID, q.From, q.To)
+	assetQuery := s.db.Model(&models.AssetValueSnapshot{}).
+		Where("user_id = ? AND date BETWEEN ? AND ?", userID, q.From, q.To)
+	if q.MemberID != nil {
+		query = query.Where("member_id = ?", *q.MemberID)
+		assetQuery = assetQuery.Where("member_id = ?", *q.MemberID)
+	}
+	var snapshots []models.AccountBalanceSnapshot
+	if err := query.Order("date").Find(&snapshots).Error; err != nil {
+		return nil, err
+	}
+	var assetSnapshots []models.AssetValueSnapshot
+	if err := assetQuery.Order("date").Find(&assetSnapshots).Error; err != nil {
+		return nil, err
+	}
+	// total per ledger dipakai apa adanya kalau tidak difilter per member; untuk
+	// tanggal lama yang belum punya AssetValueSnapshot hanya total ini yang lengkap
+	stored := map[string]models.NetWorthSnapshot{}
+	if q.MemberID == nil {
+		var totals []models.NetWorthSnapshot
+		if err := s.db.Where("user_id = ? AND date BETWEEN ? AND ?", userID, q.From, q.To).
+			Find(&totals).Error; err != nil {
+			return nil, err
+		}
+		for _, t := range totals {
+			stored[t.Date] = t
+		}
+	}
+
+	// tanggal penutup tiap periode = tanggal snapshot terakhir di periode itu
+	closing := map[string]string{}
+	var periods []string
+	addDate := func(date string) {
+		d, _ := time.Parse("2006-01-02", date)
+		p := netWorthPeriod(d, q.Interval)
+		if _, ok := closing[p]; !ok {
+			periods = append(periods, p)
+		}
+		if date > closing[p] {
+			closing[p] = date
+		}
+	}
+	for _, snap := range snapshots {
+		addDate(snap.Date)
+	}
+	for _, snap := range assetSnapshots {
+		addDate(snap.Date)
+	}
+	for date := range stored {
+		addDate(date)
+	}
+	sort.Strings(periods)
+
+	memberNames, err := s.memberNames(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	points := make(map[string]*NetWorthPoint, len(periods))
+	byMember := map[string]map[uint]float64{}
+	for _, p := range periods {
+		points[p] = &NetWorthPoint{Period: p, Date: closing[p], ByType: map[string]float64{}}
+		byMember[p] = map[uint]float64{}
+	}
+	for _, snap := range snapshots {
+		d, _ := time.Parse("2006-01-02", snap.Date)
+		p := netWorthPeriod(d, q.Interval)
+		if closing[p] != snap.Date {
+			continue
+		}
+		pt := points[p]
+		pt.Accounts += snap.Balance
+		pt.ByType[snap.AccountType] += snap.Balance
+		byMember[p][snap.MemberID] += snap.Balance
+	}
+	for _, snap := range assetSnapshots {
+		d, _ := time.Parse("2006-01-02", snap.Date)
+		p := netWorthPeriod(d, q.Interval)
+		if closing[p] != snap.Date {
+			continue
+		}
+		pt := points[p]
+		value := snap.Value
+		if snap.Kind == models.AssetKindLiability {
+			pt.Liabilities += value
+			value = -value
+		} else {
+			pt.Assets += value
+		}
+		pt.ByType[snap.Kind+":"+snap.Category] += value
+		var memberID uint // 0 = milik bersama
+		if snap.MemberID != nil {
+			memberID = *snap.MemberID
+		}
+		byMember[p][memberID] += value
+	}
+
+	series := &NetWorthSeries{From: q.From, To: q.To, Interval: q.Interval, Points: []NetWorthPoint{}}
+	for _, p := range periods {
+		pt := points[p]
+		if t, ok := stored[pt.Date]; ok {
+			pt.Accounts, pt.Assets, pt.Liabilities = t.AccountsTotal, t.AssetsTotal, t.LiabilitiesTotal
+		}
+		pt.Total = pt.Accounts + pt.Assets - pt.Liabilities
+
+		for memberID, total := range byMember[p] {
+			pt.ByMember = append(pt.ByMember, NetWorthMemberTotal{
+				MemberID: memberID, MemberName: memberNames[memberID], Total: total,
+			})
+		}
+		sort.Slice(pt.ByMember, func(i, j int) bool { return pt.ByMember[i].MemberID < pt.ByMember[j].MemberID })
+		series.Points = append(series.Points, *pt)
+	}
+	return series, nil
+}
+
+// memberNames termasuk member yang sudah dihapus, karena snapshot lama masih merujuknya.
+func (s *NetWorthService) memberNames(userID uint) (map[uint]string, error) {
+	var members []models.Member
+	if err := s.db.Unscoped().Select("id, name").Where("user_id = ?", userID).Find(&members).Error; err != nil {
+		return nil, err
+	}
+	names := map[uint]string{0: "Shared"}
+	for _, m := range members {
+		names[m.ID] = m.Name
+	}
+	return names, nil
+}
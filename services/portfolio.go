@@ -0,0 +1,224 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"finance-app/models"
+	"finance-app/utils"
+)
+
+// quantityEpsilon menoleransi sisa pembulatan float saat menjual seluruh unit.
+const quantityEpsilon = 1e-9
+
+// lotCash adalah efek satu lot ke saldo kas akun investasi.
+func lotCash(lot *models.InvestmentLot) float64 {
+	gross := lot.Quantity * lot.Price
+	if lot.Side == models.LotSideBuy {
+		return -(gross + lot.Fee)
+	}
+	return gross - lot.Fee
+}
+
+// costTracker menghitung harga perolehan satu instrumen di satu akun.
+type costTracker struct {
+	method   string
+	quantity float64
+	cost     float64
+	fifo     []fifoLot // hanya untuk FIFO
+}
+
+type fifoLot struct {
+	quantity float64
+	unitCost float64
+}
+
+func newCostTracker(method string) *costTracker {
+	return &costTracker{method: method}
+}
+
+// apply memproses satu lot dan mengembalikan realized gain-nya (0 untuk pembelian).
+// Fee pembelian masuk ke harga perolehan, fee penjualan mengurangi hasil jual.
+func (t *costTracker) apply(lot *models.InvestmentLot) (float64, error) {
+	if lot.Side == models.LotSideBuy {
+		cost := lot.Quantity*lot.Price + lot.Fee
+		t.quantity += lot.Quantity
+		t.cost += cost
+		if t.method == models.CostMethodFIFO {
+			t.fifo = append(t.fifo, fifoLot{quantity: lot.Quantity, unitCost: cost / lot.Quantity})
+		}
+		return 0, nil
+	}
+
+	if lot.Quantity > t.quantity+quantityEpsilon {
+		return 0, &utils.AppError{
+			Message:    fmt.Sprintf("Sell on %s exceeds holdings (%.8g available)", lot.Date, t.quantity),
+			StatusCode: http.StatusBadRequest,
+			Code:       "INSUFFICIENT_HOLDINGS",
+		}
+	}
+
+	var cost float64
+	if t.method == models.CostMethodFIFO {
+		remaining := lot.Quantity
+		for remaining > quantityEpsilon && len(t.fifo) > 0 {
+			head := &t.fifo[0]
+			take := head.quantity
+			if take > remaining {
+				take = remaining
+			}
+			cost += take * head.unitCost
+			head.quantity -= take
+			remaining -= take
+			if head.quantity <= quantityEpsilon {
+				t.fifo = t.fifo[1:]
+			}
+		}
+	} else if t.quantity > 0 {
+		cost = t.cost / t.quantity * lot.Quantity
+	}
+
+	t.quantity -= lot.Quantity
+	t.cost -= cost
+	if t.quantity <= quantityEpsilon {
+		t.quantity, t.cost, t.fifo = 0, 0, nil
+	}
+	return lot.Quantity*lot.Price - lot.Fee - cost, nil
+}
+
+func sortLots(lots []models.InvestmentLot) {
+	sort.SliceStable(lots, func(i, j int) bool {
+		if lots[i].Date != lots[j].Date {
+			return lots[i].Date < lots[j].Date
+		}
+		return lots[i].ID < lots[j].ID
+	})
+}
+
+// checkHoldings memastikan tidak ada penjualan yang melebihi unit yang dimiliki
+// pada tanggalnya.
+func checkHoldings(lots []models.InvestmentLot) error {
+	sortLots(lots)
+	trackers := map[uint]*costTracker{}
+	for i := range lots {
+		t, ok := trackers[lots[i].SecurityID]
+		if !ok {
+			t = newCostTracker(models.CostMethodAverage)
+			trackers[lots[i].SecurityID] = t
+		}
+		if _, err := t.apply(&lots[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/* ===========================
+   Portfolio
+=========================== */
+
+type PortfolioQuery struct {
+	AsOf      string `form:"as_of"`  // YYYY-MM-DD, default hari ini
+	From      string `form:"from"`   // awal periode realized gain & dividen, kosong = sejak awal
+	Method    string `form:"method"` // fifo (default) atau average
+	AccountID *uint  `form:"account_id"`
+
+	// Diisi controller untuk role member: hanya akun milik member ini
+	MemberID *uint `form:"-"`
+}
+
+type Position struct {
+	AccountID      uint    `json:"account_id"`
+	SecurityID     uint    `json:"security_id"`
+	Symbol         string  `json:"symbol"`
+	Name           string  `json:"name"`
+	Kind           string  `json:"kind"`
+	Quantity       float64 `json:"quantity"`
+	CostBasis      float64 `json:"cost_basis"`
+	AverageCost    float64 `json:"average_cost"`
+	Price          float64 `json:"price"`
+	PriceDate      string  `json:"price_date"`
+	MarketValue    float64 `json:"market_value"`
+	UnrealizedGain float64 `json:"unrealized_gain"`
+	UnrealizedPct  float64 `json:"unrealized_pct"`
+	RealizedGain   float64 `json:"realized_gain"`
+	Dividends      float64 `json:"dividends"`
+}
+
+type PortfolioAccount struct {
+	AccountID   uint    `json:"account_id"`
+	Name        string  `json:"name"`
+	Cash        float64 `json:"cash"`
+	MarketValue float64 `json:"market_value"`
+	Total       float64 `json:"total"`
+}
+
+type PortfolioTotals struct {
+	MarketValue    float64 `json:"market_value"`
+	CostBasis      float64 `json:"cost_basis"`
+	UnrealizedGain float64 `json:"unrealized_gain"`
+	RealizedGain   float64 `json:"realized_gain"`
+	Dividends      float64 `json:"dividends"`
+	Cash           float64 `json:"cash"`
+	Total          float64 `json:"total"` // cash + market value
+}
+
+type Portfolio struct {
+	AsOf      string             `json:"as_of"`
+	From      string             `json:"from,omitempty"`
+	Method    string             `json:"method"`
+	Positions []Position         `json:"positions"`
+	Accounts  []PortfolioAccount `json:"accounts"`
+	Totals    PortfolioTotals    `json:"totals"`
+}
+
+// pricePoint adalah harga yang diketahui pada satu tanggal, dari SecurityPrice
+// atau dari harga transaksi lot.
+type pricePoint struct {
+	date  string
+	price float64
+}
+
+// priceBook menyimpan riwayat harga per instrumen, urut tanggal.
+type priceBook map[uint][]pricePoint
+
+func newPriceBook(prices []models.SecurityPrice, lots []models.InvestmentLot) priceBook {
+	book := priceBook{}
+	for _, l := range lots {
+		book[l.SecurityID] = append(book[l.SecurityID], pricePoint{l.Date, l.Price})
+	}
+	// harga resmi ditambahkan terakhir supaya menang saat tanggalnya sama
+	for _, p := range prices {
+		book[p.SecurityID] = append(book[p.SecurityID], pricePoint{p.Date, p.Price})
+	}
+	for id := range book {
+		points := book[id]
+		sort.SliceStable(points, func(i, j int) bool { return points[i].date < points[j].date })
+	}
+	return book
+}
+
+// at mengembalikan harga terakhir dengan tanggal <= date.
+func (b priceBook) at(securityID uint, date string) (pricePoint, bool) {
+	points := b[securityID]
+	i := sort.Search(len(points), func(i int) bool { return points[i].date > date })
+	if i == 0 {
+		return pricePoint{}, false
+	}
+	return points[i-1], true
+}
+
+// holdingsValue menghitung nilai pasar unit yang dipegang pada tanggal date.
+func holdingsValue(quantities map[uint]float64, book priceBook, date string) float64 {
+	var total float64
+	for securityID, qty := range quantities {
+		if qty <= quantityEpsilon {
+			continue
+		}
+		if p, ok := book.at(securityID, date); ok {
+			total += qty * p.price
+		}
+	}
+	return round2(total)
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"finance-app/models"
+	"finance-app/utils"
+)
+
+func buy(securityID uint, date string, qty, price, fee float64) models.InvestmentLot {
+	return models.InvestmentLot{SecurityID: securityID, Side: models.LotSideBuy, Date: date, Quantity: qty, Price: price, Fee: fee}
+}
+
+func sell(securityID uint, date string, qty, price, fee float64) models.InvestmentLot {
+	return models.InvestmentLot{SecurityID: securityID, Side: models.LotSideSell, Date: date, Quantity: qty, Price: price, Fee: fee}
+}
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestCostTracker(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		lots     []models.InvestmentLot
+		gains    []float64 // realized gain per lot
+		quantity float64
+		cost     float64
+	}{
+		{
+			name:   "fifo memakai lot tertua dulu, fee beli masuk harga perolehan",
+			method: models.CostMethodFIFO,
+			lots: []models.InvestmentLot{
+				buy(1, "2026-01-01", 10, 100, 10),
+				buy(1, "2026-02-01", 10, 120, 0),
+				sell(1, "2026-03-01", 15, 130, 5),
+			},
+			gains:    []float64{0, 0, 1945 - (1010 + 600)},
+			quantity: 5,
+			cost:     600,
+		},
+		{
+			name:   "average memakai harga rata-rata",
+			method: models.CostMethodAverage,
+			lots: []models.InvestmentLot{
+				buy(1, "2026-01-01", 10, 100, 10),
+				buy(1, "2026-02-01", 10, 120, 0),
+				sell(1, "2026-03-01", 15, 130, 5),
+			},
+			gains:    []float64{0, 0, 1945 - 110.5*15},
+			quantity: 5,
+			cost:     110.5 * 5,
+		},
+		{
+			name:   "fifo jual sebagian dari lot yang sama beberapa kali",
+			method: models.CostMethodFIFO,
+			lots: []models.InvestmentLot{
+				buy(1, "2026-01-01", 10, 10, 0),
+				sell(1, "2026-01-02", 4, 12, 0),
+				sell(1, "2026-01-03", 4, 12, 0),
+				buy(1, "2026-01-04", 5, 20, 0),
+				sell(1, "2026-01-05", 5, 20, 0),
+			},
+			gains:    []float64{0, 8, 8, 0, 100 - (2*10 + 3*20)},
+			quantity: 2,
+			cost:     40,
+		},
+		{
+			name:   "sisa pembulatan float saat jual semua di-nol-kan",
+			method: models.CostMethodFIFO,
+			lots: []models.InvestmentLot{
+				buy(1, "2026-01-01", 0.1, 10, 0),
+				buy(1, "2026-01-02", 0.2, 10, 0),
+				sell(1, "2026-01-03", 0.3, 10, 0),
+			},
+			gains:    []float64{0, 0, 0},
+			quantity: 0,
+			cost:     0,
+		},
+		{
+			name:   "jual sedikit di atas holding masih dalam toleransi",
+			method: models.CostMethodAverage,
+			lots: []models.InvestmentLot{
+				buy(1, "2026-01-01", 1, 50, 0),
+				sell(1, "2026-01-02", 1+1e-10, 60, 1),
+			},
+			gains:    []float64{0, 60*(1+1e-10) - 1 - 50},
+			quantity: 0,
+			cost:     0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := newCostTracker(tt.method)
+			for i := range tt.lots {
+				gain, err := tracker.apply(&tt.lots[i])
+				if err != nil {
+					t.Fatalf("lot %d: unexpected error: %v", i, err)
+				}
+				if !approxEqual(gain, tt.gains[i]) {
+					t.Errorf("lot %d: gain = %v, want %v", i, gain, tt.gains[i])
+				}
+			}
+			if !approxEqual(tracker.quantity, tt.quantity) {
+				t.Errorf("quantity = %v, want %v", tracker.quantity, tt.quantity)
+			}
+			if !approxEqual(tracker.cost, tt.cost) {
+				t.Errorf("cost = %v, want %v", tracker.cost, tt.cost)
+			}
+			if tt.quantity == 0 && len(tracker.fifo) != 0 {
+				t.Errorf("fifo masih berisi %d lot setelah jual semua", len(tracker.fifo))
+			}
+		})
+	}
+}
+
+func TestCostTrackerOversell(t *testing.T) {
+	for _, method := range []string{models.CostMethodFIFO, models.CostMethodAverage} {
+		tracker := newCostTracker(method)
+		b := buy(1, "2026-01-01", 1, 10, 0)
+		s := sell(1, "2026-01-02", 1.001, 10, 0)
+		if _, err := tracker.apply(&b); err != nil {
+			t.Fatalf("%s: unexpected error: %v", method, err)
+		}
+		_, err := tracker.apply(&s)
+		var appErr *utils.AppError
+		if !errors.As(err, &appErr) || appErr.Code != "INSUFFICIENT_HOLDINGS" {
+			t.Errorf("%s: err = %v, want INSUFFICIENT_HOLDINGS", method, err)
+		}
+	}
+}
+
+func TestCheckHoldings(t *testing.T) {
+	tests := []struct {
+		name    string
+		lots    []models.InvestmentLot
+		wantErr bool
+	}{
+		{
+			name: "urutan input tidak berpengaruh, diurutkan per tanggal",
+			lots: []models.InvestmentLot{
+				sell(1, "2026-02-01", 5, 10, 0),
+				buy(1, "2026-01-01", 5, 10, 0),
+			},
+		},
+		{
+			name: "jual sebelum tanggal beli",
+			lots: []models.InvestmentLot{
+				buy(1, "2026-02-01", 5, 10, 0),
+				sell(1, "2026-01-01", 5, 10, 0),
+			},
+			wantErr: true,
+		},
+		{
+			name: "holding tiap instrumen dihitung terpisah",
+			lots: []models.InvestmentLot{
+				buy(1, "2026-01-01", 5, 10, 0),
+				sell(2, "2026-01-02", 5, 10, 0),
+			},
+			wantErr: true,
+		},
+		{
+			name: "tanggal sama diurutkan per ID",
+			lots: func() []models.InvestmentLot {
+				s := sell(1, "2026-01-01", 5, 10, 0)
+				s.ID = 2
+				b := buy(1, "2026-01-01", 5, 10, 0)
+				b.ID = 1
+				return []models.InvestmentLot{s, b}
+			}(),
+		},
+		{
+			name: "jual melebihi total beli",
+			lots: []models.InvestmentLot{
+				buy(1, "2026-01-01", 5, 10, 0),
+				buy(1, "2026-01-02", 5, 10, 0),
+				sell(1, "2026-01-03", 11, 10, 0),
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkHoldings(tt.lots)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"finance-app/models"
+)
+
+const (
+	SavingStatusAchieved      = "achieved"
+	SavingStatusOnTrack       = "on track"
+	SavingStatusBehind        = "behind"
+	SavingStatusOverdue       = "overdue"
+	SavingStatusNoTargetDate  = "no target date"
+	SavingStatusNotStarted    = "not started"
+	avgDaysPerMonth           = 30.436875
+	savingForecastDateLayout  = "2006-01-02"
+	savingForecastMonthLayout = "2006-01"
+)
+
+type SavingSeriesPoint struct {
+	Month   string  `json:"month"`
+	Actual  float64 `json:"actual"`
+	Planned float64 `json:"planned"`
+}
+
+type SavingForecast struct {
+	TargetDate              string              `json:"target_date"`
+	Remaining               float64             `json:"remaining"`
+	DaysLeft                int                 `json:"days_left"`
+	RequiredMonthly         float64             `json:"required_monthly"`
+	RequiredWeekly          float64             `json:"required_weekly"`
+	AverageMonthly          float64             `json:"average_monthly"`
+	ProjectedCompletionDate string              `json:"projected_completion_date,omitempty"`
+	Status                  string              `json:"status"`
+	Series                  []SavingSeriesPoint `json:"series"`
+}
+
+// ForecastSavingTarget menghitung kebutuhan setoran dan proyeksi selesai
+// berdasarkan ledger kontribusi sampai tanggal now.
+func ForecastSavingTarget(target models.SavingTarget, contributions []models.SavingContribution, now time.Time) SavingForecast {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	start := time.Date(target.CreatedAt.Year(), target.CreatedAt.Month(), target.CreatedAt.Day(), 0, 0, 0, 0, time.UTC)
+
+	sort.Slice(contributions, func(i, j int) bool { return contributions[i].Date < contributions[j].Date })
+	if len(contributions) > 0 {
+		if first, err := time.Parse(savingForecastDateLayout, contributions[0].Date); err == nil && first.Before(start) {
+			start = first
+		}
+	}
+
+	f := SavingForecast{
+		TargetDate: target.TargetDate,
+		Remaining:  math.Max(target.TargetAmount-target.CurrentAmount, 0),
+	}
+
+	// rata-rata setoran bersih per bulan sejak mulai
+	elapsedDays := today.Sub(start).Hours() / 24
+	if elapsedDays < 1 {
+		elapsedDays = 1
+	}
+	dailyRate := target.CurrentAmount / elapsedDays
+	f.AverageMonthly = round2(dailyRate * avgDaysPerMonth)
+
+	targetDate, err := time.Parse(savingForecastDateLayout, target.TargetDate)
+	hasTargetDate := err == nil
+	if hasTargetDate {
+		f.DaysLeft = int(math.Ceil(targetDate.Sub(today).Hours() / 24))
+		if f.DaysLeft > 0 && f.Remaining > 0 {
+			f.RequiredMonthly = round2(f.Remaining / math.Max(float64(f.DaysLeft)/avgDaysPerMonth, 1))
+			f.RequiredWeekly = round2(f.Remaining / math.Max(float64(f.DaysLeft)/7, 1))
+		}
+	}
+
+	var projected time.Time
+	if f.Remaining == 0 {
+		projected = today
+	} else if dailyRate > 0 {
+		projected = today.AddDate(0, 0, int(math.Ceil(f.Remaining/dailyRate)))
+	}
+	if !projected.IsZero() {
+		f.ProjectedCompletionDate = projected.Format(savingForecastDateLayout)
+	}
+
+	switch {
+	case f.Remaining == 0:
+		f.Status = SavingStatusAchieved
+	case !hasTargetDate:
+		f.Status = SavingStatusNoTargetDate
+	case f.DaysLeft <= 0:
+		f.Status = SavingStatusOverdue
+	case projected.IsZero():
+		f.Status = SavingStatusNotStarted
+	case !projected.After(targetDate):
+		f.Status = SavingStatusOnTrack
+	default:
+		f.Status = SavingStatusBehind
+	}
+
+	f.Series = savingSeries(target, contributions, start, today, targetDate, hasTargetDate)
+	return f
+}
+
+// savingSeries membuat deret bulanan: saldo aktual kumulatif dan garis rencana
+// linear dari tanggal mulai sampai TargetDate.
+func savingSeries(target models.SavingTarget, contributions []models.SavingContribution, start, today, targetDate time.Time, hasTargetDate bool) []SavingSeriesPoint {
+	end := today
+	if hasTargetDate && targetDate.After(end) {
+		end = targetDate
+	}
+
+	totalDays := targetDate.Sub(start).Hours() / 24
+	var points []SavingSeriesPoint
+	running := 0.0
+	idx := 0
+	for m := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC); !m.After(end); m = m.AddDate(0, 1, 0) {
+		monthEnd := m.AddDate(0, 1, -1)
+		for idx < len(contributions) && contributions[idx].Date <= monthEnd.Format(savingForecastDateLayout) {
+			if contributions[idx].Type == models.SavingContributionWithdrawal {
+				running -= contributions[idx].Amount
+			} else {
+				running += contributions[idx].Amount
+			}
+			idx++
+		}
+
+		point := SavingSeriesPoint{Month: m.Format(savingForecastMonthLayout)}
+		if !m.After(today) {
+			point.Actual = round2(running)
+		}
+		if hasTargetDate && totalDays > 0 {
+			progress := math.Min(monthEnd.Sub(start).Hours()/24/totalDays, 1)
+			point.Planned = round2(target.TargetAmount * math.Max(progress, 0))
+		}
+		points = append(points, point)
+	}
+	return points
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,344 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"finance-app/models"
+	"finance-app/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SavingContributionInput struct {
+	Amount           float64 `json:"amount" binding:"required,gt=0"`
+	Date             string  `json:"date"`
+	Description      string  `json:"description"`
+	CounterAccountID *uint   `json:"counter_account_id"`
+
+	// diisi internal oleh ApplyRules
+	SavingRuleID  *uint `json:"-"`
+	TransactionID *uint `json:"-"`
+}
+
+type SavingService struct {
+	db *gorm.DB
+}
+
+func NewSavingService(db *gorm.DB) *SavingService {
+	return &SavingService{db: db}
+}
+
+/* ===========================
+   Helpers
+=========================== */
+
+func (s *SavingService) findAccount(tx *gorm.DB, userID, accountID uint) (*models.Account, error) {
+	var acc models.Account
+	if err := tx.Joins("JOIN members ON members.id = accounts.member_id").
+		Where("members.user_id = ? AND accounts.id = ?", userID, accountID).
+		First(&acc).Error; err != nil {
+		return nil, utils.NewAppError("Account not found", http.StatusNotFound)
+	}
+	return &acc, nil
+}
+
+// earmarkedTotal menjumlahkan dana yang sudah dialokasikan ke saving target di satu akun.
+func (s *SavingService) earmarkedTotal(tx *gorm.DB, accountID uint) (float64, error) {
+	var total float64
+	err := tx.Model(&models.SavingTarget{}).
+		Where("account_id = ?", accountID).
+		Select("COALESCE(SUM(current_amount),0)").
+		Scan(&total).Error
+	return total, err
+}
+
+// RecalculateCurrentAmount menghitung ulang CurrentAmount dari ledger kontribusi.
+func (s *SavingService) RecalculateCurrentAmount(tx *gorm.DB, targetID uint) error {
+	var total float64
+	if err := tx.Model(&models.SavingContribution{}).
+		Where("saving_target_id = ?", targetID).
+		Select("COALESCE(SUM(CASE WHEN type = ? THEN amount ELSE -amount END),0)", models.SavingContributionDeposit).
+		Scan(&total).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.SavingTarget{}).
+		Where("id = ?", targetID).
+		Update("current_amount", total).Error
+}
+
+// Record menulis satu baris ledger di dalam transaksi DB yang sudah berjalan.
+func (s *SavingService) Record(tx *gorm.DB, target *models.SavingTarget, cType string, in SavingContributionInput) (*models.SavingContribution, error) {
+	if in.Amount <= 0 {
+		return nil, utils.NewAppError("Amount must be greater than zero", http.StatusBadRequest)
+	}
+	if in.Date == "" {
+		in.Date = time.Now().Format("2006-01-02")
+	} else if _, err := time.Parse("2006-01-02", in.Date); err != nil {
+		return nil, utils.NewAppError("Invalid date format", http.StatusBadRequest)
+	}
+
+	targetAcc, err := s.findAccount(tx, target.UserID, target.AccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	contribution := models.SavingContribution{
+		UserID:           target.UserID,
+		SavingTargetID:   target.ID,
+		Type:             cType,
+		Amount:           in.Amount,
+		Date:             in.Date,
+		Description:      in.Description,
+		CounterAccountID: in.CounterAccountID,
+		SavingRuleID:     in.SavingRuleID,
+		TransactionID:    in.TransactionID,
+	}
+
+	switch cType {
+	case models.SavingContributionDeposit:
+		if err := EnsureAccountActive(tx, target.AccountID); err != nil {
+			return nil, err
+		}
+		if in.CounterAccountID != nil && *in.CounterAccountID != target.AccountID {
+			source, err := s.findAccount(tx, target.UserID, *in.CounterAccountID)
+			if err != nil {
+				return nil, err
+			}
+			transfer, err := moveFunds(tx, target.UserID, target.MemberID, source, targetAcc, in.Amount, in.Date, "Saving: "+target.Name)
+			if err != nil {
+				return nil, err
+			}
+			contribution.TransferID = &transfer.ID
+		} else {
+			// earmark: saldo akun harus cukup untuk semua target yang memakai akun ini
+			earmarked, err := s.earmarkedTotal(tx, target.AccountID)
+			if err != nil {
+				return nil, err
+			}
+			if targetAcc.Balance < earmarked+in.Amount {
+				return nil, utils.NewAppError("Account balance does not cover earmarked savings", http.StatusBadRequest)
+			}
+			contribution.CounterAccountID = nil
+		}
+	case models.SavingContributionWithdrawal:
+		if in.Amount > target.CurrentAmount {
+			return nil, utils.NewAppError("Withdrawal exceeds saved amount", http.StatusBadRequest)
+		}
+		if in.CounterAccountID != nil && *in.CounterAccountID != target.AccountID {
+			dest, err := s.findAccount(tx, target.UserID, *in.CounterAccountID)
+			if err != nil {
+				return nil, err
+			}
+			transfer, err := moveFunds(tx, target.UserID, target.MemberID, targetAcc, dest, in.Amount, in.Date, "Saving withdrawal: "+target.Name)
+			if err != nil {
+				return nil, err
+			}
+			contribution.TransferID = &transfer.ID
+		} else {
+			contribution.CounterAccountID = nil
+		}
+	default:
+		return nil, utils.NewAppError("Invalid contribution type", http.StatusBadRequest)
+	}
+
+	if err := tx.Create(&contribution).Error; err != nil {
+		return nil, err
+	}
+	if err := s.RecalculateCurrentAmount(tx, target.ID); err != nil {
+		return nil, err
+	}
+	return &contribution, nil
+}
+
+// ApplyRules menjalankan SavingRule aktif yang cocok dengan transaksi baru.
+// Rule yang tidak bisa dijalankan (misal saldo kurang) dilewati tanpa
+// menggagalkan transaksi utamanya.
+func (s *SavingService) ApplyRules(tx *gorm.DB, trx *models.Transaction) error {
+	var rules []models.SavingRule
+	if err := tx.Preload("SavingTarget").
+		Where("user_id = ? AND is_active = ? AND transaction_type = ?", trx.UserID, true, trx.Type).
+		Where("category_id IS NULL OR category_id = ?", trx.CategoryID).
+		Find(&rules).Error; err != nil {
+		return err
+	}
+	return s.applyRuleSet(tx, trx, rules)
+}
+
+// ReapplyRules menghitung ulang tabungan otomatis transaksi yang diedit, hanya
+// dari aturan yang dulu menghasilkan kontribusinya. Aturan yang baru dibuat atau
+// sudah dinonaktifkan tidak ikut berlaku surut.
+func (s *SavingService) ReapplyRules(tx *gorm.DB, trx *models.Transaction, ruleIDs []uint) error {
+	if len(ruleIDs) == 0 {
+		return nil
+	}
+	var rules []models.SavingRule
+	if err := tx.Preload("SavingTarget").
+		Where("user_id = ? AND id IN ? AND transaction_type = ?", trx.UserID, ruleIDs, trx.Type).
+		Where("category_id IS NULL OR category_id = ?", trx.CategoryID).
+		Find(&rules).Error; err != nil {
+		return err
+	}
+	return s.applyRuleSet(tx, trx, rules)
+}
+
+func (s *SavingService) applyRuleSet(tx *gorm.DB, trx *models.Transaction, rules []models.SavingRule) error {
+	for _, rule := range rules {
+		amount := round2(rule.Amount(trx.Amount))
+		if amount <= 0 || rule.SavingTarget.ID == 0 {
+			continue
+		}
+
+		ruleID, trxID, accountID := rule.ID, trx.ID, trx.AccountID
+		_, err := s.Record(tx, &rule.SavingTarget, models.SavingContributionDeposit, SavingContributionInput{
+			Amount:           amount,
+			Date:             trx.Date,
+			Description:      fmt.Sprintf("Auto-saving (%s) from transaction #%d", rule.Type, trx.ID),
+			CounterAccountID: &accountID,
+			SavingRuleID:     &ruleID,
+			TransactionID:    &trxID,
+		})
+		var appErr *utils.AppError
+		if errors.As(err, &appErr) || errors.Is(err, ErrInsufficientBalance) {
+			log.Printf("saving rule %d skipped for transaction %d: %v", rule.ID, trx.ID, err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseSavingContributions membatalkan tabungan otomatis yang dibuat ApplyRules
+// dari sebuah transaksi: transfernya dikembalikan, kontribusinya dihapus dan
+// CurrentAmount target dihitung ulang. Transfer yang sudah terhapus lebih dulu
+// (hapus akun cascade) dilewati. Kalau dana di akun tabungan sudah terpakai,
+// ErrInsufficientBalance dikembalikan supaya saldonya tidak jadi minus.
+func releaseSavingContributions(tx *gorm.DB, transactionID uint, batch string) error {
+	var contributions []models.SavingContribution
+	if err := tx.Where("transaction_id = ?", transactionID).Find(&contributions).Error; err != nil {
+		return err
+	}
+	for i := range contributions {
+		c := contributions[i]
+		if c.TransferID != nil {
+			var transfer models.Transfer
+			err := tx.First(&transfer, *c.TransferID).Error
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			if err == nil {
+				var savings models.Account
+				if err := tx.First(&savings, transfer.ToAccountID).Error; err != nil {
+					return err
+				}
+				if savings.Balance < transfer.Amount {
+					return ErrInsufficientBalance
+				}
+				if err := tx.Model(&models.Account{}).Where("id = ?", transfer.FromAccountID).
+					Update("balance", gorm.Expr("balance + ?", transfer.Amount)).Error; err != nil {
+					return err
+				}
+				if err := tx.Model(&models.Account{}).Where("id = ?", transfer.ToAccountID).
+					Update("balance", gorm.Expr("balance - ?", transfer.Amount)).Error; err != nil {
+					return err
+				}
+				if err := ReleaseLoanPayments(tx, "transfer_id", transfer.ID); err != nil {
+					return err
+				}
+				if err := tx.Delete(&transfer).Error; err != nil {
+					return err
+				}
+			}
+		}
+		if err := stampBatch(tx.Model(&c), batch); err != nil {
+			return err
+		}
+		if err := tx.Delete(&c).Error; err != nil {
+			return err
+		}
+		if err := NewSavingService(tx).RecalculateCurrentAmount(tx, c.SavingTargetID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveSavingContributions menyamakan tanggal tabungan otomatis (dan transfernya)
+// dengan tanggal transaksi yang diedit.
+func moveSavingContributions(tx *gorm.DB, transactionID uint, date string) error {
+	transferIDs := tx.Model(&models.SavingContribution{}).
+		Select("transfer_id").
+		Where("transaction_id = ? AND transfer_id IS NOT NULL", transactionID)
+	if err := tx.Model(&models.Transfer{}).Where("id IN (?)", transferIDs).
+		Update("date", date).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.SavingContribution{}).Where("transaction_id = ?", transactionID).
+		Update("date", date).Error
+}
+
+/* ===========================
+   Services
+=========================== */
+
+func (s *SavingService) FindTarget(userID uint, id string) (*models.SavingTarget, error) {
+	var target models.SavingTarget
+	if err := s.db.Joins("JOIN members ON members.id = saving_targets.member_id").
+		Where("members.user_id = ? AND saving_targets.id = ?", userID, id).
+		First(&target).Error; err != nil {
+		return nil, err
+	}
+	return &target, nil
+}
+
+func (s *SavingService) GetContributions(userID uint, targetID string) ([]models.SavingContribution, error) {
+	target, err := s.FindTarget(userID, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	var contributions []models.SavingContribution
+	if err := s.db.Where("saving_target_id = ?", target.ID).
+		Order("date DESC, id DESC").
+		Find(&contributions).Error; err != nil {
+		return nil, err
+	}
+	return contributions, nil
+}
+
+func (s *SavingService) Contribute(userID uint, targetID string, in SavingContributionInput) (*models.SavingContribution, error) {
+	return s.record(userID, targetID, models.SavingContributionDeposit, in)
+}
+
+func (s *SavingService) Withdraw(userID uint, targetID string, in SavingContributionInput) (*models.SavingContribution, error) {
+	return s.record(userID, targetID, models.SavingContributionWithdrawal, in)
+}
+
+func (s *SavingService) record(userID uint, targetID string, cType string, in SavingContributionInput) (*models.SavingContribution, error) {
+	target, err := s.FindTarget(userID, targetID)
+	if err != nil {
+		return nil, utils.NewAppError("Saving target not found", http.StatusNotFound)
+	}
+
+	var contribution *models.SavingContribution
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// baca ulang dengan row lock supaya dua penarikan bersamaan tidak sama-sama lolos cek saldo target
+		var locked models.SavingTarget
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, target.ID).Error; err != nil {
+			return err
+		}
+		var e error
+		contribution, e = s.Record(tx, &locked, cType, in)
+		return e
+	})
+	if err != nil {
+		return nil, err
+	}
+	return contribution, nil
+}
@@ -0,0 +1,33 @@
+package services
+
+import (
+	"log"
+	"time"
+)
+
+// Job adalah pekerjaan latar belakang yang dijalankan berkala oleh scheduler.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(now time.Time) error
+}
+
+// StartScheduler menjalankan setiap job sekali saat start, lalu tiap Interval.
+func StartScheduler(jobs ...Job) {
+	for _, job := range jobs {
+		go func(job Job) {
+			run := func() {
+				if err := job.Run(time.Now()); err != nil {
+					log.Printf("⚠️  job %s failed: %v", job.Name, err)
+				}
+			}
+
+			run()
+			ticker := time.NewTicker(job.Interval)
+			defer ticker.Stop()
+			for range ticker.C {
+				run()
+			}
+		}(job)
+	}
+}
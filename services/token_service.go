@@ -0,0 +1,287 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"finance-app/config"
+	"finance-app/models"
+	"finance-app/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrInvalidRefreshToken = &utils.AppError{
+	Message:    "Invalid or expired refresh token",
+	StatusCode: http.StatusUnauthorized,
+	Code:       "INVALID_REFRESH_TOKEN",
+}
+
+// TokenPair adalah pasangan access + refresh token yang dikirim ke client.
+type TokenPair struct {
+	Token        string    `json:"token"` // access token, nama lama dipertahankan
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresIn    int64     `json:"expires_in"` // detik
+	ExpiresAt    time.Time `json:"expires_at"`
+	SessionID    string    `json:"-"`
+}
+
+// SessionMeta adalah info perangkat yang dicatat saat login.
+type SessionMeta struct {
+	Device    string
+	IPAddress string
+	UserAgent string
+}
+
+type TokenService struct {
+	db *gorm.DB
+}
+
+func NewTokenService(db *gorm.DB) *TokenService {
+	return &TokenService{db: db}
+}
+
+/* ===========================
+   Issue / Refresh
+=========================== */
+
+// IssueTokenPair membuka sesi (family) baru untuk user.
+func (s *TokenService) IssueTokenPair(userID uint, meta SessionMeta) (*TokenPair, error) {
+	familyID, err := utils.RandomHex(16)
+	if err != nil {
+		return nil, err
+	}
+
+	var pair *TokenPair
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		pair, err = s.issue(tx, userID, familyID)
+		if err != nil {
+			return err
+		}
+		session := models.Session{
+			UserID:     userID,
+			FamilyID:   familyID,
+			Device:     truncate(meta.Device, 100),
+			IPAddress:  truncate(meta.IPAddress, 45),
+			UserAgent:  truncate(meta.UserAgent, 255),
+			LastSeenAt: time.Now(),
+			ExpiresAt:  time.Now().Add(config.Get().RefreshTokenTTL),
+		}
+		return tx.Create(&session).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+func (s *TokenService) issue(tx *gorm.DB, userID uint, familyID string) (*TokenPair, error) {
+	cfg := config.Get()
+
+	refresh, hash, err := NewToken()
+	if err != nil {
+		return nil, err
+	}
+	rt := models.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(cfg.RefreshTokenTTL),
+	}
+	if err := tx.Create(&rt).Error; err != nil {
+		return nil, err
+	}
+
+	access, claims, err := utils.GenerateAccessToken(userID, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		Token:        access,
+		RefreshToken: refresh,
+		ExpiresIn:    int64(cfg.AccessTokenTTL.Seconds()),
+		ExpiresAt:    claims.ExpiresAt,
+		SessionID:    familyID,
+	}, nil
+}
+
+// Refresh merotasi refresh token. Token yang sudah pernah dirotasi lalu dipakai
+// lagi dianggap bocor, sehingga seluruh family ikut dicabut.
+func (s *TokenService) Refresh(refreshToken string) (*TokenPair, error) {
+	var pair *TokenPair
+	reused := false
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var rt models.RefreshToken
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", HashToken(refreshToken)).
+			First(&rt).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidRefreshToken
+		}
+		if err != nil {
+			return err
+		}
+
+		if rt.RevokedAt != nil || time.Now().After(rt.ExpiresAt) {
+			return ErrInvalidRefreshToken
+		}
+		if rt.ReplacedAt != nil {
+			reused = true
+			return ErrInvalidRefreshToken
+		}
+
+		now := time.Now()
+		if err := tx.Model(&rt).Update("replaced_at", now).Error; err != nil {
+			return err
+		}
+
+		pair, err = s.issue(tx, rt.UserID, rt.FamilyID)
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.Session{}).
+			Where("family_id = ?", rt.FamilyID).
+			Updates(map[string]interface{}{
+				"last_seen_at": now,
+				"expires_at":   now.Add(config.Get().RefreshTokenTTL),
+			}).Error
+	})
+
+	if reused {
+		// dicabut di luar transaksi di atas supaya tidak ikut di-rollback
+		var rt models.RefreshToken
+		if s.db.Where("token_hash = ?", HashToken(refreshToken)).First(&rt).Error == nil {
+			if rerr := s.RevokeFamily(rt.UserID, rt.FamilyID); rerr != nil {
+				return nil, rerr
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+/* ===========================
+   Revocation
+=========================== */
+
+// Logout mencabut access token saat ini dan seluruh sesinya.
+func (s *TokenService) Logout(p *utils.Principal) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if p.TokenID != "" {
+			if err := s.revoke(tx, p.UserID, models.RevokedTokenJTI, p.TokenID, p.ExpiresAt); err != nil {
+				return err
+			}
+		}
+		if p.SessionID == "" {
+			return nil
+		}
+		return s.revokeFamily(tx, p.UserID, p.SessionID)
+	})
+}
+
+// RevokeFamily mencabut satu sesi: refresh token-nya dan semua access token turunannya.
+func (s *TokenService) RevokeFamily(userID uint, familyID string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return s.revokeFamily(tx, userID, familyID)
+	})
+}
+
+// RevokeAllForUser mencabut semua sesi user (mis. setelah ganti password).
+func (s *TokenService) RevokeAllForUser(userID uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var families []string
+		if err := tx.Model(&models.RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Distinct().Pluck("family_id", &families).Error; err != nil {
+			return err
+		}
+		for _, f := range families {
+			if err := s.revokeFamily(tx, userID, f); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *TokenService) revokeFamily(tx *gorm.DB, userID uint, familyID string) error {
+	if err := tx.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND family_id = ? AND revoked_at IS NULL", userID, familyID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.Session{}).
+		Where("user_id = ? AND family_id = ? AND revoked_at IS NULL", userID, familyID).
+		Update("revoked_at", time.Now()).Error; err != nil {
+		return err
+	}
+	// access token family ini paling lama hidup selama AccessTokenTTL
+	expiresAt := time.Now().Add(config.Get().AccessTokenTTL)
+	return s.revoke(tx, userID, models.RevokedTokenFamily, familyID, expiresAt)
+}
+
+func (s *TokenService) revoke(tx *gorm.DB, userID uint, kind, value string, expiresAt time.Time) error {
+	entry := models.RevokedToken{
+		Kind:      kind,
+		Value:     value,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&entry).Error
+}
+
+/* ===========================
+   Sessions
+=========================== */
+
+// ListSessions mengembalikan sesi aktif user; currentFamily ditandai Current.
+func (s *TokenService) ListSessions(userID uint, currentFamily string) ([]models.Session, error) {
+	var sessions []models.Session
+	err := s.db.
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].FamilyID == currentFamily
+	}
+	return sessions, nil
+}
+
+// RevokeSession mencabut satu sesi milik user berdasarkan ID-nya.
+func (s *TokenService) RevokeSession(userID, sessionID uint) error {
+	var session models.Session
+	if err := s.db.
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+		First(&session).Error; err != nil {
+		return err
+	}
+	return s.RevokeFamily(userID, session.FamilyID)
+}
+
+// CleanupExpired membuang refresh token, sesi dan entri pencabutan yang sudah kedaluwarsa.
+func (s *TokenService) CleanupExpired(now time.Time) error {
+	if err := s.db.Where("expires_at < ?", now).Delete(&models.RefreshToken{}).Error; err != nil {
+		return err
+	}
+	if err := s.db.Where("expires_at < ? OR revoked_at < ?", now, now.Add(-config.Get().AccessTokenTTL)).
+		Delete(&models.Session{}).Error; err != nil {
+		return err
+	}
+	return s.db.Where("expires_at < ?", now).Delete(&models.RevokedToken{}).Error
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
@@ -100,6 +100,80 @@ func (s *TransactionService) validateRelations(userID, memberID, accountID, cate
 	return nil
 }
 
+// checkSpendingCap menolak pengeluaran yang melewati batas periode member.
+// excludeID (kalau bukan 0) adalah transaksi yang sedang diedit, tidak ikut dihitung.
+func (s *TransactionService) checkSpendingCap(memberID uint, date string, amount float64, excludeID uint) error {
+	var member models.Member
+	if err := s.db.First(&member, memberID).Error; err != nil {
+		return err
+	}
+	if member.SpendingCap <= 0 {
+		return nil
+	}
+
+	d, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return utils.NewAppError("Invalid date format", http.StatusBadRequest)
+	}
+	var start, end time.Time
+	if member.SpendingCapPeriod == "weekly" {
+		offset := (int(d.Weekday()) + 6) % 7 // Senin sebagai awal minggu
+		start = d.AddDate(0, 0, -offset)
+		end = start.AddDate(0, 0, 6)
+	} else {
+		start = time.Date(d.Year(), d.Month(), 1, 0, 0, 0, 0, time.UTC)
+		end = start.AddDate(0, 1, -1)
+	}
+
+	query := s.db.Model(&models.Transaction{}).
+		Where("member_id = ? AND type = 'expense' AND date BETWEEN ? AND ?", memberID, start.Format("2006-01-02"), end.Format("2006-01-02"))
+	if excludeID != 0 {
+		query = query.Where("id <> ?", excludeID)
+	}
+	var spent float64
+	if err := query.Select("COALESCE(SUM(amount),0)").Scan(&spent).Error; err != nil {
+		return err
+	}
+	if spent+amount > member.SpendingCap {
+		return &utils.AppError{
+			Message:    "Spending cap exceeded for this period",
+			StatusCode: http.StatusBadRequest,
+			Code:       "SPENDING_CAP_EXCEEDED",
+			Details: map[string]interface{}{
+				"cap":       member.SpendingCap,
+				"period":    member.SpendingCapPeriod,
+				"spent":     spent,
+				"remaining": member.SpendingCap - spent,
+			},
+		}
+	}
+	return nil
+}
+
+// EnsureAccountActive menolak transaksi baru ke akun yang sudah diarsipkan.
+func EnsureAccountActive(db *gorm.DB, accountID uint) error {
+	var acc models.Account
+	if err := db.Select("id, archived_at").First(&acc, accountID).Error; err != nil {
+		return utils.NewAppError("Account not found", http.StatusNotFound)
+	}
+	if acc.ArchivedAt != nil {
+		return utils.NewAppError("Account is archived", http.StatusBadRequest)
+	}
+	return nil
+}
+
+// EnsureCategoryActive menolak pemakaian kategori yang sudah diarsipkan.
+func EnsureCategoryActive(db *gorm.DB, categoryID uint) error {
+	var cat models.Category
+	if err := db.Select("id, archived_at").First(&cat, categoryID).Error; err != nil {
+		return utils.NewAppError("Category not found", http.StatusNotFound)
+	}
+	if cat.ArchivedAt != nil {
+		return utils.NewAppError("Category is archived", http.StatusBadRequest)
+	}
+	return nil
+}
+
 /* ===========================
    Services
 =========================== */
@@ -186,6 +260,17 @@ func (s *TransactionService) Create(userID uint, req map[string]interface{}) (*m
 	if err := s.validateRelations(userID, memberID, accountID, categoryID); err != nil {
 		return nil, err
 	}
+	if err := EnsureAccountActive(s.db, accountID); err != nil {
+		return nil, err
+	}
+	if err := EnsureCategoryActive(s.db, categoryID); err != nil {
+		return nil, err
+	}
+	if req["type"] == "expense" {
+		if err := s.checkSpendingCap(memberID, dateStr, req["amount"].(float64), 0); err != nil {
+			return nil, err
+		}
+	}
 
 	trx := models.Transaction{
 		UserID:      userID,
@@ -205,7 +290,8 @@ func (s *TransactionService) Create(userID uint, req map[string]interface{}) (*m
 		if err := s.adjustAccountBalance(tx, trx.AccountID, trx.Type, trx.Amount, true); err != nil {
 			return err
 		}
-		return nil
+		// jalankan aturan tabungan otomatis (round-up / persentase)
+		return NewSavingService(tx).ApplyRules(tx, &trx)
 	})
 	if err != nil {
 		return nil, err
@@ -255,8 +341,47 @@ func (s *TransactionService) Update(userID uint, id string, req map[string]inter
 	if err := s.validateRelations(userID, newMemberID, newAccountID, newCategoryID); err != nil {
 		return nil, err
 	}
+	// transaksi lama di akun/kategori arsip tetap bisa diedit, tapi tidak boleh dipindah ke sana
+	if newAccountID != existing.AccountID {
+		if err := EnsureAccountActive(s.db, newAccountID); err != nil {
+			return nil, err
+		}
+	}
+	if newCategoryID != existing.CategoryID {
+		if err := EnsureCategoryActive(s.db, newCategoryID); err != nil {
+			return nil, err
+		}
+	}
+	// edit yang tidak mengubah nominal / tipe / member / tanggal tidak dicek ulang
+	capChanged := newType != existing.Type || newAmount != existing.Amount ||
+		newMemberID != existing.MemberID || newDate != existing.Date
+	if newType == "expense" && capChanged {
+		if err := s.checkSpendingCap(newMemberID, newDate, newAmount, existing.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	// tabungan otomatis hanya dihitung ulang kalau nominal / tipe / kategori / akun berubah
+	savingsChanged := newType != existing.Type || newAmount != existing.Amount ||
+		newCategoryID != existing.CategoryID || newAccountID != existing.AccountID
 
 	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var ruleIDs []uint
+		if savingsChanged {
+			if err := tx.Model(&models.SavingContribution{}).
+				Where("transaction_id = ? AND saving_rule_id IS NOT NULL", existing.ID).
+				Pluck("saving_rule_id", &ruleIDs).Error; err != nil {
+				return err
+			}
+			// tabungan otomatis dari versi lama dibatalkan, lalu dihitung ulang di bawah
+			if err := releaseSavingContributions(tx, existing.ID, ""); err != nil {
+				return err
+			}
+		} else if newDate != existing.Date {
+			if err := moveSavingContributions(tx, existing.ID, newDate); err != nil {
+				return err
+			}
+		}
 		// rollback saldo lama
 		if err := s.adjustAccountBalance(tx, existing.AccountID, existing.Type, existing.Amount, false); err != nil {
 			return err
@@ -279,7 +404,17 @@ func (s *TransactionService) Update(userID uint, id string, req map[string]inter
 		if err := s.adjustAccountBalance(tx, newAccountID, newType, newAmount, true); err != nil {
 			return err
 		}
-		return nil
+		// ikutkan perubahan ke angsuran pinjaman / dividen yang tertaut ke transaksi ini
+		if err := refreshLoanPayments(tx, existing); err != nil {
+			return err
+		}
+		if err := refreshInvestmentDividends(tx, existing); err != nil {
+			return err
+		}
+		if !savingsChanged {
+			return nil
+		}
+		return NewSavingService(tx).ReapplyRules(tx, existing, ruleIDs)
 	})
 	if err != nil {
 		return nil, err
@@ -298,6 +433,15 @@ func (s *TransactionService) Delete(userID uint, id string) error {
 		if err := s.adjustAccountBalance(tx, trx.AccountID, trx.Type, trx.Amount, false); err != nil {
 			return err
 		}
+		if err := ReleaseLoanPayments(tx, "transaction_id", trx.ID); err != nil {
+			return err
+		}
+		if err := releaseInvestmentDividends(tx, trx.ID, ""); err != nil {
+			return err
+		}
+		if err := releaseSavingContributions(tx, trx.ID, ""); err != nil {
+			return err
+		}
 		return tx.Delete(trx).Error
 	})
 }
@@ -0,0 +1,376 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"finance-app/config"
+	"finance-app/models"
+	"finance-app/utils"
+
+	"gorm.io/gorm"
+)
+
+const (
+	TrashTransactions = "transactions"
+	TrashTransfers    = "transfers"
+	TrashAccounts     = "accounts"
+	TrashCategories   = "categories"
+)
+
+// TrashTypes adalah jenis data yang bisa dilihat & dipulihkan dari trash.
+var TrashTypes = []string{TrashTransactions, TrashTransfers, TrashAccounts, TrashCategories}
+
+// purgeBatchSize membatasi jumlah baris yang dihapus permanen per tabel per run.
+const purgeBatchSize = 500
+
+type TrashService struct {
+	db *gorm.DB
+}
+
+func NewTrashService(db *gorm.DB) *TrashService {
+	return &TrashService{db: db}
+}
+
+func IsTrashType(t string) bool {
+	for _, v := range TrashTypes {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
+
+func errRestoreConflict(msg string) error {
+	return &utils.AppError{Message: msg, StatusCode: http.StatusConflict, Code: "RESTORE_CONFLICT"}
+}
+
+/* ===========================
+   List
+=========================== */
+
+// List mengembalikan data yang dihapus dalam masa retensi, dikelompokkan per jenis.
+// trashType kosong = semua jenis.
+func (s *TrashService) List(userID uint, trashType string) (map[string]interface{}, error) {
+	since := time.Now().Add(-config.Get().TrashRetention)
+	result := map[string]interface{}{}
+
+	want := func(t string) bool { return trashType == "" || trashType == t }
+	deleted := func(q *gorm.DB) *gorm.DB {
+		return q.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at >= ?", since).Order("deleted_at DESC")
+	}
+
+	if want(TrashTransactions) {
+		var rows []models.Transaction
+		if err := deleted(s.db.Model(&models.Transaction{})).
+			Where("user_id = ?", userID).Find(&rows).Error; err != nil {
+			return nil, err
+		}
+		result[TrashTransactions] = rows
+	}
+	if want(TrashTransfers) {
+		var rows []models.Transfer
+		if err := deleted(s.db.Model(&models.Transfer{})).
+			Where("user_id = ?", userID).Find(&rows).Error; err != nil {
+			return nil, err
+		}
+		result[TrashTransfers] = rows
+	}
+	if want(TrashAccounts) {
+		var rows []models.Account
+		if err := deleted(s.db.Model(&models.Account{})).
+			Where("member_id IN (?)", s.db.Unscoped().Model(&models.Member{}).Select("id").Where("user_id = ?", userID)).
+			Find(&rows).Error; err != nil {
+			return nil, err
+		}
+		result[TrashAccounts] = rows
+	}
+	if want(TrashCategories) {
+		var rows []models.Category
+		if err := deleted(s.db.Model(&models.Category{})).
+			Where("user_id = ?", userID).Find(&rows).Error; err != nil {
+			return nil, err
+		}
+		result[TrashCategories] = rows
+	}
+	return result, nil
+}
+
+/* ===========================
+   Restore
+=========================== */
+
+// Restore memulihkan satu data dari trash dan menerapkan ulang efek saldonya.
+func (s *TrashService) Restore(userID uint, trashType string, id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		switch trashType {
+		case TrashTransactions:
+			return s.restoreTransaction(tx, userID, id)
+		case TrashTransfers:
+			return s.restoreTransfer(tx, userID, id)
+		case TrashAccounts:
+			return s.restoreAccount(tx, userID, id)
+		case TrashCategories:
+			return s.restoreCategory(tx, userID, id)
+		}
+		return &utils.AppError{Message: "Unknown trash type", StatusCode: http.StatusBadRequest}
+	})
+}
+
+func undelete(tx *gorm.DB, model interface{}, id uint) error {
+	return tx.Unscoped().Model(model).Where("id = ?", id).Update("deleted_at", nil).Error
+}
+
+// stampBatch menandai baris yang akan ikut terhapus dalam satu batch hapus akun.
+// batch kosong = hapus biasa, tidak ditandai.
+func stampBatch(query *gorm.DB, batch string) error {
+	if batch == "" {
+		return nil
+	}
+	return query.Update("deletion_batch", batch).Error
+}
+
+// undeleteBatch memulihkan semua baris model yang terhapus dalam batch.
+func undeleteBatch(tx *gorm.DB, model interface{}, batch string) error {
+	return tx.Unscoped().Model(model).
+		Where("deletion_batch = ? AND deleted_at IS NOT NULL", batch).
+		Updates(map[string]interface{}{"deleted_at": nil, "deletion_batch": ""}).Error
+}
+
+func (s *TrashService) restoreTransaction(tx *gorm.DB, userID, id uint) error {
+	var trx models.Transaction
+	if err := tx.Unscoped().Where("user_id = ? AND id = ? AND deleted_at IS NOT NULL", userID, id).
+		First(&trx).Error; err != nil {
+		return err
+	}
+
+	// relasi harus masih ada, kalau tidak transaksi tidak bisa dipulihkan
+	if err := tx.Where("user_id = ? AND id = ?", userID, trx.MemberID).First(&models.Member{}).Error; err != nil {
+		return notFoundAsConflict(err, "Member of this transaction has been deleted")
+	}
+	if err := tx.Where("user_id = ? AND id = ?", userID, trx.CategoryID).First(&models.Category{}).Error; err != nil {
+		return notFoundAsConflict(err, "Category of this transaction has been deleted; restore it first")
+	}
+	if err := tx.First(&models.Account{}, trx.AccountID).Error; err != nil {
+		return notFoundAsConflict(err, "Account of this transaction has been deleted; restore it first")
+	}
+
+	if err := NewTransactionService(tx).adjustAccountBalance(tx, trx.AccountID, trx.Type, trx.Amount, true); err != nil {
+		return err
+	}
+	return undelete(tx, &models.Transaction{}, trx.ID)
+}
+
+func (s *TrashService) restoreTransfer(tx *gorm.DB, userID, id uint) error {
+	var transfer models.Transfer
+	if err := tx.Unscoped().Where("user_id = ? AND id = ? AND deleted_at IS NOT NULL", userID, id).
+		First(&transfer).Error; err != nil {
+		return err
+	}
+
+	var from, to models.Account
+	if err := tx.First(&from, transfer.FromAccountID).Error; err != nil {
+		return notFoundAsConflict(err, "Source account has been deleted; restore it first")
+	}
+	if err := tx.First(&to, transfer.ToAccountID).Error; err != nil {
+		return notFoundAsConflict(err, "Destination account has been deleted; restore it first")
+	}
+	// transfer yang terhapus bersama akun (cascade) tidak pernah dibalik di sisi
+	// akun tersebut, jadi sisi itu tidak diterapkan ulang
+	applyFrom := transfer.DeletionBatch == "" || from.DeletionBatch != transfer.DeletionBatch
+	applyTo := transfer.DeletionBatch == "" || to.DeletionBatch != transfer.DeletionBatch
+	if applyFrom && from.Balance < transfer.Amount {
+		return ErrInsufficientBalance
+	}
+
+	if applyFrom {
+		from.Balance -= transfer.Amount
+		if err := tx.Save(&from).Error; err != nil {
+			return err
+		}
+	}
+	if applyTo {
+		to.Balance += transfer.Amount
+		if err := tx.Save(&to).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Unscoped().Model(&transfer).Updates(map[string]interface{}{"deleted_at": nil, "deletion_batch": ""}).Error
+}
+
+func (s *TrashService) restoreAccount(tx *gorm.DB, userID, id uint) error {
+	var acc models.Account
+	if err := tx.Unscoped().
+		Joins("JOIN members ON members.id = accounts.member_id").
+		Where("members.user_id = ? AND accounts.id = ? AND accounts.deleted_at IS NOT NULL", userID, id).
+		First(&acc).Error; err != nil {
+		return err
+	}
+	if err := tx.First(&models.Member{}, acc.MemberID).Error; err != nil {
+		return notFoundAsConflict(err, "Member of this account has been deleted")
+	}
+	// saldo akun disimpan apa adanya saat dihapus, jadi cukup dipulihkan
+	if err := undelete(tx, &models.Account{}, acc.ID); err != nil {
+		return err
+	}
+	return s.restoreAccountCascade(tx, &acc)
+}
+
+// restoreAccountCascade memulihkan riwayat yang terhapus dalam batch yang sama
+// dengan akun (lihat IntegrityService.cascadeAccount). Transaksinya tidak pernah
+// di-rollback dari saldo akun, jadi tidak di-apply ulang; untuk transfer hanya
+// sisi akun lain yang perlu diterapkan kembali. Akun yang dihapus sebelum ada
+// DeletionBatch dipulihkan tanpa riwayatnya.
+func (s *TrashService) restoreAccountCascade(tx *gorm.DB, acc *models.Account) error {
+	// DeletionBatch akun sengaja tidak dikosongkan: transfer batch yang belum bisa
+	// dipulihkan di sini masih dikenali oleh restoreTransfer
+	batch := acc.DeletionBatch
+	if batch == "" {
+		return nil
+	}
+
+	var targetIDs []uint
+	if err := tx.Unscoped().Model(&models.SavingTarget{}).
+		Where("deletion_batch = ? AND deleted_at IS NOT NULL", batch).
+		Pluck("id", &targetIDs).Error; err != nil {
+		return err
+	}
+	for _, model := range []interface{}{
+		&models.Transaction{}, &models.RecurringTransaction{},
+		&models.SavingTarget{}, &models.SavingRule{},
+		&models.InvestmentLot{}, &models.InvestmentDividend{}, &models.Asset{},
+	} {
+		if err := undeleteBatch(tx, model, batch); err != nil {
+			return err
+		}
+	}
+
+	var transfers []models.Transfer
+	if err := tx.Unscoped().Where("deletion_batch = ? AND deleted_at IS NOT NULL", batch).
+		Find(&transfers).Error; err != nil {
+		return err
+	}
+	for _, t := range transfers {
+		otherID, delta := t.ToAccountID, t.Amount
+		if t.ToAccountID == acc.ID {
+			otherID, delta = t.FromAccountID, -t.Amount
+		}
+		var other models.Account
+		if otherID != acc.ID {
+			// akun lawan sudah dihapus juga: transfer tetap di trash
+			if err := tx.First(&other, otherID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					continue
+				}
+				return err
+			}
+			if other.Balance+delta < 0 {
+				continue
+			}
+			if err := tx.Model(&other).Update("balance", gorm.Expr("balance + ?", delta)).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Unscoped().Model(&t).Updates(map[string]interface{}{"deleted_at": nil, "deletion_batch": ""}).Error; err != nil {
+			return err
+		}
+	}
+
+	// kontribusi tabungan hanya dipulihkan kalau transfernya (jika ada) ikut pulih
+	var contributions []models.SavingContribution
+	if err := tx.Unscoped().Where("deletion_batch = ? AND deleted_at IS NOT NULL", batch).
+		Where("transfer_id IS NULL OR transfer_id IN (?)", tx.Model(&models.Transfer{}).Select("id")).
+		Find(&contributions).Error; err != nil {
+		return err
+	}
+	targets := map[uint]bool{}
+	for _, id := range targetIDs {
+		targets[id] = true
+	}
+	for _, c := range contributions {
+		if err := tx.Unscoped().Model(&c).Updates(map[string]interface{}{"deleted_at": nil, "deletion_batch": ""}).Error; err != nil {
+			return err
+		}
+		targets[c.SavingTargetID] = true
+	}
+	savings := NewSavingService(tx)
+	for id := range targets {
+		if err := savings.RecalculateCurrentAmount(tx, id); err != nil {
+			return err
+		}
+	}
+
+	// uang saku hanya dipulihkan kalau akun di sisi lainnya masih ada
+	activeAccounts := tx.Model(&models.Account{}).Select("id")
+	if err := tx.Unscoped().Model(&models.Allowance{}).
+		Where("deletion_batch = ? AND deleted_at IS NOT NULL", batch).
+		Where("from_account_id IN (?) AND to_account_id IN (?)", activeAccounts, activeAccounts).
+		Updates(map[string]interface{}{"deleted_at": nil, "deletion_batch": ""}).Error; err != nil {
+		return err
+	}
+
+	if err := relinkLoanPayments(tx, batch); err != nil {
+		return err
+	}
+	if acc.Type == models.AccountTypeInvestment {
+		return syncPortfolio(tx, acc.ID)
+	}
+	return nil
+}
+
+func (s *TrashService) restoreCategory(tx *gorm.DB, userID, id uint) error {
+	var cat models.Category
+	if err := tx.Unscoped().Where("user_id = ? AND id = ? AND deleted_at IS NOT NULL", userID, id).
+		First(&cat).Error; err != nil {
+		return err
+	}
+	if cat.ParentID != nil {
+		if err := tx.Where("user_id = ? AND id = ?", userID, *cat.ParentID).First(&models.Category{}).Error; err != nil {
+			return notFoundAsConflict(err, "Parent category has been deleted; restore it first")
+		}
+	}
+	return undelete(tx, &models.Category{}, cat.ID)
+}
+
+func notFoundAsConflict(err error, msg string) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errRestoreConflict(msg)
+	}
+	return err
+}
+
+/* ===========================
+   Purge
+=========================== */
+
+// Purge menghapus permanen data yang sudah di trash lebih lama dari masa retensi.
+// Baris yang masih direferensikan data lain (foreign key) dilewati.
+func (s *TrashService) Purge(now time.Time) error {
+	cutoff := now.Add(-config.Get().TrashRetention)
+
+	// urutan penting: anak dulu baru induk
+	for _, model := range []interface{}{&models.Transaction{}, &models.Transfer{}, &models.Account{}, &models.Category{}} {
+		var ids []uint
+		if err := s.db.Unscoped().Model(model).
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+			Limit(purgeBatchSize).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+
+		purged := 0
+		for _, id := range ids {
+			if err := s.db.Unscoped().Delete(model, id).Error; err != nil {
+				log.Printf("⚠️  purge: skip %T #%d: %v", model, id, err)
+				continue
+			}
+			purged++
+		}
+		if purged > 0 {
+			log.Printf("🗑️  purge: removed %d %T older than %s", purged, model, cutoff.Format("2006-01-02"))
+		}
+	}
+	return nil
+}
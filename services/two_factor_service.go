@@ -0,0 +1,298 @@
+package services
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/base64"
+	"image/png"
+	"net/http"
+	"strings"
+	"time"
+
+	"finance-app/models"
+	"finance-app/utils"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	totpIssuer        = "Finance App"
+	totpPeriod        = 30
+	recoveryCodeCount = 10
+)
+
+var (
+	ErrInvalidTwoFactorCode = &utils.AppError{
+		Message:    "Invalid two-factor code",
+		StatusCode: http.StatusUnauthorized,
+		Code:       "INVALID_2FA_CODE",
+	}
+	ErrTwoFactorEnabled = &utils.AppError{
+		Message:    "Two-factor authentication is already enabled",
+		StatusCode: http.StatusConflict,
+		Code:       "2FA_ALREADY_ENABLED",
+	}
+	ErrTwoFactorNotEnabled = &utils.AppError{
+		Message:    "Two-factor authentication is not enabled",
+		StatusCode: http.StatusConflict,
+		Code:       "2FA_NOT_ENABLED",
+	}
+)
+
+// TwoFactorSetup dikirim ke client saat enroll untuk dipindai aplikasi authenticator.
+type TwoFactorSetup struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+	QRPNG      string `json:"qr_png"` // data URI base64
+}
+
+type TwoFactorService struct {
+	db *gorm.DB
+}
+
+func NewTwoFactorService(db *gorm.DB) *TwoFactorService {
+	return &TwoFactorService{db: db}
+}
+
+/* ===========================
+   Enrollment
+=========================== */
+
+// Setup membuat secret baru (belum aktif sampai dikonfirmasi lewat Confirm).
+func (s *TwoFactorService) Setup(userID uint) (*TwoFactorSetup, error) {
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      totpIssuer,
+		AccountName: user.Email,
+		Period:      totpPeriod,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := key.Image(256, 256)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Model(&user).Updates(map[string]interface{}{
+		"totp_secret":    key.Secret(),
+		"totp_last_step": 0,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorSetup{
+		Secret:     key.Secret(),
+		OTPAuthURI: key.URL(),
+		QRPNG:      "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()),
+	}, nil
+}
+
+// Confirm mengaktifkan 2FA setelah kode pertama valid, lalu mengembalikan recovery code.
+func (s *TwoFactorService) Confirm(userID uint, code string) ([]string, error) {
+	var codes []string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.First(&user, userID).Error; err != nil {
+			return err
+		}
+		if user.TwoFactorEnabled {
+			return ErrTwoFactorEnabled
+		}
+		if user.TOTPSecret == "" {
+			return &utils.AppError{Message: "Run 2FA setup first", StatusCode: http.StatusBadRequest}
+		}
+		if err := s.verifyTOTP(tx, &user, code); err != nil {
+			return err
+		}
+		if err := tx.Model(&user).Update("two_factor_enabled", true).Error; err != nil {
+			return err
+		}
+
+		var err error
+		codes, err = s.replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	return codes, err
+}
+
+// Disable mematikan 2FA. Butuh password dan kode (TOTP atau recovery code).
+func (s *TwoFactorService) Disable(userID uint, password, code string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.First(&user, userID).Error; err != nil {
+			return err
+		}
+		if !user.TwoFactorEnabled {
+			return ErrTwoFactorNotEnabled
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+			return &utils.AppError{Message: "Invalid password", StatusCode: http.StatusUnauthorized}
+		}
+		if err := s.verify(tx, &user, code); err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&user).Updates(map[string]interface{}{
+			"two_factor_enabled": false,
+			"totp_secret":        "",
+			"totp_last_step":     0,
+		}).Error
+	})
+}
+
+// RegenerateRecoveryCodes mengganti semua recovery code; kode lama langsung tidak berlaku.
+func (s *TwoFactorService) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	var codes []string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.First(&user, userID).Error; err != nil {
+			return err
+		}
+		if !user.TwoFactorEnabled {
+			return ErrTwoFactorNotEnabled
+		}
+		if err := s.verifyTOTP(tx, &user, code); err != nil {
+			return err
+		}
+
+		var err error
+		codes, err = s.replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	return codes, err
+}
+
+/* ===========================
+   Verification
+=========================== */
+
+// Verify mengecek kode TOTP atau recovery code untuk langkah kedua login.
+func (s *TwoFactorService) Verify(userID uint, code string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.First(&user, userID).Error; err != nil {
+			return err
+		}
+		if !user.TwoFactorEnabled {
+			return ErrTwoFactorNotEnabled
+		}
+		return s.verify(tx, &user, code)
+	})
+}
+
+func (s *TwoFactorService) verify(tx *gorm.DB, user *models.User, code string) error {
+	code = strings.TrimSpace(code)
+	if len(code) == 6 {
+		return s.verifyTOTP(tx, user, code)
+	}
+	return s.useRecoveryCode(tx, user.ID, code)
+}
+
+// verifyTOTP menerima kode dari step sebelumnya/sekarang/berikutnya (toleransi jam),
+// dan menolak step yang sudah pernah dipakai.
+func (s *TwoFactorService) verifyTOTP(tx *gorm.DB, user *models.User, code string) error {
+	code = strings.TrimSpace(code)
+	now := time.Now().Unix() / totpPeriod
+
+	for step := now - 1; step <= now+1; step++ {
+		expected, err := totp.GenerateCodeCustom(user.TOTPSecret, time.Unix(step*totpPeriod, 0), totp.ValidateOpts{
+			Period:    totpPeriod,
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err != nil {
+			return err
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) != 1 {
+			continue
+		}
+		if step <= user.TOTPLastStep {
+			return ErrInvalidTwoFactorCode
+		}
+
+		res := tx.Model(&models.User{}).
+			Where("id = ? AND totp_last_step < ?", user.ID, step).
+			Update("totp_last_step", step)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrInvalidTwoFactorCode
+		}
+		user.TOTPLastStep = step
+		return nil
+	}
+	return ErrInvalidTwoFactorCode
+}
+
+func (s *TwoFactorService) useRecoveryCode(tx *gorm.DB, userID uint, code string) error {
+	res := tx.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, HashToken(normalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// RemainingRecoveryCodes menghitung recovery code yang belum dipakai.
+func (s *TwoFactorService) RemainingRecoveryCodes(userID uint) (int64, error) {
+	var cnt int64
+	err := s.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&cnt).Error
+	return cnt, err
+}
+
+/* ===========================
+   Helpers
+=========================== */
+
+func (s *TwoFactorService) replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	rows := make([]models.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw, err := utils.RandomHex(5)
+		if err != nil {
+			return nil, err
+		}
+		code := raw[:5] + "-" + raw[5:]
+		codes = append(codes, code)
+		rows = append(rows, models.RecoveryCode{UserID: userID, CodeHash: HashToken(normalizeRecoveryCode(code))})
+	}
+	if err := tx.Create(&rows).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.ReplaceAll(code, "-", "")
+}
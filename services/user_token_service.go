@@ -0,0 +1,212 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"finance-app/config"
+	"finance-app/models"
+	"finance-app/utils"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	passwordResetTTL     = time.Hour
+	emailVerificationTTL = 48 * time.Hour
+)
+
+var ErrInvalidUserToken = &utils.AppError{
+	Message:    "Invalid or expired token",
+	StatusCode: http.StatusBadRequest,
+	Code:       "INVALID_TOKEN",
+}
+
+// UserTokenService menangani alur berbasis email: lupa password dan verifikasi email.
+type UserTokenService struct {
+	db     *gorm.DB
+	mailer Mailer
+}
+
+func NewUserTokenService(db *gorm.DB, mailer Mailer) *UserTokenService {
+	return &UserTokenService{db: db, mailer: mailer}
+}
+
+/* ===========================
+   Helpers
+=========================== */
+
+// create membuat token baru dan membatalkan token lain dengan tujuan yang sama.
+func (s *UserTokenService) create(user models.User, purpose string, ttl time.Duration) (string, error) {
+	token, hash, err := NewToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.UserToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", user.ID, purpose).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.UserToken{
+			UserID:    user.ID,
+			Purpose:   purpose,
+			Email:     user.Email,
+			TokenHash: hash,
+			ExpiresAt: time.Now().Add(ttl),
+		}).Error
+	})
+	return token, err
+}
+
+// consume menandai token terpakai dan mengembalikannya. Harus dipanggil di dalam transaksi.
+func (s *UserTokenService) consume(tx *gorm.DB, token, purpose string) (*models.UserToken, error) {
+	var ut models.UserToken
+	err := tx.Where("token_hash = ? AND purpose = ?", HashToken(token), purpose).First(&ut).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidUserToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if ut.UsedAt != nil || time.Now().After(ut.ExpiresAt) {
+		return nil, ErrInvalidUserToken
+	}
+
+	// update bersyarat supaya token tidak bisa dipakai dua kali secara bersamaan
+	res := tx.Model(&models.UserToken{}).
+		Where("id = ? AND used_at IS NULL", ut.ID).
+		Update("used_at", time.Now())
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrInvalidUserToken
+	}
+	return &ut, nil
+}
+
+func appLink(path, token string) string {
+	return fmt.Sprintf("%s%s?token=%s", config.Get().AppURL, path, url.QueryEscape(token))
+}
+
+/* ===========================
+   Password reset
+=========================== */
+
+// RequestPasswordReset mengirim link reset jika email terdaftar. Email yang tidak
+// dikenal diabaikan tanpa error supaya tidak bisa dipakai menebak akun.
+func (s *UserTokenService) RequestPasswordReset(email string) error {
+	var user models.User
+	err := s.db.Where("email = ?", email).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token, err := s.create(user, models.UserTokenPasswordReset, passwordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(MailMessage{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to reset your password. It expires in %s.\n\n%s\n\nIf you didn't request this, you can ignore this email.\n",
+			user.Username, passwordResetTTL, appLink("/reset-password", token)),
+	})
+}
+
+// ResetPassword mengganti password dengan token reset lalu mencabut semua sesi.
+func (s *UserTokenService) ResetPassword(token, newPassword string) error {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	var userID uint
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		ut, err := s.consume(tx, token, models.UserTokenPasswordReset)
+		if err != nil {
+			return err
+		}
+		userID = ut.UserID
+
+		var user models.User
+		if err := tx.First(&user, ut.UserID).Error; err != nil {
+			return err
+		}
+		updates := map[string]interface{}{"password": string(hashed)}
+		// link reset sampai ke inbox yang sama, berarti email-nya terbukti valid
+		if user.EmailVerifiedAt == nil && user.Email == ut.Email {
+			updates["email_verified_at"] = time.Now()
+		}
+		return tx.Model(&user).Updates(updates).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	return NewTokenService(s.db).RevokeAllForUser(userID)
+}
+
+/* ===========================
+   Email verification
+=========================== */
+
+// SendEmailVerification mengirim link verifikasi ke email user saat ini.
+func (s *UserTokenService) SendEmailVerification(user models.User) error {
+	token, err := s.create(user, models.UserTokenEmailVerification, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(MailMessage{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below. It expires in %s.\n\n%s\n",
+			user.Username, emailVerificationTTL, appLink("/verify-email", token)),
+	})
+}
+
+// SendEmailVerificationAsync sama seperti SendEmailVerification tapi kegagalan hanya dicatat.
+func (s *UserTokenService) SendEmailVerificationAsync(user models.User) {
+	go func() {
+		if err := s.SendEmailVerification(user); err != nil {
+			log.Printf("⚠️  failed to send verification email to user %d: %v", user.ID, err)
+		}
+	}()
+}
+
+// VerifyEmail menandai email terverifikasi. Token untuk alamat lama (sebelum
+// email diganti) ditolak.
+func (s *UserTokenService) VerifyEmail(token string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		ut, err := s.consume(tx, token, models.UserTokenEmailVerification)
+		if err != nil {
+			return err
+		}
+
+		var user models.User
+		if err := tx.First(&user, ut.UserID).Error; err != nil {
+			return err
+		}
+		if user.Email != ut.Email {
+			return ErrInvalidUserToken
+		}
+		return tx.Model(&user).Update("email_verified_at", time.Now()).Error
+	})
+}
+
+// CleanupExpired membuang token email yang sudah kedaluwarsa.
+func (s *UserTokenService) CleanupExpired(now time.Time) error {
+	return s.db.Where("expires_at < ?", now).Delete(&models.UserToken{}).Error
+}
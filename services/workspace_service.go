@@ -0,0 +1,284 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"finance-app/models"
+	"finance-app/utils"
+
+	"gorm.io/gorm"
+)
+
+const invitationTTL = 7 * 24 * time.Hour
+
+type WorkspaceService struct {
+	db *gorm.DB
+}
+
+func NewWorkspaceService(db *gorm.DB) *WorkspaceService {
+	return &WorkspaceService{db: db}
+}
+
+/* ===========================
+   Helpers
+=========================== */
+
+// NewToken membuat token acak (hex) beserta hash SHA-256 untuk disimpan di DB.
+func NewToken() (token string, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token = hex.EncodeToString(buf)
+	return token, HashToken(token), nil
+}
+
+func HashToken(token string) string {
+	return utils.SHA256Hex(token)
+}
+
+// linkMember menautkan member ledger ke user login (nil = lepas tautan).
+func (s *WorkspaceService) linkMember(tx *gorm.DB, ownerID uint, memberID *uint, userID uint) error {
+	if err := tx.Model(&models.Member{}).
+		Where("user_id = ? AND login_user_id = ?", ownerID, userID).
+		Update("login_user_id", nil).Error; err != nil {
+		return err
+	}
+	if memberID == nil {
+		return nil
+	}
+	res := tx.Model(&models.Member{}).
+		Where("user_id = ? AND id = ?", ownerID, *memberID).
+		Update("login_user_id", userID)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return utils.NewAppError("Member not found", http.StatusNotFound)
+	}
+	return nil
+}
+
+func (s *WorkspaceService) validateRole(role string) error {
+	if !models.ValidWorkspaceRoles[role] || role == models.WorkspaceRoleOwner {
+		return utils.NewAppError("Invalid role, allowed: editor, viewer, member", http.StatusBadRequest)
+	}
+	return nil
+}
+
+/* ===========================
+   Services
+=========================== */
+
+func (s *WorkspaceService) GetWorkspace(scope *utils.WorkspaceScope) (*models.Workspace, error) {
+	var ws models.Workspace
+	if err := s.db.First(&ws, scope.WorkspaceID).Error; err != nil {
+		return nil, err
+	}
+	return &ws, nil
+}
+
+func (s *WorkspaceService) ListForUser(userID uint) ([]models.WorkspaceMembership, error) {
+	var memberships []models.WorkspaceMembership
+	err := s.db.Preload("Workspace").Where("user_id = ?", userID).Find(&memberships).Error
+	return memberships, err
+}
+
+func (s *WorkspaceService) Rename(scope *utils.WorkspaceScope, name string) (*models.Workspace, error) {
+	ws, err := s.GetWorkspace(scope)
+	if err != nil {
+		return nil, err
+	}
+	ws.Name = name
+	if err := s.db.Save(ws).Error; err != nil {
+		return nil, err
+	}
+	return ws, nil
+}
+
+func (s *WorkspaceService) ListMemberships(scope *utils.WorkspaceScope) ([]map[string]interface{}, error) {
+	var rows []map[string]interface{}
+	err := s.db.Table("workspace_memberships").
+		Select("workspace_memberships.id, workspace_memberships.user_id, users.username, users.email, workspace_memberships.role, workspace_memberships.member_id, workspace_memberships.created_at").
+		Joins("JOIN users ON users.id = workspace_memberships.user_id").
+		Where("workspace_memberships.workspace_id = ? AND workspace_memberships.deleted_at IS NULL", scope.WorkspaceID).
+		Order("workspace_memberships.id ASC").
+		Find(&rows).Error
+	return rows, err
+}
+
+// Invite membuat undangan dan mengembalikan token mentah (hanya sekali).
+func (s *WorkspaceService) Invite(scope *utils.WorkspaceScope, email, role string, memberID *uint) (*models.WorkspaceInvitation, string, error) {
+	if err := s.validateRole(role); err != nil {
+		return nil, "", err
+	}
+	if memberID != nil {
+		var cnt int64
+		s.db.Model(&models.Member{}).Where("user_id = ? AND id = ?", scope.OwnerID, *memberID).Count(&cnt)
+		if cnt == 0 {
+			return nil, "", utils.NewAppError("Member not found", http.StatusNotFound)
+		}
+	}
+
+	token, hash, err := NewToken()
+	if err != nil {
+		return nil, "", err
+	}
+	inv := models.WorkspaceInvitation{
+		WorkspaceID: scope.WorkspaceID,
+		InvitedByID: scope.UserID,
+		Email:       strings.ToLower(strings.TrimSpace(email)),
+		Role:        role,
+		MemberID:    memberID,
+		TokenHash:   hash,
+		ExpiresAt:   time.Now().Add(invitationTTL),
+	}
+	if err := s.db.Create(&inv).Error; err != nil {
+		return nil, "", err
+	}
+	return &inv, token, nil
+}
+
+// SendInvitation mengirim link undangan ke email yang diundang.
+func (s *WorkspaceService) SendInvitation(mailer Mailer, inv *models.WorkspaceInvitation, token string) error {
+	var inviter models.User
+	if err := s.db.Select("id", "username").First(&inviter, inv.InvitedByID).Error; err != nil {
+		return err
+	}
+	return mailer.Send(MailMessage{
+		To:      inv.Email,
+		Subject: "You've been invited to a workspace",
+		Body: fmt.Sprintf("Hi,\n\n%s invited you to join their workspace as %s. Open the link below to accept. It expires in %s.\n\n%s\n",
+			inviter.Username, inv.Role, invitationTTL, appLink("/accept-invitation", token)),
+	})
+}
+
+func (s *WorkspaceService) ListInvitations(scope *utils.WorkspaceScope) ([]models.WorkspaceInvitation, error) {
+	var invs []models.WorkspaceInvitation
+	err := s.db.Where("workspace_id = ? AND accepted_at IS NULL AND expires_at > ?", scope.WorkspaceID, time.Now()).
+		Order("id DESC").Find(&invs).Error
+	return invs, err
+}
+
+func (s *WorkspaceService) RevokeInvitation(scope *utils.WorkspaceScope, id string) error {
+	res := s.db.Where("workspace_id = ? AND id = ?", scope.WorkspaceID, id).Delete(&models.WorkspaceInvitation{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return utils.NewAppError("Invitation not found", http.StatusNotFound)
+	}
+	return nil
+}
+
+func (s *WorkspaceService) AcceptInvitation(userID uint, token string) (*models.WorkspaceMembership, error) {
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, utils.NewAppError("User not found", http.StatusNotFound)
+	}
+
+	var inv models.WorkspaceInvitation
+	if err := s.db.Where("token_hash = ?", HashToken(token)).First(&inv).Error; err != nil {
+		return nil, utils.NewAppError("Invitation not found", http.StatusNotFound)
+	}
+	if inv.AcceptedAt != nil || time.Now().After(inv.ExpiresAt) {
+		return nil, utils.NewAppError("Invitation has expired", http.StatusGone)
+	}
+	if !strings.EqualFold(inv.Email, user.Email) {
+		return nil, utils.NewAppError("Invitation was sent to a different email", http.StatusForbidden)
+	}
+
+	var ws models.Workspace
+	if err := s.db.First(&ws, inv.WorkspaceID).Error; err != nil {
+		return nil, utils.NewAppError("Workspace not found", http.StatusNotFound)
+	}
+
+	membership := models.WorkspaceMembership{
+		WorkspaceID: inv.WorkspaceID,
+		UserID:      userID,
+		Role:        inv.Role,
+		MemberID:    inv.MemberID,
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var cnt int64
+		tx.Model(&models.WorkspaceMembership{}).
+			Where("workspace_id = ? AND user_id = ?", inv.WorkspaceID, userID).Count(&cnt)
+		if cnt > 0 {
+			return utils.NewAppError("You are already a member of this workspace", http.StatusConflict)
+		}
+		if err := tx.Create(&membership).Error; err != nil {
+			return err
+		}
+		if err := s.linkMember(tx, ws.OwnerID, inv.MemberID, userID); err != nil {
+			return err
+		}
+		now := time.Now()
+		return tx.Model(&inv).Update("accepted_at", &now).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	membership.Workspace = ws
+	return &membership, nil
+}
+
+func (s *WorkspaceService) UpdateMembership(scope *utils.WorkspaceScope, id string, role string, memberID *uint) (*models.WorkspaceMembership, error) {
+	var m models.WorkspaceMembership
+	if err := s.db.Where("workspace_id = ? AND id = ?", scope.WorkspaceID, id).First(&m).Error; err != nil {
+		return nil, utils.NewAppError("Membership not found", http.StatusNotFound)
+	}
+	if m.Role == models.WorkspaceRoleOwner {
+		return nil, utils.NewAppError("Owner membership cannot be changed", http.StatusBadRequest)
+	}
+	if role != "" {
+		if err := s.validateRole(role); err != nil {
+			return nil, err
+		}
+		m.Role = role
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if memberID != nil {
+			// member_id = 0 melepas tautan member
+			if *memberID == 0 {
+				memberID = nil
+			}
+			if err := s.linkMember(tx, scope.OwnerID, memberID, m.UserID); err != nil {
+				return err
+			}
+			m.MemberID = memberID
+		}
+		return tx.Save(&m).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// RemoveMembership dipakai owner untuk mengeluarkan user, atau user untuk keluar sendiri.
+func (s *WorkspaceService) RemoveMembership(scope *utils.WorkspaceScope, id string) error {
+	var m models.WorkspaceMembership
+	if err := s.db.Where("workspace_id = ? AND id = ?", scope.WorkspaceID, id).First(&m).Error; err != nil {
+		return utils.NewAppError("Membership not found", http.StatusNotFound)
+	}
+	if m.Role == models.WorkspaceRoleOwner {
+		return utils.NewAppError("Owner cannot leave their own workspace", http.StatusBadRequest)
+	}
+	if !scope.IsOwner() && m.UserID != scope.UserID {
+		return utils.NewAppError("Insufficient workspace role", http.StatusForbidden)
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.linkMember(tx, scope.OwnerID, nil, m.UserID); err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&m).Error
+	})
+}
@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"finance-app/database"
+	"finance-app/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// APIKeyPrefix membedakan API key dari JWT pada header Authorization.
+	APIKeyPrefix = "fa_"
+	APIKeyHeader = "X-API-Key"
+)
+
+// APIKeyResources adalah resource yang bisa diberi scope "<resource>:read" / "<resource>:write".
+var APIKeyResources = []string{
+	"transactions", "transfers", "accounts", "categories", "budgets",
+	"members", "recurring", "savings", "allowances", "assets", "loans", "investments", "reports",
+}
+
+// IsValidAPIKeyScope mengecek format scope, mis. "transactions:read".
+func IsValidAPIKeyScope(scope string) bool {
+	parts := strings.SplitN(scope, ":", 2)
+	if len(parts) != 2 || (parts[1] != "read" && parts[1] != "write") {
+		return false
+	}
+	for _, r := range APIKeyResources {
+		if r == parts[0] {
+			// report hanya bisa dibaca
+			return r != "reports" || parts[1] == "read"
+		}
+	}
+	return false
+}
+
+// SHA256Hex mengembalikan hash SHA-256 dalam bentuk hex.
+func SHA256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// extractAPIKey mengambil API key dari X-API-Key atau "Authorization: Bearer fa_...".
+func extractAPIKey(c *gin.Context) string {
+	if key := c.GetHeader(APIKeyHeader); key != "" {
+		return key
+	}
+	auth := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if strings.HasPrefix(auth, APIKeyPrefix) {
+		return auth
+	}
+	return ""
+}
+
+// authenticateAPIKey memvalidasi API key dan mencatat pemakaian terakhirnya.
+func authenticateAPIKey(raw, ip string) (*models.APIKey, error) {
+	db := database.GetDB()
+
+	var key models.APIKey
+	if err := db.Where("key_hash = ?", SHA256Hex(raw)).First(&key).Error; err != nil {
+		return nil, errors.New("invalid API key")
+	}
+	now := time.Now()
+	if key.RevokedAt != nil {
+		return nil, errors.New("API key has been revoked")
+	}
+	if key.ExpiresAt != nil && now.After(*key.ExpiresAt) {
+		return nil, errors.New("API key has expired")
+	}
+
+	// sama seperti sesi, last_used_at cukup ditulis paling sering sekali per menit
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > sessionTouchInterval {
+		if err := db.Model(&key).Updates(map[string]interface{}{
+			"last_used_at": now,
+			"last_used_ip": ip,
+		}).Error; err != nil {
+			log.Printf("⚠️  failed to touch API key: %v", err)
+		}
+	}
+	return &key, nil
+}
+
+// RequireScope membatasi request ber-API key ke scope resource:read (GET/HEAD)
+// atau resource:write (method lain). Request via JWT tidak dibatasi.
+func RequireScope(resource string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, err := GetPrincipal(c)
+		if err != nil {
+			RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		scope := resource + ":write"
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			scope = resource + ":read"
+		}
+		if !p.HasScope(scope) {
+			RespondWithError(c, http.StatusForbidden, &AppError{
+				Message:    "API key is missing required scope",
+				StatusCode: http.StatusForbidden,
+				Code:       "INSUFFICIENT_SCOPE",
+				Details:    gin.H{"required_scope": scope},
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// JWTOnly menolak API key untuk endpoint akun (profil, sesi, 2FA, workspace, API key).
+func JWTOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if p, err := GetPrincipal(c); err != nil || p.IsAPIKey() {
+			RespondWithError(c, http.StatusForbidden, "This endpoint cannot be used with an API key")
+			return
+		}
+		c.Next()
+	}
+}
@@ -1,8 +1,12 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"finance-app/config"
+	"finance-app/database"
+	"finance-app/models"
 	"log"
 	"strings"
 	"time"
@@ -11,17 +15,98 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func GenerateToken(userID uint) (string, error) {
-	config := config.LoadConfig()
+// TokenClaims adalah isi access token yang sudah divalidasi.
+type TokenClaims struct {
+	UserID    uint
+	JTI       string
+	SessionID string // family refresh token asal access token ini
+	ExpiresAt time.Time
+}
+
+const (
+	TokenTypeAccess    = "access"
+	TokenTypeChallenge = "2fa_challenge"
+
+	// ChallengeTokenTTL adalah batas waktu memasukkan kode 2FA setelah password benar.
+	ChallengeTokenTTL = 5 * time.Minute
+)
+
+// GenerateChallengeToken membuat token sementara untuk langkah kedua login 2FA.
+func GenerateChallengeToken(userID uint) (string, error) {
+	config := config.Get()
 
 	claims := jwt.MapClaims{}
+	claims["typ"] = TokenTypeChallenge
 	claims["user_id"] = userID
-	claims["exp"] = time.Now().Add(time.Hour * 72).Unix()
+	claims["exp"] = time.Now().Add(ChallengeTokenTTL).Unix()
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(config.JWTSecret))
 }
 
+// ParseChallengeToken memvalidasi challenge token 2FA dan mengembalikan user ID-nya.
+func ParseChallengeToken(tokenString string) (uint, error) {
+	config := config.Get()
+
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return []byte(config.JWTSecret), nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return 0, errors.New("invalid token")
+	}
+	if typ, _ := claims["typ"].(string); typ != TokenTypeChallenge {
+		return 0, errors.New("invalid challenge token")
+	}
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return 0, errors.New("invalid user_id format in token")
+	}
+	return uint(userID), nil
+}
+
+// GenerateAccessToken membuat access token berumur pendek untuk satu sesi.
+func GenerateAccessToken(userID uint, sessionID string) (string, *TokenClaims, error) {
+	config := config.Get()
+
+	jti, err := RandomHex(16)
+	if err != nil {
+		return "", nil, err
+	}
+	expiresAt := time.Now().Add(config.AccessTokenTTL)
+
+	claims := jwt.MapClaims{}
+	claims["typ"] = TokenTypeAccess
+	claims["user_id"] = userID
+	claims["jti"] = jti
+	claims["sid"] = sessionID
+	claims["iat"] = time.Now().Unix()
+	claims["exp"] = expiresAt.Unix()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := token.SignedString([]byte(config.JWTSecret))
+	if err != nil {
+		return "", nil, err
+	}
+	return signed, &TokenClaims{UserID: userID, JTI: jti, SessionID: sessionID, ExpiresAt: expiresAt}, nil
+}
+
+// RandomHex menghasilkan string hex acak sepanjang n byte.
+func RandomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
 // Tambahkan di bagian atas file
 const (
 	DebugLevelNone = iota
@@ -31,15 +116,17 @@ const (
 
 var DebugLevel = DebugLevelBasic // Atur level debug sesuai kebutuhan
 
-func GetUserIDFromToken(c *gin.Context) (uint, error) {
-	config := config.LoadConfig()
+// parseTokenClaims memvalidasi Bearer token pada request dan mengembalikan claim-nya.
+// Hanya dipanggil sekali per request oleh JWTAuthMiddleware; handler membaca Principal.
+func parseTokenClaims(c *gin.Context) (*TokenClaims, error) {
+	config := config.Get()
 
 	authHeader := c.GetHeader("Authorization")
 	if authHeader == "" {
 		if DebugLevel >= DebugLevelBasic {
 			log.Printf("DEBUG: Authorization header is missing")
 		}
-		return 0, errors.New("authorization header is required")
+		return nil, errors.New("authorization header is required")
 	}
 
 	if DebugLevel >= DebugLevelVerbose {
@@ -59,7 +146,7 @@ func GetUserIDFromToken(c *gin.Context) (uint, error) {
 		if DebugLevel >= DebugLevelBasic {
 			log.Printf("DEBUG: Invalid authorization header format. Parts: %v", parts)
 		}
-		return 0, errors.New("invalid authorization header format")
+		return nil, errors.New("invalid authorization header format")
 	}
 	tokenString := parts[1]
 
@@ -82,7 +169,7 @@ func GetUserIDFromToken(c *gin.Context) (uint, error) {
 		if DebugLevel >= DebugLevelBasic {
 			log.Printf("DEBUG: JWT parsing error: %v", err)
 		}
-		return 0, err
+		return nil, err
 	}
 
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
@@ -90,12 +177,17 @@ func GetUserIDFromToken(c *gin.Context) (uint, error) {
 			log.Printf("DEBUG: All JWT claims: %+v", claims)
 		}
 
+		// challenge token 2FA dan token khusus lain tidak boleh dipakai sebagai access token
+		if typ, _ := claims["typ"].(string); typ != "" && typ != TokenTypeAccess {
+			return nil, errors.New("token cannot be used for authentication")
+		}
+
 		userIDFloat, ok := claims["user_id"]
 		if !ok {
 			if DebugLevel >= DebugLevelBasic {
 				log.Printf("DEBUG: user_id claim not found in token")
 			}
-			return 0, errors.New("user_id claim not found in token")
+			return nil, errors.New("user_id claim not found in token")
 		}
 
 		userID, ok := userIDFloat.(float64)
@@ -103,24 +195,93 @@ func GetUserIDFromToken(c *gin.Context) (uint, error) {
 			if DebugLevel >= DebugLevelBasic {
 				log.Printf("DEBUG: user_id is not a number. Type: %T, Value: %v", userIDFloat, userIDFloat)
 			}
-			return 0, errors.New("invalid user_id format in token")
+			return nil, errors.New("invalid user_id format in token")
 		}
 
 		if DebugLevel >= DebugLevelBasic {
 			log.Printf("DEBUG: Successfully extracted user_id: %d", uint(userID))
 		}
-		return uint(userID), nil
+
+		parsed := &TokenClaims{UserID: uint(userID)}
+		parsed.JTI, _ = claims["jti"].(string)
+		parsed.SessionID, _ = claims["sid"].(string)
+		if exp, ok := claims["exp"].(float64); ok {
+			parsed.ExpiresAt = time.Unix(int64(exp), 0)
+		}
+		return parsed, nil
 	}
 
 	if DebugLevel >= DebugLevelBasic {
 		log.Printf("DEBUG: Token is invalid or claims cannot be extracted")
 	}
-	return 0, errors.New("invalid token")
+	return nil, errors.New("invalid token")
+}
+
+// IsTokenRevoked mengecek daftar pencabutan untuk jti maupun sesi asal token.
+func IsTokenRevoked(claims *TokenClaims) (bool, error) {
+	values := []string{}
+	if claims.JTI != "" {
+		values = append(values, claims.JTI)
+	}
+	if claims.SessionID != "" {
+		values = append(values, claims.SessionID)
+	}
+	if len(values) == 0 {
+		// token lama tanpa jti/sid tidak bisa dicabut, tolak saja
+		return true, nil
+	}
+
+	var cnt int64
+	err := database.GetDB().Model(&models.RevokedToken{}).
+		Where("value IN ?", values).
+		Count(&cnt).Error
+	return cnt > 0, err
 }
 
+// sessionTouchInterval membatasi seberapa sering last_seen_at ditulis ke DB.
+const sessionTouchInterval = time.Minute
+
+// touchSession memperbarui last_seen_at dan IP terakhir dari sesi token ini.
+func touchSession(claims *TokenClaims, ip string) {
+	if claims.SessionID == "" {
+		return
+	}
+	now := time.Now()
+	err := database.GetDB().Model(&models.Session{}).
+		Where("family_id = ? AND last_seen_at < ?", claims.SessionID, now.Add(-sessionTouchInterval)).
+		Updates(map[string]interface{}{"last_seen_at": now, "ip_address": ip}).Error
+	if err != nil {
+		log.Printf("⚠️  failed to touch session: %v", err)
+	}
+}
+
+// JWTAuthMiddleware menerima Bearer JWT maupun API key (lihat RequireScope).
 func JWTAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		_, err := GetUserIDFromToken(c)
+		if raw := extractAPIKey(c); raw != "" {
+			key, err := authenticateAPIKey(raw, c.ClientIP())
+			if err != nil {
+				c.JSON(401, gin.H{"error": "Unauthorized", "detail": err.Error()})
+				c.Abort()
+				return
+			}
+			setPrincipal(c, &Principal{
+				UserID: key.UserID,
+				APIKey: key,
+				Scopes: key.ScopeSlice(),
+			})
+			c.Next()
+			return
+		}
+
+		claims, err := parseTokenClaims(c)
+		if err == nil {
+			var revoked bool
+			revoked, err = IsTokenRevoked(claims)
+			if err == nil && revoked {
+				err = errors.New("token has been revoked")
+			}
+		}
 		if err != nil {
 			// Tambahin log
 			println("JWT Error:", err.Error())
@@ -129,6 +290,13 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 			c.Abort()
 			return
 		}
+		touchSession(claims, c.ClientIP())
+		setPrincipal(c, &Principal{
+			UserID:    claims.UserID,
+			SessionID: claims.SessionID,
+			TokenID:   claims.JTI,
+			ExpiresAt: claims.ExpiresAt,
+		})
 		c.Next()
 	}
 }
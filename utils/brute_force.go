@@ -0,0 +1,295 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"finance-app/database"
+	"finance-app/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AttemptState adalah status percobaan gagal untuk satu key (akun / IP).
+type AttemptState struct {
+	Failures     int
+	LastFailure  time.Time
+	BlockedUntil time.Time
+}
+
+// AttemptStore menyimpan status percobaan login. Implementasi in-memory cukup untuk
+// satu instance; untuk beberapa instance pakai store bersama (mis. Redis).
+type AttemptStore interface {
+	Get(key string) (AttemptState, bool)
+	Set(key string, state AttemptState, ttl time.Duration)
+	Delete(key string)
+}
+
+// MemoryAttemptStore adalah AttemptStore in-memory dengan kedaluwarsa per key.
+type MemoryAttemptStore struct {
+	mu        sync.Mutex
+	items     map[string]memoryAttempt
+	lastSweep time.Time
+}
+
+type memoryAttempt struct {
+	state     AttemptState
+	expiresAt time.Time
+}
+
+func NewMemoryAttemptStore() *MemoryAttemptStore {
+	return &MemoryAttemptStore{items: map[string]memoryAttempt{}}
+}
+
+func (s *MemoryAttemptStore) Get(key string) (AttemptState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.items[key]
+	if !ok || time.Now().After(item.expiresAt) {
+		return AttemptState{}, false
+	}
+	return item.state, true
+}
+
+func (s *MemoryAttemptStore) Set(key string, state AttemptState, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.items[key] = memoryAttempt{state: state, expiresAt: now.Add(ttl)}
+
+	// bersihkan key kedaluwarsa sesekali supaya map tidak tumbuh terus
+	if now.Sub(s.lastSweep) > 5*time.Minute {
+		for k, item := range s.items {
+			if now.After(item.expiresAt) {
+				delete(s.items, k)
+			}
+		}
+		s.lastSweep = now
+	}
+}
+
+func (s *MemoryAttemptStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.items, key)
+}
+
+// AttemptPolicy mengatur backoff dan lockout.
+type AttemptPolicy struct {
+	FreeAttempts    int           // gagal sebanyak ini belum kena delay
+	BaseDelay       time.Duration // delay setelah FreeAttempts, lalu berlipat dua
+	MaxDelay        time.Duration
+	LockoutAfter    int // gagal sebanyak ini = dikunci LockoutDuration
+	LockoutDuration time.Duration
+	Window          time.Duration // hitungan gagal di-reset setelah tidak ada kegagalan selama ini
+}
+
+// blockFor menghitung lama blokir setelah kegagalan ke-n.
+func (p AttemptPolicy) blockFor(failures int) time.Duration {
+	if failures >= p.LockoutAfter {
+		return p.LockoutDuration
+	}
+	if failures < p.FreeAttempts {
+		return 0
+	}
+	delay := time.Duration(float64(p.BaseDelay) * math.Pow(2, float64(failures-p.FreeAttempts)))
+	if delay > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return delay
+}
+
+var (
+	// AccountAttemptPolicy: satu akun (email / user 2FA) dikunci 15 menit setelah 10 gagal.
+	AccountAttemptPolicy = AttemptPolicy{
+		FreeAttempts:    3,
+		BaseDelay:       time.Second,
+		MaxDelay:        5 * time.Minute,
+		LockoutAfter:    10,
+		LockoutDuration: 15 * time.Minute,
+		Window:          time.Hour,
+	}
+	// IPAttemptPolicy lebih longgar karena satu IP bisa dipakai banyak user (kantor, NAT).
+	IPAttemptPolicy = AttemptPolicy{
+		FreeAttempts:    10,
+		BaseDelay:       time.Second,
+		MaxDelay:        5 * time.Minute,
+		LockoutAfter:    50,
+		LockoutDuration: 30 * time.Minute,
+		Window:          time.Hour,
+	}
+)
+
+// BruteForceGuard melacak kegagalan per akun dan per IP.
+type BruteForceGuard struct {
+	Store         AttemptStore
+	AccountPolicy AttemptPolicy
+	IPPolicy      AttemptPolicy
+}
+
+func NewBruteForceGuard(store AttemptStore) *BruteForceGuard {
+	return &BruteForceGuard{
+		Store:         store,
+		AccountPolicy: AccountAttemptPolicy,
+		IPPolicy:      IPAttemptPolicy,
+	}
+}
+
+// DefaultBruteForceGuard dipakai bersama oleh semua endpoint login.
+var DefaultBruteForceGuard = NewBruteForceGuard(NewMemoryAttemptStore())
+
+type attemptKey struct {
+	key    string
+	policy AttemptPolicy
+}
+
+func (g *BruteForceGuard) keys(identity, ip string) []attemptKey {
+	keys := []attemptKey{{key: "ip:" + ip, policy: g.IPPolicy}}
+	if identity != "" {
+		keys = append(keys, attemptKey{key: identity, policy: g.AccountPolicy})
+	}
+	return keys
+}
+
+// IdentityFunc mengambil identitas akun dari request (mis. "email:a@b.c"), "" jika tidak ada.
+type IdentityFunc func(c *gin.Context) string
+
+// LoginEmailIdentity membaca field email dari body JSON tanpa menghabiskan body.
+func LoginEmailIdentity(c *gin.Context) string {
+	var input struct {
+		Email string `json:"email"`
+	}
+	if !peekJSONBody(c, &input) {
+		return ""
+	}
+	email := strings.ToLower(strings.TrimSpace(input.Email))
+	if email == "" {
+		return ""
+	}
+	return "email:" + email
+}
+
+// ChallengeIdentity memakai user dari challenge token 2FA, supaya tebakan kode
+// dibatasi per akun, bukan hanya per IP.
+func ChallengeIdentity(c *gin.Context) string {
+	var input struct {
+		ChallengeToken string `json:"challenge_token"`
+	}
+	if !peekJSONBody(c, &input) || input.ChallengeToken == "" {
+		return ""
+	}
+	userID, err := ParseChallengeToken(input.ChallengeToken)
+	if err != nil {
+		return ""
+	}
+	return "user:" + strconv.FormatUint(uint64(userID), 10)
+}
+
+// retryAfter mengembalikan sisa waktu blokir terlama dari semua key.
+func (g *BruteForceGuard) retryAfter(keys []attemptKey, now time.Time) time.Duration {
+	var wait time.Duration
+	for _, k := range keys {
+		state, ok := g.Store.Get(k.key)
+		if !ok {
+			continue
+		}
+		if d := state.BlockedUntil.Sub(now); d > wait {
+			wait = d
+		}
+	}
+	return wait
+}
+
+func (g *BruteForceGuard) recordFailure(keys []attemptKey, now time.Time) {
+	for _, k := range keys {
+		state, ok := g.Store.Get(k.key)
+		if !ok || now.Sub(state.LastFailure) > k.policy.Window {
+			state = AttemptState{}
+		}
+		state.Failures++
+		state.LastFailure = now
+		if block := k.policy.blockFor(state.Failures); block > 0 {
+			state.BlockedUntil = now.Add(block)
+		}
+
+		ttl := k.policy.Window
+		if d := state.BlockedUntil.Sub(now); d > ttl {
+			ttl = d
+		}
+		g.Store.Set(k.key, state, ttl)
+	}
+}
+
+// Middleware memblokir request dengan 429 + Retry-After selama masa backoff/lockout,
+// dan mencatat hasil handler: 401 dihitung gagal, 2xx mereset hitungan akun.
+func (g *BruteForceGuard) Middleware(identify IdentityFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identity := identify(c)
+		ip := c.ClientIP()
+		keys := g.keys(identity, ip)
+
+		now := time.Now()
+		if wait := g.retryAfter(keys, now); wait > 0 {
+			seconds := int(math.Ceil(wait.Seconds()))
+			recordLoginAttempt(c, identity, "locked_out")
+			c.Header("Retry-After", strconv.Itoa(seconds))
+			RespondWithError(c, http.StatusTooManyRequests, &AppError{
+				Message:    "Too many failed login attempts. Try again later.",
+				StatusCode: http.StatusTooManyRequests,
+				Code:       "TOO_MANY_ATTEMPTS",
+				Details:    gin.H{"retry_after": seconds},
+			})
+			return
+		}
+
+		c.Next()
+
+		switch status := c.Writer.Status(); {
+		case status == http.StatusUnauthorized:
+			g.recordFailure(keys, time.Now())
+			recordLoginAttempt(c, identity, "invalid_credentials")
+		case status >= 200 && status < 300 && identity != "":
+			g.Store.Delete(identity)
+		}
+	}
+}
+
+// peekJSONBody membaca body JSON ke dst lalu mengembalikan body supaya handler tetap bisa bind.
+func peekJSONBody(c *gin.Context, dst interface{}) bool {
+	if c.Request.Body == nil {
+		return false
+	}
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+	if err != nil {
+		return false
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	return json.Unmarshal(body, dst) == nil
+}
+
+func recordLoginAttempt(c *gin.Context, identity, reason string) {
+	ua := c.GetHeader("User-Agent")
+	if len(ua) > 255 {
+		ua = ua[:255]
+	}
+	attempt := models.LoginAttempt{
+		Identity:  identity,
+		IPAddress: c.ClientIP(),
+		UserAgent: ua,
+		Route:     c.FullPath(),
+		Reason:    reason,
+	}
+	if err := database.GetDB().Create(&attempt).Error; err != nil {
+		log.Printf("⚠️  failed to record login attempt: %v", err)
+	}
+}
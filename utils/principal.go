@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"finance-app/database"
+	"finance-app/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const principalContextKey = "principal"
+
+var ErrNoPrincipal = errors.New("request is not authenticated")
+
+// Principal adalah identitas request yang sudah divalidasi oleh JWTAuthMiddleware.
+type Principal struct {
+	UserID    uint
+	SessionID string    // family refresh token (kosong untuk API key)
+	TokenID   string    // jti access token (kosong untuk API key)
+	ExpiresAt time.Time // kedaluwarsa access token
+	APIKey    *models.APIKey
+	Scopes    []string        // nil = akses penuh (login via JWT)
+	Workspace *WorkspaceScope // diisi oleh WorkspaceMiddleware
+}
+
+// IsAPIKey true jika request diautentikasi dengan API key.
+func (p *Principal) IsAPIKey() bool {
+	return p.APIKey != nil
+}
+
+// HasScope selalu true untuk JWT; untuk API key dicek ke daftar scope.
+func (p *Principal) HasScope(scope string) bool {
+	if p.Scopes == nil {
+		return true
+	}
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func setPrincipal(c *gin.Context, p *Principal) {
+	c.Set(principalContextKey, p)
+}
+
+// GetPrincipal mengambil principal dari context. Error berarti route tidak
+// melewati JWTAuthMiddleware.
+func GetPrincipal(c *gin.Context) (*Principal, error) {
+	if v, ok := c.Get(principalContextKey); ok {
+		if p, ok := v.(*Principal); ok {
+			return p, nil
+		}
+	}
+	return nil, ErrNoPrincipal
+}
+
+// GetUserID mengembalikan ID user login dari principal.
+func GetUserID(c *gin.Context) (uint, error) {
+	p, err := GetPrincipal(c)
+	if err != nil {
+		return 0, err
+	}
+	return p.UserID, nil
+}
+
+// RequestDB mengembalikan koneksi DB yang membawa pelaku request (user, API key, IP)
+// supaya perubahan data tercatat di audit log. Context-nya sengaja tidak terikat ke
+// request, jadi aman dipakai goroutine yang berjalan setelah response dikirim.
+func RequestDB(c *gin.Context) *gorm.DB {
+	actor := &database.AuditActor{IP: c.ClientIP()}
+	if p, err := GetPrincipal(c); err == nil {
+		actor.UserID = p.UserID
+		if p.APIKey != nil {
+			id := p.APIKey.ID
+			actor.APIKeyID = &id
+		}
+	}
+	return database.GetDB().WithContext(database.WithAuditActor(context.Background(), actor))
+}
@@ -9,9 +9,10 @@ import (
 
 // AppError represents a custom application error
 type AppError struct {
-	Message    string `json:"message"`
-	StatusCode int    `json:"-"`
-	Code       string `json:"code,omitempty"`
+	Message    string      `json:"message"`
+	StatusCode int         `json:"-"`
+	Code       string      `json:"code,omitempty"`
+	Details    interface{} `json:"details,omitempty"`
 }
 
 // Error implements the error interface
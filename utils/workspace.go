@@ -0,0 +1,222 @@
+package utils
+
+import (
+	"errors"
+	"finance-app/database"
+	"finance-app/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const WorkspaceHeader = "X-Workspace-ID"
+
+// WorkspaceScope adalah hasil otorisasi workspace untuk satu request.
+type WorkspaceScope struct {
+	WorkspaceID uint
+	UserID      uint   // user login yang melakukan request
+	OwnerID     uint   // pemilik ledger; semua data workspace disimpan dengan user_id ini
+	Role        string // owner, editor, viewer, member
+	MemberID    *uint  // member ledger yang ditautkan ke user login
+}
+
+func (s *WorkspaceScope) CanWrite() bool {
+	return s.Role != models.WorkspaceRoleViewer
+}
+
+func (s *WorkspaceScope) IsOwner() bool {
+	return s.Role == models.WorkspaceRoleOwner
+}
+
+// RestrictedMemberID mengembalikan member yang boleh diakses oleh role "member".
+// ok = false berarti user bisa melihat seluruh data workspace. Role member tanpa
+// tautan member mendapat ID 0 sehingga tidak melihat data apa pun.
+func (s *WorkspaceScope) RestrictedMemberID() (memberID uint, ok bool) {
+	if s.Role != models.WorkspaceRoleMember {
+		return 0, false
+	}
+	if s.MemberID == nil {
+		return 0, true
+	}
+	return *s.MemberID, true
+}
+
+// EnsurePersonalWorkspace membuat workspace pribadi (role owner) kalau user belum punya.
+func EnsurePersonalWorkspace(db *gorm.DB, user models.User) (*models.Workspace, error) {
+	var ws models.Workspace
+	err := db.Where("owner_id = ?", user.ID).First(&ws).Error
+	if err == nil {
+		return &ws, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		ws = models.Workspace{Name: user.Username + "'s household", OwnerID: user.ID}
+		if err := tx.Create(&ws).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.WorkspaceMembership{
+			WorkspaceID: ws.ID,
+			UserID:      user.ID,
+			Role:        models.WorkspaceRoleOwner,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ws, nil
+}
+
+func resolveWorkspace(c *gin.Context, userID uint) (*WorkspaceScope, error) {
+	db := database.GetDB()
+	query := db.Preload("Workspace").Where("user_id = ?", userID)
+
+	requested := c.GetHeader(WorkspaceHeader)
+	if requested == "" {
+		requested = c.Query("workspace_id")
+	}
+
+	var membership models.WorkspaceMembership
+	if requested != "" {
+		id, err := strconv.Atoi(requested)
+		if err != nil {
+			return nil, NewAppError("Invalid workspace ID", http.StatusBadRequest)
+		}
+		if err := query.Where("workspace_id = ?", id).First(&membership).Error; err != nil {
+			return nil, NewAppError("Workspace not found", http.StatusForbidden)
+		}
+	} else {
+		// default: workspace milik sendiri, kalau tidak ada pakai keanggotaan pertama
+		err := query.Order("CASE WHEN role = 'owner' THEN 0 ELSE 1 END, id ASC").First(&membership).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			var user models.User
+			if err := db.First(&user, userID).Error; err != nil {
+				return nil, NewAppError("User not found", http.StatusUnauthorized)
+			}
+			if _, err := EnsurePersonalWorkspace(db, user); err != nil {
+				return nil, err
+			}
+			err = db.Preload("Workspace").Where("user_id = ?", userID).First(&membership).Error
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &WorkspaceScope{
+		WorkspaceID: membership.WorkspaceID,
+		UserID:      userID,
+		OwnerID:     membership.Workspace.OwnerID,
+		Role:        membership.Role,
+		MemberID:    membership.MemberID,
+	}, nil
+}
+
+// GetWorkspaceScope mengambil workspace aktif dari principal (atau me-resolve kalau belum).
+func GetWorkspaceScope(c *gin.Context) (*WorkspaceScope, error) {
+	p, err := GetPrincipal(c)
+	if err != nil {
+		return nil, err
+	}
+	if p.Workspace != nil {
+		return p.Workspace, nil
+	}
+	scope, err := resolveWorkspace(c, p.UserID)
+	if err != nil {
+		return nil, err
+	}
+	p.Workspace = scope
+	return scope, nil
+}
+
+// GetLedgerUserID mengembalikan user_id yang dipakai untuk semua query data
+// ledger di workspace aktif (yaitu ID pemilik workspace).
+func GetLedgerUserID(c *gin.Context) (uint, error) {
+	scope, err := GetWorkspaceScope(c)
+	if err != nil {
+		return 0, err
+	}
+	return scope.OwnerID, nil
+}
+
+func respondWorkspaceError(c *gin.Context, err error) {
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		RespondWithError(c, appErr.StatusCode, appErr)
+		return
+	}
+	RespondWithError(c, http.StatusUnauthorized, "Unauthorized")
+}
+
+// WorkspaceMiddleware me-resolve workspace aktif dan menyimpannya di context.
+func WorkspaceMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, err := GetWorkspaceScope(c); err != nil {
+			respondWorkspaceError(c, err)
+			return
+		}
+		c.Next()
+	}
+}
+
+// WorkspaceWriteGuard menolak request yang mengubah data dari role viewer.
+func WorkspaceWriteGuard() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodGet {
+			c.Next()
+			return
+		}
+		scope, err := GetWorkspaceScope(c)
+		if err != nil {
+			respondWorkspaceError(c, err)
+			return
+		}
+		if !scope.CanWrite() {
+			RespondWithError(c, http.StatusForbidden, "Your role in this workspace is read-only")
+			return
+		}
+		c.Next()
+	}
+}
+
+// RestrictMemberWrites menolak request yang mengubah data dari role member.
+func RestrictMemberWrites() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodGet {
+			c.Next()
+			return
+		}
+		scope, err := GetWorkspaceScope(c)
+		if err != nil {
+			respondWorkspaceError(c, err)
+			return
+		}
+		if _, restricted := scope.RestrictedMemberID(); restricted {
+			RespondWithError(c, http.StatusForbidden, "Your role can only manage your own transactions")
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireWorkspaceRole membatasi endpoint hanya untuk role tertentu.
+func RequireWorkspaceRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scope, err := GetWorkspaceScope(c)
+		if err != nil {
+			respondWorkspaceError(c, err)
+			return
+		}
+		for _, r := range roles {
+			if scope.Role == r {
+				c.Next()
+				return
+			}
+		}
+		RespondWithError(c, http.StatusForbidden, "Insufficient workspace role")
+	}
+}